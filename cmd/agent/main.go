@@ -11,6 +11,7 @@ import (
 
 	"github.com/UmangDiyora/logpipeline/internal/agent/buffer"
 	"github.com/UmangDiyora/logpipeline/internal/agent/collector"
+	"github.com/UmangDiyora/logpipeline/internal/agent/multiline"
 	"github.com/UmangDiyora/logpipeline/internal/agent/shipper"
 	"github.com/UmangDiyora/logpipeline/pkg/config"
 )
@@ -88,6 +89,7 @@ func main() {
 				Source:     input.Name,
 				Host:       cfg.Agent.ID,
 				BufferSize: 64 * 1024,
+				Multiline:  multilineConfig(input.Multiline),
 			}
 			coll, err = collector.NewFileCollector(input.Name, fileConfig)
 
@@ -197,6 +199,20 @@ func defaultConfig() *config.AgentConfig {
 	}
 }
 
+func multilineConfig(cfg *config.MultilineConfig) *multiline.Config {
+	if cfg == nil {
+		return nil
+	}
+	return &multiline.Config{
+		Preset:   cfg.Preset,
+		Pattern:  cfg.Pattern,
+		Negate:   cfg.Negate,
+		Match:    cfg.Match,
+		MaxLines: cfg.MaxLines,
+		Timeout:  cfg.Timeout,
+	}
+}
+
 func parseSize(size string) int64 {
 	// Simple size parser (for production, use a proper parser)
 	var value int64
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/api"
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+)
+
+// runDLQ runs a dlq subcommand
+func runDLQ(c *client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: logcli dlq <list|show|purge|replay> [arguments]")
+	}
+
+	switch args[0] {
+	case "list":
+		return dlqList(c, args[1:])
+	case "show":
+		return dlqShow(c, args[1:])
+	case "purge":
+		return dlqPurge(c, args[1:])
+	case "replay":
+		return dlqReplay(c, args[1:])
+	default:
+		return fmt.Errorf("unknown dlq command: %s", args[0])
+	}
+}
+
+// filterFlags are the flags selecting dead-letter records
+type filterFlags struct {
+	pipeline string
+	stage    string
+	before   string
+}
+
+// register adds the filter flags to a flag set
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.pipeline, "pipeline", "", "Only records that failed in this pipeline")
+	fs.StringVar(&f.stage, "stage", "", "Only records whose stage starts with this value, e.g. parser")
+	fs.StringVar(&f.before, "before", "", "Only records that failed before this RFC3339 time or duration ago, e.g. 1h")
+}
+
+// empty reports whether no filter is set
+func (f *filterFlags) empty() bool {
+	return f.pipeline == "" && f.stage == "" && f.before == ""
+}
+
+// beforeTime parses the before flag
+func (f *filterFlags) beforeTime() (time.Time, error) {
+	if f.before == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(f.before); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, f.before)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -before %q: expected RFC3339 time or duration", f.before)
+	}
+	return t, nil
+}
+
+// query returns the filter as query parameters
+func (f *filterFlags) query() (url.Values, error) {
+	query := url.Values{}
+	if f.pipeline != "" {
+		query.Set("pipeline", f.pipeline)
+	}
+	if f.stage != "" {
+		query.Set("stage", f.stage)
+	}
+	before, err := f.beforeTime()
+	if err != nil {
+		return nil, err
+	}
+	if !before.IsZero() {
+		query.Set("before", before.Format(time.RFC3339))
+	}
+	return query, nil
+}
+
+// dlqList lists records
+func dlqList(c *client, args []string) error {
+	fs := flag.NewFlagSet("dlq list", flag.ExitOnError)
+	var filter filterFlags
+	filter.register(fs)
+	offset := fs.Int("offset", 0, "Records to skip")
+	limit := fs.Int("limit", 100, "Maximum records to list")
+	asJSON := fs.Bool("json", false, "Print the records as JSON")
+	fs.Parse(args)
+
+	query, err := filter.query()
+	if err != nil {
+		return err
+	}
+	query.Set("offset", strconv.Itoa(*offset))
+	query.Set("limit", strconv.Itoa(*limit))
+
+	var result struct {
+		Total   int           `json:"total"`
+		Records []*dlq.Record `json:"records"`
+	}
+	if err := c.do(http.MethodGet, "/api/v1/dlq", query, nil, &result); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFAILED AT\tPIPELINE\tSTAGE\tERROR")
+	for _, r := range result.Records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.FailedAt.Format(time.RFC3339), r.PipelineID, r.Stage, truncate(r.Error, 80))
+	}
+	w.Flush()
+	fmt.Printf("\nShowing %d of %d records\n", len(result.Records), result.Total)
+	return nil
+}
+
+// truncate shortens a single-line version of s to max characters
+func truncate(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
+
+// dlqShow prints a record
+func dlqShow(c *client, args []string) error {
+	fs := flag.NewFlagSet("dlq show", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: logcli dlq show <id>")
+	}
+
+	var record dlq.Record
+	if err := c.do(http.MethodGet, "/api/v1/dlq/"+url.PathEscape(fs.Arg(0)), nil, nil, &record); err != nil {
+		return err
+	}
+	return printJSON(record)
+}
+
+// dlqPurge deletes records by ID or filter
+func dlqPurge(c *client, args []string) error {
+	fs := flag.NewFlagSet("dlq purge", flag.ExitOnError)
+	var filter filterFlags
+	filter.register(fs)
+	all := fs.Bool("all", false, "Delete every record")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		deleted := 0
+		for _, id := range fs.Args() {
+			if err := c.do(http.MethodDelete, "/api/v1/dlq/"+url.PathEscape(id), nil, nil, nil); err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+			deleted++
+		}
+		fmt.Printf("Deleted %d records\n", deleted)
+		return nil
+	}
+
+	if filter.empty() && !*all {
+		return fmt.Errorf("give record IDs, a filter or -all")
+	}
+
+	query, err := filter.query()
+	if err != nil {
+		return err
+	}
+	if *all {
+		query.Set("all", "true")
+	}
+
+	var result struct {
+		Purged int `json:"purged"`
+	}
+	if err := c.do(http.MethodDelete, "/api/v1/dlq", query, nil, &result); err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d records\n", result.Purged)
+	return nil
+}
+
+// dlqReplay replays records by ID or filter
+func dlqReplay(c *client, args []string) error {
+	fs := flag.NewFlagSet("dlq replay", flag.ExitOnError)
+	var filter filterFlags
+	filter.register(fs)
+	all := fs.Bool("all", false, "Replay every record")
+	to := fs.String("to", "", "Replay through this pipeline instead of the one each record failed in")
+	fs.Parse(args)
+
+	request := api.ReplayRequest{
+		IDs:      fs.Args(),
+		All:      *all,
+		Pipeline: *to,
+	}
+	if len(request.IDs) == 0 {
+		if filter.empty() && !*all {
+			return fmt.Errorf("give record IDs, a filter or -all")
+		}
+		before, err := filter.beforeTime()
+		if err != nil {
+			return err
+		}
+		if !filter.empty() {
+			request.Filter = &api.FilterRequest{
+				Pipeline: filter.pipeline,
+				Stage:    filter.stage,
+				Before:   before,
+			}
+		}
+	}
+
+	var result api.ReplayResponse
+	if err := c.do(http.MethodPost, "/api/v1/dlq/replay", nil, request, &result); err != nil {
+		return err
+	}
+
+	fmt.Printf("Replayed %d records\n", result.Replayed)
+	for _, failure := range result.Failed {
+		fmt.Printf("  %s: %s\n", failure.ID, failure.Error)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d records could not be replayed", len(result.Failed))
+	}
+	return nil
+}
@@ -1,10 +1,66 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+)
+
+var (
+	serverAddr = flag.String("server", "http://localhost:8080", "Server address")
+	apiKey     = flag.String("api-key", "", "API key for authentication")
+	version    = "1.0.0"
 )
 
 func main() {
-	fmt.Println("LogPipeline CLI v1.0.0")
-	fmt.Println("CLI tool coming soon!")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	client := newClient(*serverAddr, *apiKey)
+
+	var err error
+	switch args[0] {
+	case "dlq":
+		err = runDLQ(client, args[1:])
+	case "pipeline":
+		err = runPipeline(client, args[1:])
+	case "version":
+		fmt.Printf("logcli v%s\n", version)
+	case "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `LogPipeline CLI v%s
+
+Usage: logcli [flags] <command> [arguments]
+
+Commands:
+  dlq list         List dead-letter records
+  dlq show         Show a dead-letter record
+  dlq purge        Delete dead-letter records
+  dlq replay       Replay dead-letter records through a pipeline
+  pipeline test    Run sample lines through a pipeline and show each stage
+  pipeline verify  Run pipeline test suites locally
+  version          Print the version
+
+Flags:
+`, version)
+	flag.PrintDefaults()
 }
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/api"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/suite"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// runPipeline runs a pipeline subcommand
+func runPipeline(c *client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: logcli pipeline <test|verify> [arguments]")
+	}
+
+	switch args[0] {
+	case "test":
+		return pipelineTest(c, args[1:])
+	case "verify":
+		return pipelineVerify(args[1:])
+	default:
+		return fmt.Errorf("unknown pipeline command: %s", args[0])
+	}
+}
+
+// pipelineTest runs sample lines through a pipeline on the server and
+// prints the entry after each stage
+func pipelineTest(c *client, args []string) error {
+	fs := flag.NewFlagSet("pipeline test", flag.ExitOnError)
+	id := fs.String("id", "", "Pipeline on the server to test")
+	file := fs.String("file", "", "YAML file with a pipeline definition, as in server.yaml")
+	configFile := fs.String("config", "", "Server configuration file to read the pipeline from")
+	name := fs.String("pipeline", "", "Pipeline name in the configuration file")
+	source := fs.String("source", "", "Source set on the samples")
+	host := fs.String("host", "", "Host set on the samples")
+	asJSON := fs.Bool("json", false, "Print the simulation as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: logcli pipeline test [flags] <samples file or ->")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a samples file is required")
+	}
+
+	request := api.SimulateRequest{
+		ID:     *id,
+		Source: *source,
+		Host:   *host,
+	}
+
+	switch {
+	case *id != "" && (*file != "" || *configFile != ""):
+		return fmt.Errorf("give either -id, -file or -config")
+	case *file != "":
+		def, err := readDefinition(*file)
+		if err != nil {
+			return err
+		}
+		request.Pipeline = pipeline.ToModel(def)
+	case *configFile != "":
+		def, err := configDefinition(*configFile, *name)
+		if err != nil {
+			return err
+		}
+		request.Pipeline = pipeline.ToModel(def)
+	case *id == "":
+		return fmt.Errorf("give -id, -file or -config")
+	}
+
+	samples, err := readSamples(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	request.Samples = samples
+
+	var result api.SimulateResponse
+	if err := c.do(http.MethodPost, "/api/v1/pipelines/simulate", nil, request, &result); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(result)
+	}
+
+	failed := 0
+	for i, sample := range result.Results {
+		printSample(i+1, sample)
+		if sample.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d samples failed", failed, len(result.Results))
+	}
+	return nil
+}
+
+// pipelineVerify runs test suites locally against pipelines of a server
+// configuration and prints the mismatches
+func pipelineVerify(args []string) error {
+	fs := flag.NewFlagSet("pipeline verify", flag.ExitOnError)
+	configFile := fs.String("config", "", "Server configuration file, instead of the one named by each suite")
+	asJSON := fs.Bool("json", false, "Print the results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: logcli pipeline verify [flags] <suite file>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a suite file is required")
+	}
+
+	type suiteResult struct {
+		Suite    string          `json:"suite"`
+		Pipeline string          `json:"pipeline"`
+		Results  []*suite.Result `json:"results"`
+	}
+
+	var reports []suiteResult
+	total, failed := 0, 0
+	for _, path := range fs.Args() {
+		s, err := suite.Load(path)
+		if err != nil {
+			return err
+		}
+
+		configPath := *configFile
+		if configPath == "" {
+			configPath = s.ConfigPath()
+		}
+		if configPath == "" {
+			return fmt.Errorf("%s: no server configuration; set config in the suite or use -config", path)
+		}
+		def, err := configDefinition(configPath, s.Pipeline)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		results, err := suite.Run(def, s.Tests)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		reports = append(reports, suiteResult{Suite: path, Pipeline: s.Pipeline, Results: results})
+
+		for _, result := range results {
+			total++
+			if !result.Passed() {
+				failed++
+			}
+		}
+	}
+
+	if *asJSON {
+		if err := printJSON(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, report := range reports {
+			fmt.Printf("%s (pipeline %s)\n", report.Suite, report.Pipeline)
+			for _, result := range report.Results {
+				printCase(result)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("%d passed, %d failed\n", total-failed, failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, total)
+	}
+	return nil
+}
+
+// printCase prints the outcome of a test case with a diff of mismatches
+func printCase(result *suite.Result) {
+	if result.Passed() {
+		fmt.Printf("  PASS  %s\n", result.Name)
+		return
+	}
+
+	fmt.Printf("  FAIL  %s\n", result.Name)
+	for _, m := range result.Mismatches {
+		fmt.Printf("        %s\n", m.Path)
+		fmt.Printf("          - %s\n", m.Expected)
+		fmt.Printf("          + %s\n", m.Actual)
+	}
+	for i, entry := range result.Output {
+		fmt.Printf("        output[%d]: %s\n", i, summarize(entry))
+	}
+}
+
+// readDefinition reads a single pipeline definition
+func readDefinition(path string) (*config.PipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline file: %w", err)
+	}
+
+	var def config.PipelineConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline file: %w", err)
+	}
+	return &def, nil
+}
+
+// configDefinition reads a pipeline definition from a server configuration
+func configDefinition(path, name string) (*config.PipelineConfig, error) {
+	if name == "" {
+		return nil, fmt.Errorf("-pipeline is required with -config")
+	}
+
+	cfg, err := config.LoadServerConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cfg.Pipelines {
+		if cfg.Pipelines[i].Name == name {
+			return &cfg.Pipelines[i], nil
+		}
+	}
+	return nil, fmt.Errorf("pipeline %q not found in %s", name, path)
+}
+
+// readSamples reads non-empty lines from a file, or stdin for "-"
+func readSamples(path string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open samples: %w", err)
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	var samples []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		samples = append(samples, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples in %s", path)
+	}
+	return samples, nil
+}
+
+// printSample prints the stages of a simulated sample
+func printSample(n int, sample *pipeline.SampleResult) {
+	fmt.Printf("Sample %d: %s\n", n, truncate(sample.Input.Raw, 100))
+
+	for _, stage := range sample.Stages {
+		took := stage.Took.Round(time.Microsecond)
+		switch {
+		case stage.Error != "":
+			fmt.Printf("  %-28s %8s  ERROR: %s\n", stage.Stage, took, stage.Error)
+		case stage.Partial:
+			fmt.Printf("  %-28s %8s  held until the entry is complete\n", stage.Stage, took)
+		case len(stage.Entries) == 0:
+			fmt.Printf("  %-28s %8s  dropped\n", stage.Stage, took)
+		default:
+			for i, entry := range stage.Entries {
+				label := stage.Stage
+				if i > 0 {
+					label = ""
+				}
+				fmt.Printf("  %-28s %8s  %s\n", label, took, summarize(entry))
+				took = 0
+			}
+		}
+	}
+
+	if sample.Error == "" {
+		fmt.Printf("  => %d entries stored\n", len(sample.Output))
+	}
+	fmt.Println()
+}
+
+// summarize formats an entry on one line
+func summarize(entry *models.LogEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "level=%s message=%q", entry.Level, truncate(entry.Message, 60))
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, _ := json.Marshal(entry.Fields[key])
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+
+	if len(entry.Tags) > 0 {
+		fmt.Fprintf(&b, " tags=%s", strings.Join(entry.Tags, ","))
+	}
+	return b.String()
+}
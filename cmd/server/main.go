@@ -1,14 +1,20 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/UmangDiyora/logpipeline/internal/api"
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
 	"github.com/UmangDiyora/logpipeline/internal/pipeline"
 	"github.com/UmangDiyora/logpipeline/internal/pipeline/receiver"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/registry"
 	"github.com/UmangDiyora/logpipeline/internal/query"
 	"github.com/UmangDiyora/logpipeline/internal/storage"
 	"github.com/UmangDiyora/logpipeline/pkg/config"
@@ -26,12 +32,16 @@ func main() {
 	fmt.Printf("LogPipeline Server v%s\n", version)
 	fmt.Printf("Loading configuration from: %s\n", *configFile)
 
-	// Load configuration
+	// Load configuration; an invalid file is fatal, a missing one falls
+	// back to the defaults
 	cfg, err := config.LoadServerConfig(*configFile)
-	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("Config file not found: %v\n", err)
 		fmt.Println("Using default configuration...")
 		cfg = defaultConfig()
+	} else if err != nil {
+		fmt.Printf("Fatal: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Create storage
@@ -55,6 +65,18 @@ func main() {
 	queryEngine := query.NewEngine(store, query.DefaultConfig())
 	fmt.Println("Query engine initialized")
 
+	// Keep entries that fail in a pipeline for inspection and replay
+	var deadLetter *dlq.Store
+	if cfg.DeadLetter.Enabled {
+		deadLetter, err = dlq.Open(cfg.DeadLetter.Path, cfg.DeadLetter.MaxRecords)
+		if err != nil {
+			fmt.Printf("Fatal: failed to open dead-letter queue: %v\n", err)
+			os.Exit(1)
+		}
+		defer deadLetter.Close()
+		fmt.Printf("Dead-letter queue at: %s (%d records)\n", cfg.DeadLetter.Path, deadLetter.Len())
+	}
+
 	// Create channels
 	receiverOutput := make(chan *models.LogEntry, 10000)
 	pipelineOutput := make(chan *models.LogEntry, 10000)
@@ -64,6 +86,8 @@ func main() {
 		HTTPAddr:     fmt.Sprintf(":%d", cfg.Server.HTTPPort),
 		MaxBatchSize: 10000,
 		RateLimit:    1000,
+		Timeout:      30 * time.Second,
+		RetryAfter:   time.Second,
 	}
 
 	recv, err := receiver.New(receiverConfig, receiverOutput)
@@ -72,35 +96,62 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create pipelines, each with its own input fed by a router that
+	// distributes received entries by filter. Pipelines created through
+	// the API run next to those of the config file.
+	manager := pipeline.NewManager(receiverOutput, pipelineOutput, deadLetter)
+	pipelines, err := registry.Open(cfg.PipelineStore, cfg.PipelineFiles, manager)
+	if err != nil {
+		fmt.Printf("Fatal: failed to open pipeline store: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := pipelines.Load(cfg.Pipelines, cfg.Routing); err != nil {
+		fmt.Printf("Fatal: failed to create pipelines: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range pipelines.List() {
+		fmt.Printf("Pipeline '%s' %s\n", p.Name, p.Status)
+	}
+	api.NewPipelineHandler(pipelines).Register(recv)
+
+	// Reload pipelines and routing from the config file; other settings
+	// require a restart
+	reload := func() (*pipeline.ReloadSummary, error) {
+		newCfg, err := config.LoadServerConfig(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		return pipelines.Load(newCfg.Pipelines, newCfg.Routing)
+	}
+	api.NewAdminHandler(reload).Register(recv)
+
+	// Serve the dead-letter queue API next to ingestion
+	if deadLetter != nil {
+		api.NewDLQHandler(deadLetter, manager).Register(recv)
+	}
+
 	if err := recv.Start(); err != nil {
 		fmt.Printf("Fatal: failed to start receiver: %v\n", err)
 		os.Exit(1)
 	}
-	defer recv.Stop()
 
 	fmt.Printf("HTTP receiver listening on port %d\n", cfg.Server.HTTPPort)
 
-	// Create pipelines
-	pipelines := make([]*pipeline.Pipeline, 0)
-	for _, pipeConfig := range cfg.Pipelines {
-		pipelineConfig := &pipeline.Config{
-			ID:      pipeConfig.Name,
-			Name:    pipeConfig.Name,
-			Workers: 4,
-		}
-
-		pipe, err := pipeline.New(pipelineConfig, receiverOutput, pipelineOutput)
-		if err != nil {
-			fmt.Printf("Warning: failed to create pipeline %s: %v\n", pipeConfig.Name, err)
-			continue
+	// Serve metrics derived from logs by metrics processors
+	if cfg.Metrics.Enabled {
+		metricsServer := metrics.NewServer(fmt.Sprintf(":%d", cfg.Metrics.Port), cfg.Metrics.Path, metrics.Default)
+		if err := metricsServer.Start(); err != nil {
+			fmt.Printf("Warning: failed to start metrics server: %v\n", err)
+		} else {
+			defer metricsServer.Stop()
+			fmt.Printf("Metrics endpoint: http://localhost:%d%s\n", cfg.Metrics.Port, cfg.Metrics.Path)
 		}
-
-		pipelines = append(pipelines, pipe)
-		fmt.Printf("Pipeline '%s' initialized\n", pipeConfig.Name)
 	}
 
 	// Start storage writer
+	written := make(chan struct{})
 	go func() {
+		defer close(written)
 		for entry := range pipelineOutput {
 			if err := store.Write(entry); err != nil {
 				fmt.Printf("Error writing to storage: %v\n", err)
@@ -113,19 +164,30 @@ func main() {
 	fmt.Printf("Ingestion endpoint: http://localhost:%d/api/v1/logs/ingest\n", cfg.Server.HTTPPort)
 	fmt.Printf("Health endpoint: http://localhost:%d/api/v1/health\n", cfg.Server.HTTPPort)
 
-	// Wait for shutdown signal
+	// Reload on SIGHUP until a shutdown signal
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	<-sigChan
-	fmt.Println("\nShutdown signal received, stopping server...")
-
-	// Stop pipelines
-	for _, pipe := range pipelines {
-		if err := pipe.Stop(); err != nil {
-			fmt.Printf("Error stopping pipeline: %v\n", err)
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
 		}
+		fmt.Println("Reload signal received, reloading pipelines...")
+		summary, err := reload()
+		if err != nil {
+			fmt.Printf("Reload failed, keeping current pipelines: %v\n", err)
+			continue
+		}
+		fmt.Printf("Reloaded: started %v, restarted %v, stopped %v, unchanged %v\n",
+			summary.Started, summary.Restarted, summary.Stopped, summary.Unchanged)
 	}
+	fmt.Println("\nShutdown signal received, stopping server...")
+
+	// Stop accepting entries, then route and process the ones received
+	recv.Stop()
+	manager.Stop()
+	close(pipelineOutput)
+	<-written
 
 	// Print statistics
 	stats := queryEngine.Stats()
@@ -151,6 +213,8 @@ func defaultConfig() *config.ServerConfig {
 			Type: "memory",
 			Path: "/tmp/logpipeline/index",
 		},
+		PipelineStore: "/tmp/logpipeline/pipelines",
+		PipelineFiles: "/tmp/logpipeline/pipelines/files",
 		Pipelines: []config.PipelineConfig{
 			{
 				Name:   "default",
@@ -160,6 +224,7 @@ func defaultConfig() *config.ServerConfig {
 		Metrics: config.MetricsConfig{
 			Enabled: true,
 			Port:    2112,
+			Path:    "/metrics",
 		},
 	}
 }
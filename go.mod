@@ -4,7 +4,8 @@ go 1.24.7
 
 require (
 	github.com/fsnotify/fsnotify v1.9.0
+	github.com/oschwald/maxminddb-golang v1.13.1
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require golang.org/x/sys v0.13.0 // indirect
+require golang.org/x/sys v0.21.0 // indirect
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/UmangDiyora/logpipeline/internal/agent/multiline"
 	"github.com/UmangDiyora/logpipeline/internal/agent/tailer"
 	"github.com/UmangDiyora/logpipeline/pkg/models"
 )
@@ -30,6 +31,9 @@ type FileCollectorConfig struct {
 
 	// Host identifier
 	Host string
+
+	// Multiline combines related lines such as stack traces
+	Multiline *multiline.Config
 }
 
 // FileCollector collects logs from files
@@ -56,6 +60,7 @@ func NewFileCollector(name string, config *FileCollectorConfig) (*FileCollector,
 		PollInterval: 1 * time.Second,
 		BatchSize:    100,
 		BatchTimeout: 5 * time.Second,
+		Multiline:    config.Multiline,
 	}
 
 	if tailerConfig.BufferSize == 0 {
@@ -0,0 +1,278 @@
+package multiline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Match modes
+const (
+	// MatchAfter appends matching lines to the preceding line
+	MatchAfter = "after"
+
+	// MatchBefore prepends matching lines to the following line
+	MatchBefore = "before"
+)
+
+// PresetStackTrace groups Java, Go, Python and Node stack traces
+const PresetStackTrace = "stacktrace"
+
+// Config holds multiline aggregation configuration
+type Config struct {
+	// Preset selects built-in continuation rules (e.g. "stacktrace")
+	Preset string
+
+	// Pattern is the regex pattern to match
+	Pattern string
+
+	// Negate inverts the pattern matching
+	Negate bool
+
+	// Match specifies when to combine lines (before, after)
+	Match string
+
+	// MaxLines is the maximum lines to combine
+	MaxLines int
+
+	// Timeout is the maximum time to wait for continuation
+	Timeout time.Duration
+}
+
+// DefaultConfig returns a Config for stack trace aggregation
+func DefaultConfig() *Config {
+	return &Config{
+		Preset:   PresetStackTrace,
+		Match:    MatchAfter,
+		MaxLines: 500,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// matcher decides whether a line continues the current event
+type matcher interface {
+	// continues reports whether line belongs to the pending event
+	continues(line string, pending bool) bool
+
+	// reset clears any state carried between lines
+	reset()
+}
+
+// Aggregator combines related lines into single events. It is not safe for
+// concurrent use; each source should own its aggregator.
+type Aggregator struct {
+	config   *Config
+	matcher  matcher
+	lines    []string
+	deadline time.Time
+	now      func() time.Time
+}
+
+// New creates a new multiline aggregator
+func New(config *Config) (*Aggregator, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	cfg := *config
+	if cfg.Match == "" {
+		cfg.Match = MatchAfter
+	}
+	if cfg.MaxLines <= 0 {
+		cfg.MaxLines = 500
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	if cfg.Match != MatchAfter && cfg.Match != MatchBefore {
+		return nil, fmt.Errorf("unsupported multiline match: %s", cfg.Match)
+	}
+
+	var m matcher
+	switch {
+	case cfg.Preset == PresetStackTrace:
+		if cfg.Match != MatchAfter {
+			return nil, fmt.Errorf("preset %s requires match %q", cfg.Preset, MatchAfter)
+		}
+		m = &stackTraceMatcher{}
+	case cfg.Preset != "":
+		return nil, fmt.Errorf("unsupported multiline preset: %s", cfg.Preset)
+	case cfg.Pattern == "":
+		return nil, fmt.Errorf("multiline pattern or preset is required")
+	default:
+		re, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile multiline pattern: %w", err)
+		}
+		m = &patternMatcher{pattern: re, negate: cfg.Negate}
+	}
+
+	return &Aggregator{
+		config:  &cfg,
+		matcher: m,
+		lines:   make([]string, 0),
+		now:     time.Now,
+	}, nil
+}
+
+// Add feeds a line to the aggregator and returns any completed events
+func (a *Aggregator) Add(line string) []string {
+	line = strings.TrimRight(line, "\r\n")
+	pending := len(a.lines) > 0
+	matches := a.matcher.continues(line, pending)
+
+	var events []string
+
+	switch a.config.Match {
+	case MatchAfter:
+		// A matching line continues the pending event, anything else starts
+		// a new one
+		if pending && !matches {
+			events = append(events, a.take())
+		}
+		a.append(line)
+
+	case MatchBefore:
+		// Matching lines accumulate until a non-matching line closes the event
+		a.append(line)
+		if !matches {
+			events = append(events, a.take())
+		}
+	}
+
+	if len(a.lines) >= a.config.MaxLines {
+		events = append(events, a.take())
+	}
+
+	return events
+}
+
+// Flush returns the pending event, if any
+func (a *Aggregator) Flush() []string {
+	if len(a.lines) == 0 {
+		return nil
+	}
+	a.matcher.reset()
+	return []string{a.take()}
+}
+
+// FlushExpired returns the pending event if no continuation arrived within
+// the configured timeout
+func (a *Aggregator) FlushExpired() []string {
+	if len(a.lines) == 0 || a.now().Before(a.deadline) {
+		return nil
+	}
+	return a.Flush()
+}
+
+// Pending returns the number of buffered lines
+func (a *Aggregator) Pending() int {
+	return len(a.lines)
+}
+
+// append buffers a line and extends the timeout
+func (a *Aggregator) append(line string) {
+	a.lines = append(a.lines, line)
+	a.deadline = a.now().Add(a.config.Timeout)
+}
+
+// take returns the buffered event and resets the buffer
+func (a *Aggregator) take() string {
+	event := strings.Join(a.lines, "\n")
+	a.lines = a.lines[:0]
+	return event
+}
+
+// patternMatcher implements regex based continuation
+type patternMatcher struct {
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+func (m *patternMatcher) continues(line string, pending bool) bool {
+	return m.pattern.MatchString(line) != m.negate
+}
+
+func (m *patternMatcher) reset() {}
+
+var (
+	// Java/Node frames, Go file lines and Python frames are all indented
+	indentedLine = regexp.MustCompile(`^[ \t]+\S`)
+
+	// Java cause chains and elided frames
+	javaContinuation = regexp.MustCompile(`^(Caused by: |Suppressed: |\s*\.\.\. \d+ (more|common frames omitted))`)
+
+	// Exception headers such as "java.lang.IllegalStateException: boom" or "TypeError: x"
+	exceptionHeader = regexp.MustCompile(`^([A-Za-z_$][\w$]*\.)*[A-Za-z_$][\w$]*(Exception|Error|Throwable)(: .*)?$`)
+
+	// Python traceback header
+	pythonTraceback = regexp.MustCompile(`^Traceback \(most recent call last\):`)
+
+	// Python final exception line, e.g. "ValueError: bad value" or "KeyboardInterrupt"
+	pythonException = regexp.MustCompile(`^[A-Za-z_][\w.]*(: .*)?$`)
+
+	// Go goroutine headers and frames
+	goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	goFunctionLine  = regexp.MustCompile(`^(created by )?[\w./*()\[\]-]+\(.*\)( in goroutine \d+)?$`)
+)
+
+// stackTraceMatcher recognizes continuation lines of common stack traces
+type stackTraceMatcher struct {
+	inPython bool
+	inGo     bool
+}
+
+func (m *stackTraceMatcher) continues(line string, pending bool) bool {
+	if !pending {
+		m.observeStart(line)
+		return false
+	}
+
+	switch {
+	case pythonTraceback.MatchString(line):
+		m.inPython = true
+		return true
+
+	case goroutineHeader.MatchString(line):
+		m.inGo = true
+		return true
+
+	case indentedLine.MatchString(line):
+		return true
+
+	case javaContinuation.MatchString(line):
+		return true
+
+	case m.inGo && (line == "" || goFunctionLine.MatchString(line) || strings.HasPrefix(line, "exit status ")):
+		return true
+
+	case m.inPython && pythonException.MatchString(line):
+		// The exception line terminates the traceback
+		m.inPython = false
+		return true
+
+	case exceptionHeader.MatchString(line):
+		return true
+	}
+
+	m.reset()
+	m.observeStart(line)
+	return false
+}
+
+// observeStart tracks state for a line that begins a new event
+func (m *stackTraceMatcher) observeStart(line string) {
+	switch {
+	case pythonTraceback.MatchString(line):
+		m.inPython = true
+	case goroutineHeader.MatchString(line), strings.HasPrefix(line, "panic: "), strings.HasPrefix(line, "fatal error: "):
+		m.inGo = true
+	}
+}
+
+func (m *stackTraceMatcher) reset() {
+	m.inPython = false
+	m.inGo = false
+}
@@ -0,0 +1,112 @@
+package multiline
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func feed(agg *Aggregator, input string) []string {
+	events := make([]string, 0)
+	for _, line := range strings.Split(input, "\n") {
+		events = append(events, agg.Add(line+"\n")...)
+	}
+	return append(events, agg.Flush()...)
+}
+
+func TestStackTracePreset(t *testing.T) {
+	agg, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatalf("failed to create aggregator: %v", err)
+	}
+
+	input := `INFO starting
+ERROR request failed
+java.lang.IllegalStateException: boom
+	at com.example.App.run(App.java:10)
+Caused by: java.io.IOException: reset
+	... 1 more
+ERROR job failed
+Traceback (most recent call last):
+  File "app.py", line 1, in <module>
+ValueError: bad
+panic: boom
+
+goroutine 1 [running]:
+main.main()
+	/app/main.go:5 +0x1d
+exit status 2
+INFO done`
+
+	events := feed(agg, input)
+
+	expected := []int{1, 5, 4, 6, 1}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %q", len(expected), len(events), events)
+	}
+
+	for i, lines := range expected {
+		if got := strings.Count(events[i], "\n") + 1; got != lines {
+			t.Errorf("event %d: expected %d lines, got %d: %q", i, lines, got, events[i])
+		}
+	}
+}
+
+func TestPatternNegateAfter(t *testing.T) {
+	agg, err := New(&Config{Pattern: `^\d{4}-`, Negate: true, Match: MatchAfter})
+	if err != nil {
+		t.Fatalf("failed to create aggregator: %v", err)
+	}
+
+	events := feed(agg, "2024-01-01 first\ncontinued\n2024-01-02 second")
+	if len(events) != 2 || events[0] != "2024-01-01 first\ncontinued" {
+		t.Errorf("unexpected events: %q", events)
+	}
+}
+
+func TestPatternBefore(t *testing.T) {
+	agg, err := New(&Config{Pattern: `\\$`, Match: MatchBefore})
+	if err != nil {
+		t.Fatalf("failed to create aggregator: %v", err)
+	}
+
+	events := feed(agg, "a \\\nb \\\nc\nd")
+	if len(events) != 2 || events[0] != "a \\\nb \\\nc" {
+		t.Errorf("unexpected events: %q", events)
+	}
+}
+
+func TestMaxLinesAndTimeout(t *testing.T) {
+	agg, err := New(&Config{Pattern: `^\s`, MaxLines: 2, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to create aggregator: %v", err)
+	}
+
+	if events := agg.Add("a"); len(events) != 0 {
+		t.Fatalf("unexpected events: %q", events)
+	}
+	if events := agg.Add("  b"); len(events) != 1 {
+		t.Fatalf("expected flush at max lines, got %q", events)
+	}
+
+	now := time.Now()
+	agg.now = func() time.Time { return now }
+	agg.Add("c")
+	if events := agg.FlushExpired(); len(events) != 0 {
+		t.Errorf("event flushed before timeout: %q", events)
+	}
+
+	agg.now = func() time.Time { return now.Add(2 * time.Second) }
+	if events := agg.FlushExpired(); len(events) != 1 || events[0] != "c" {
+		t.Errorf("expected expired event, got %q", events)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	if _, err := New(&Config{}); err == nil {
+		t.Error("expected error without pattern or preset")
+	}
+	if _, err := New(&Config{Pattern: "x", Match: "sideways"}); err == nil {
+		t.Error("expected error for invalid match")
+	}
+}
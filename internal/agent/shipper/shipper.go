@@ -6,10 +6,12 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -242,6 +244,16 @@ func (s *Shipper) sendWithRetry(batch *models.Batch) error {
 		}
 
 		lastErr = err
+
+		// A throttled endpoint is healthy but busy; wait as long as it asks
+		var throttled *throttledError
+		if errors.As(err, &throttled) {
+			if attempt < s.config.MaxRetries {
+				time.Sleep(throttled.retryAfter)
+			}
+			continue
+		}
+
 		s.recordFailure(ep)
 
 		// Exponential backoff
@@ -297,6 +309,9 @@ func (s *Shipper) send(ep *endpoint, batch *models.Batch) error {
 	defer resp.Body.Close()
 
 	// Check response
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &throttledError{retryAfter: s.retryAfter(resp)}
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
@@ -305,6 +320,24 @@ func (s *Shipper) send(ep *endpoint, batch *models.Batch) error {
 	return nil
 }
 
+// throttledError is returned when the server asks the shipper to slow down
+type throttledError struct {
+	retryAfter time.Duration
+}
+
+// Error implements error
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("server throttled request, retry after %s", e.retryAfter)
+}
+
+// retryAfter reads the Retry-After header, falling back to RetryBackoff
+func (s *Shipper) retryAfter(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return s.config.RetryBackoff
+}
+
 // compressGzip compresses data using gzip
 func (s *Shipper) compressGzip(data []byte) ([]byte, error) {
 	var buf bytes.Buffer
@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/UmangDiyora/logpipeline/internal/agent/multiline"
 	"github.com/fsnotify/fsnotify"
 )
 
@@ -31,6 +32,9 @@ type Config struct {
 
 	// BatchTimeout is max time to wait before flushing a batch
 	BatchTimeout time.Duration
+
+	// Multiline combines related lines (e.g. stack traces) per file
+	Multiline *multiline.Config
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -74,6 +78,13 @@ func New(config *Config, output chan<- string) (*FileTailer, error) {
 		config = DefaultConfig()
 	}
 
+	// Validate multiline settings before any file is opened
+	if config.Multiline != nil {
+		if _, err := multiline.New(config.Multiline); err != nil {
+			return nil, fmt.Errorf("invalid multiline config: %w", err)
+		}
+	}
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create watcher: %w", err)
@@ -238,6 +249,12 @@ func (ft *FileTailer) tailFile(path string) {
 
 	reader := bufio.NewReaderSize(f, ft.config.BufferSize)
 	batch := make([]string, 0, ft.config.BatchSize)
+
+	// Each file gets its own aggregator so that lines never mix across files
+	var aggregator *multiline.Aggregator
+	if ft.config.Multiline != nil {
+		aggregator, _ = multiline.New(ft.config.Multiline)
+	}
 	batchTimer := time.NewTimer(ft.config.BatchTimeout)
 	defer batchTimer.Stop()
 
@@ -248,6 +265,9 @@ func (ft *FileTailer) tailFile(path string) {
 		select {
 		case <-ft.ctx.Done():
 			// Flush remaining batch
+			if aggregator != nil {
+				batch = append(batch, aggregator.Flush()...)
+			}
 			if len(batch) > 0 {
 				ft.sendBatch(batch)
 			}
@@ -265,6 +285,11 @@ func (ft *FileTailer) tailFile(path string) {
 			line, err := reader.ReadString('\n')
 			if err != nil {
 				if err == io.EOF {
+					// Release multiline events that stopped growing
+					if aggregator != nil {
+						batch = append(batch, aggregator.FlushExpired()...)
+					}
+
 					// End of file, flush batch if we have data
 					if len(batch) > 0 {
 						ft.sendBatch(batch)
@@ -295,7 +320,11 @@ func (ft *FileTailer) tailFile(path string) {
 			ft.updateOffset(path, offset)
 
 			// Add to batch
-			batch = append(batch, line)
+			if aggregator != nil {
+				batch = append(batch, aggregator.Add(line)...)
+			} else {
+				batch = append(batch, line)
+			}
 
 			// Send batch if full
 			if len(batch) >= ft.config.BatchSize {
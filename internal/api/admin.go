@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+)
+
+// ReloadFunc reloads the pipeline configuration
+type ReloadFunc func() (*pipeline.ReloadSummary, error)
+
+// reloadWait is how long a reload request waits for the result. Replaced
+// pipelines may take up to the drain timeout to finish, which is longer
+// than the server lets a response take, so slower reloads are answered
+// with 202 Accepted and complete in the background.
+const reloadWait = 10 * time.Second
+
+// AdminHandler serves server administration:
+//
+//	POST /api/v1/admin/reload    reload pipelines and routing from the config file
+type AdminHandler struct {
+	reload ReloadFunc
+}
+
+// NewAdminHandler creates an administration handler
+func NewAdminHandler(reload ReloadFunc) *AdminHandler {
+	return &AdminHandler{reload: reload}
+}
+
+// Register registers the endpoints on a mux
+func (h *AdminHandler) Register(mux Mux) {
+	mux.Handle("POST /api/v1/admin/reload", http.HandlerFunc(h.handleReload))
+}
+
+// reloadResult is the outcome of a reload
+type reloadResult struct {
+	summary *pipeline.ReloadSummary
+	err     error
+}
+
+// handleReload reloads the configuration. An invalid configuration leaves
+// the running pipelines untouched. A reload still draining replaced
+// pipelines after reloadWait is reported as accepted.
+func (h *AdminHandler) handleReload(w http.ResponseWriter, req *http.Request) {
+	done := make(chan reloadResult, 1)
+	go func() {
+		summary, err := h.reload()
+		done <- reloadResult{summary: summary, err: err}
+	}()
+
+	timer := time.NewTimer(reloadWait)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			writeError(w, http.StatusBadRequest, result.err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, result.summary)
+	case <-timer.C:
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "reloading"})
+		go func() {
+			if result := <-done; result.err != nil {
+				fmt.Printf("Reload failed: %v\n", result.err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,61 @@
+// Package api serves the management endpoints of the server next to the
+// ingestion endpoint.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Mux registers HTTP handlers. The receiver implements it so that the
+// management endpoints share its listener and authentication.
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// maxLimit bounds the page size of list endpoints
+const maxLimit = 1000
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// decodeBody decodes a JSON request body, rejecting unknown fields
+func decodeBody(req *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// queryInt returns an integer query parameter, or def when it is absent
+func queryInt(req *http.Request, name string, def int) (int, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, &paramError{name: name, value: value}
+	}
+	return n, nil
+}
+
+// paramError is an invalid query parameter
+type paramError struct {
+	name  string
+	value string
+}
+
+// Error implements error
+func (e *paramError) Error() string {
+	return "invalid " + e.name + ": " + strconv.Quote(e.value)
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Replayer sends an entry to the input of a running pipeline
+type Replayer interface {
+	Replay(ctx context.Context, pipeline string, entry *models.LogEntry) error
+}
+
+// DLQHandler serves the dead-letter queue:
+//
+//	GET    /api/v1/dlq              list records (pipeline, stage, before, offset, limit)
+//	GET    /api/v1/dlq/{id}         show a record
+//	DELETE /api/v1/dlq/{id}         delete a record
+//	DELETE /api/v1/dlq              purge records by filter (all=true purges everything)
+//	POST   /api/v1/dlq/replay       replay records through a pipeline
+type DLQHandler struct {
+	store    *dlq.Store
+	replayer Replayer
+}
+
+// NewDLQHandler creates a dead-letter queue handler
+func NewDLQHandler(store *dlq.Store, replayer Replayer) *DLQHandler {
+	return &DLQHandler{
+		store:    store,
+		replayer: replayer,
+	}
+}
+
+// Register registers the endpoints on a mux
+func (h *DLQHandler) Register(mux Mux) {
+	mux.Handle("GET /api/v1/dlq", http.HandlerFunc(h.handleList))
+	mux.Handle("DELETE /api/v1/dlq", http.HandlerFunc(h.handlePurge))
+	mux.Handle("POST /api/v1/dlq/replay", http.HandlerFunc(h.handleReplay))
+	mux.Handle("GET /api/v1/dlq/{id}", http.HandlerFunc(h.handleGet))
+	mux.Handle("DELETE /api/v1/dlq/{id}", http.HandlerFunc(h.handleDelete))
+}
+
+// ReplayRequest selects the records to replay
+type ReplayRequest struct {
+	// IDs lists records to replay
+	IDs []string `json:"ids,omitempty"`
+
+	// Filter selects records to replay when no IDs are given
+	Filter *FilterRequest `json:"filter,omitempty"`
+
+	// All replays every record when no IDs or filter are given
+	All bool `json:"all,omitempty"`
+
+	// Pipeline replays through another pipeline than the one each record
+	// failed in
+	Pipeline string `json:"pipeline,omitempty"`
+}
+
+// FilterRequest selects records by pipeline, stage prefix and age
+type FilterRequest struct {
+	Pipeline string    `json:"pipeline,omitempty"`
+	Stage    string    `json:"stage,omitempty"`
+	Before   time.Time `json:"before,omitempty"`
+}
+
+// ReplayResponse reports the outcome of a replay
+type ReplayResponse struct {
+	Replayed int             `json:"replayed"`
+	Failed   []ReplayFailure `json:"failed"`
+}
+
+// ReplayFailure is a record that could not be replayed
+type ReplayFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// filter converts the request filter
+func (f *FilterRequest) filter() *dlq.Filter {
+	if f == nil {
+		return nil
+	}
+	return &dlq.Filter{
+		PipelineID: f.Pipeline,
+		Stage:      f.Stage,
+		Before:     f.Before,
+	}
+}
+
+// queryFilter reads a filter from query parameters. It returns nil when
+// no parameter is set.
+func queryFilter(req *http.Request) (*dlq.Filter, error) {
+	query := req.URL.Query()
+	filter := &dlq.Filter{
+		PipelineID: query.Get("pipeline"),
+		Stage:      query.Get("stage"),
+	}
+	if before := query.Get("before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			return nil, fmt.Errorf("invalid before: %w", err)
+		}
+		filter.Before = t
+	}
+
+	if filter.PipelineID == "" && filter.Stage == "" && filter.Before.IsZero() {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// handleList lists records
+func (h *DLQHandler) handleList(w http.ResponseWriter, req *http.Request) {
+	filter, err := queryFilter(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offset, err := queryInt(req, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(req, "limit", 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit == 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	records, total := h.store.List(filter, offset, limit)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":   total,
+		"records": records,
+	})
+}
+
+// handleGet returns a record
+func (h *DLQHandler) handleGet(w http.ResponseWriter, req *http.Request) {
+	record, ok := h.store.Get(req.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "record not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// handleDelete deletes a record
+func (h *DLQHandler) handleDelete(w http.ResponseWriter, req *http.Request) {
+	deleted, err := h.store.Delete(req.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		writeError(w, http.StatusNotFound, "record not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": deleted})
+}
+
+// handlePurge deletes the records matching a filter
+func (h *DLQHandler) handlePurge(w http.ResponseWriter, req *http.Request) {
+	filter, err := queryFilter(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Purging everything has to be asked for explicitly
+	if filter == nil && req.URL.Query().Get("all") != "true" {
+		writeError(w, http.StatusBadRequest, "a filter or all=true is required")
+		return
+	}
+
+	purged, err := h.store.Purge(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// handleReplay sends records back through a pipeline. Records accepted by
+// the pipeline are removed; entries that fail again come back as new
+// records.
+func (h *DLQHandler) handleReplay(w http.ResponseWriter, req *http.Request) {
+	var request ReplayRequest
+	if err := decodeBody(req, &request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	var records []*dlq.Record
+	var missing []string
+	switch {
+	case len(request.IDs) > 0:
+		for _, id := range request.IDs {
+			record, ok := h.store.Get(id)
+			if !ok {
+				missing = append(missing, id)
+				continue
+			}
+			records = append(records, record)
+		}
+	case request.Filter != nil || request.All:
+		records, _ = h.store.List(request.Filter.filter(), 0, 0)
+	default:
+		writeError(w, http.StatusBadRequest, "ids, a filter or all is required")
+		return
+	}
+
+	response := ReplayResponse{Failed: make([]ReplayFailure, 0)}
+	for _, id := range missing {
+		response.Failed = append(response.Failed, ReplayFailure{ID: id, Error: "record not found"})
+	}
+
+	replayed := make([]string, 0, len(records))
+	for _, record := range records {
+		target := request.Pipeline
+		if target == "" {
+			target = record.PipelineID
+		}
+
+		if record.Entry == nil {
+			response.Failed = append(response.Failed, ReplayFailure{ID: record.ID, Error: "record has no entry"})
+			continue
+		}
+
+		// Pipelines modify entries in place; the stored record stays intact
+		// until the replay succeeds
+		if err := h.replayer.Replay(req.Context(), target, record.Entry.Clone()); err != nil {
+			response.Failed = append(response.Failed, ReplayFailure{ID: record.ID, Error: err.Error()})
+			continue
+		}
+		replayed = append(replayed, record.ID)
+	}
+
+	if _, err := h.store.Delete(replayed...); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Replayed = len(replayed)
+	writeJSON(w, http.StatusOK, response)
+}
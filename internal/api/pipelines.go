@@ -0,0 +1,244 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/registry"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// PipelineHandler serves pipeline management:
+//
+//	GET    /api/v1/pipelines               list pipelines with status and metrics
+//	POST   /api/v1/pipelines               create and start a pipeline
+//	GET    /api/v1/pipelines/{id}          show a pipeline
+//	PUT    /api/v1/pipelines/{id}          replace a pipeline definition
+//	DELETE /api/v1/pipelines/{id}          stop and delete a pipeline
+//	POST   /api/v1/pipelines/{id}/enable   start a pipeline
+//	POST   /api/v1/pipelines/{id}/disable  stop a pipeline
+//	POST   /api/v1/pipelines/simulate      run samples through a pipeline without storing them
+type PipelineHandler struct {
+	registry *registry.Registry
+}
+
+// NewPipelineHandler creates a pipeline management handler
+func NewPipelineHandler(registry *registry.Registry) *PipelineHandler {
+	return &PipelineHandler{registry: registry}
+}
+
+// Register registers the endpoints on a mux
+func (h *PipelineHandler) Register(mux Mux) {
+	mux.Handle("GET /api/v1/pipelines", http.HandlerFunc(h.handleList))
+	mux.Handle("POST /api/v1/pipelines", http.HandlerFunc(h.handleCreate))
+	mux.Handle("GET /api/v1/pipelines/{id}", http.HandlerFunc(h.handleGet))
+	mux.Handle("PUT /api/v1/pipelines/{id}", http.HandlerFunc(h.handleUpdate))
+	mux.Handle("DELETE /api/v1/pipelines/{id}", http.HandlerFunc(h.handleDelete))
+	mux.Handle("POST /api/v1/pipelines/{id}/enable", http.HandlerFunc(h.handleEnable))
+	mux.Handle("POST /api/v1/pipelines/{id}/disable", http.HandlerFunc(h.handleDisable))
+	mux.Handle("POST /api/v1/pipelines/simulate", http.HandlerFunc(h.handleSimulate))
+}
+
+// maxSamples bounds the entries of a simulation
+const maxSamples = 1000
+
+// SimulateRequest is a pipeline and the samples to run through it
+type SimulateRequest struct {
+	// ID names a pipeline to simulate
+	ID string `json:"id,omitempty"`
+
+	// Pipeline is a definition to simulate instead of an existing pipeline
+	Pipeline *models.Pipeline `json:"pipeline,omitempty"`
+
+	// Samples are raw lines, as read by the agent
+	Samples []string `json:"samples,omitempty"`
+
+	// Source and Host are set on the sample entries
+	Source string `json:"source,omitempty"`
+	Host   string `json:"host,omitempty"`
+
+	// Entries are complete entries simulated after the samples
+	Entries []*models.LogEntry `json:"entries,omitempty"`
+}
+
+// SimulateResponse holds the simulation of each sample
+type SimulateResponse struct {
+	Pipeline string                   `json:"pipeline"`
+	Results  []*pipeline.SampleResult `json:"results"`
+}
+
+// handleList lists pipelines
+func (h *PipelineHandler) handleList(w http.ResponseWriter, req *http.Request) {
+	pipelines := h.registry.List()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":     len(pipelines),
+		"pipelines": pipelines,
+	})
+}
+
+// handleGet returns a pipeline
+func (h *PipelineHandler) handleGet(w http.ResponseWriter, req *http.Request) {
+	p, err := h.registry.Get(req.PathValue("id"))
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// handleCreate creates a pipeline. Pipelines are enabled unless the
+// request says otherwise.
+func (h *PipelineHandler) handleCreate(w http.ResponseWriter, req *http.Request) {
+	p := models.NewPipeline("")
+	if err := decodeBody(req, p); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	created, err := h.registry.Create(p)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// handleUpdate replaces a pipeline definition
+func (h *PipelineHandler) handleUpdate(w http.ResponseWriter, req *http.Request) {
+	var p models.Pipeline
+	if err := decodeBody(req, &p); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	updated, err := h.registry.Update(req.PathValue("id"), &p)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// handleDelete deletes a pipeline
+func (h *PipelineHandler) handleDelete(w http.ResponseWriter, req *http.Request) {
+	if err := h.registry.Delete(req.PathValue("id")); err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEnable starts a pipeline
+func (h *PipelineHandler) handleEnable(w http.ResponseWriter, req *http.Request) {
+	h.setEnabled(w, req, true)
+}
+
+// handleDisable stops a pipeline
+func (h *PipelineHandler) handleDisable(w http.ResponseWriter, req *http.Request) {
+	h.setEnabled(w, req, false)
+}
+
+// setEnabled starts or stops a pipeline
+func (h *PipelineHandler) setEnabled(w http.ResponseWriter, req *http.Request, enabled bool) {
+	p, err := h.registry.SetEnabled(req.PathValue("id"), enabled)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// handleSimulate runs samples through a pipeline, inline or by ID, and
+// returns the entries after each stage. Nothing is stored and running
+// pipelines are not affected.
+func (h *PipelineHandler) handleSimulate(w http.ResponseWriter, req *http.Request) {
+	var request SimulateRequest
+	if err := decodeBody(req, &request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	model := request.Pipeline
+	switch {
+	case request.ID != "" && model != nil:
+		writeError(w, http.StatusBadRequest, "give either id or pipeline")
+		return
+	case request.ID != "":
+		p, err := h.registry.Get(request.ID)
+		if err != nil {
+			writeRegistryError(w, err)
+			return
+		}
+		model = p
+	case model == nil:
+		writeError(w, http.StatusBadRequest, "id or pipeline is required")
+		return
+	}
+
+	count := len(request.Samples) + len(request.Entries)
+	if count == 0 {
+		writeError(w, http.StatusBadRequest, "samples or entries are required")
+		return
+	}
+	if count > maxSamples {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d samples are allowed", maxSamples))
+		return
+	}
+
+	def, err := pipeline.FromModel(model)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Inline definitions come from the client and may only read the files
+	// a stored pipeline could
+	if request.Pipeline != nil {
+		if err := h.registry.CheckFiles(def); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if def.Name == "" {
+		def.Name = "simulate"
+	}
+	simulator, err := pipeline.NewSimulator(def)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer simulator.Close()
+
+	response := SimulateResponse{
+		Pipeline: def.Name,
+		Results:  make([]*pipeline.SampleResult, 0, count),
+	}
+	for _, sample := range request.Samples {
+		entry := pipeline.SampleEntry(sample, request.Source, request.Host)
+		response.Results = append(response.Results, simulator.Run(entry))
+	}
+	for _, entry := range request.Entries {
+		if entry == nil {
+			writeError(w, http.StatusBadRequest, "entries must not be null")
+			return
+		}
+		response.Results = append(response.Results, simulator.Run(entry))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// writeRegistryError maps registry errors to HTTP statuses
+func writeRegistryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrPipelineNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, registry.ErrExists), errors.Is(err, registry.ErrReadOnly):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrInvalidPipeline):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/registry"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// newPipelineServer serves the pipeline endpoints of a registry whose
+// files directory is files under dir
+func newPipelineServer(t *testing.T, dir string) *httptest.Server {
+	t.Helper()
+
+	manager := pipeline.NewManager(make(chan *models.LogEntry), make(chan *models.LogEntry, 10), nil)
+	t.Cleanup(manager.Stop)
+
+	r, err := registry.Open(filepath.Join(dir, "pipelines"), filepath.Join(dir, "files"), manager)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := r.Load([]config.PipelineConfig{{Name: "default"}}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewPipelineHandler(r).Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSimulateRejectsFilesOutsideDirectory(t *testing.T) {
+	dir := t.TempDir()
+	server := newPipelineServer(t, dir)
+
+	// A readable lookup file next to the files directory
+	lookup := filepath.Join(dir, "owners.csv")
+	if err := os.WriteFile(lookup, []byte("service,team\napi,payments\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lookup file: %v", err)
+	}
+
+	body := `{"pipeline":{"name":"inline","processors":[{"type":"enrich","config":{"file":` +
+		`"` + lookup + `","fields":["service"],"watch":false}}]},"samples":["hello"]}`
+	resp, err := http.Post(server.URL+"/api/v1/pipelines/simulate", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a file outside pipeline_files, got %d", resp.StatusCode)
+	}
+
+	// The same definition may read files in the files directory
+	inside := filepath.Join(dir, "files", "owners.csv")
+	os.MkdirAll(filepath.Dir(inside), 0755)
+	if err := os.Rename(lookup, inside); err != nil {
+		t.Fatalf("Failed to move lookup file: %v", err)
+	}
+	body = strings.Replace(body, lookup, inside, 1)
+	resp, err = http.Post(server.URL+"/api/v1/pipelines/simulate", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a file in pipeline_files, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,315 @@
+// Package dlq keeps entries that failed parsing or processing so that
+// they can be inspected and replayed once the cause is fixed.
+package dlq
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// DefaultMaxRecords is the number of records kept when no limit is set
+const DefaultMaxRecords = 10000
+
+// Record is an entry that failed in a pipeline
+type Record struct {
+	// ID identifies the record
+	ID string `json:"id"`
+
+	// PipelineID is the pipeline the entry failed in
+	PipelineID string `json:"pipeline_id"`
+
+	// Stage is the failing stage, e.g. "parser:json" or "processor[2]:geoip"
+	Stage string `json:"stage"`
+
+	// Error is the failure message
+	Error string `json:"error"`
+
+	// Entry is the entry as it was before the failing pipeline changed it
+	Entry *models.LogEntry `json:"entry"`
+
+	// FailedAt is when the entry failed
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Filter selects records
+type Filter struct {
+	// PipelineID matches records of one pipeline
+	PipelineID string
+
+	// Stage matches records whose stage starts with the value
+	Stage string
+
+	// Before matches records that failed before the time
+	Before time.Time
+}
+
+// Match reports whether a record passes the filter
+func (f *Filter) Match(r *Record) bool {
+	if f == nil {
+		return true
+	}
+	if f.PipelineID != "" && r.PipelineID != f.PipelineID {
+		return false
+	}
+	if f.Stage != "" && !strings.HasPrefix(r.Stage, f.Stage) {
+		return false
+	}
+	if !f.Before.IsZero() && !r.FailedAt.Before(f.Before) {
+		return false
+	}
+	return true
+}
+
+// Store holds dead-letter records in memory, backed by a JSON lines file.
+// Records are appended to the file as they arrive; the file is rewritten
+// when records are removed or it grows well past the record limit.
+type Store struct {
+	mu          sync.RWMutex
+	path        string
+	file        *os.File
+	maxRecords  int
+	records     []*Record
+	index       map[string]*Record
+	fileRecords int
+}
+
+// Open opens or creates a store in dir. When more than maxRecords records
+// are held, the oldest are discarded.
+func Open(dir string, maxRecords int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dlq directory: %w", err)
+	}
+	if maxRecords <= 0 {
+		maxRecords = DefaultMaxRecords
+	}
+
+	s := &Store{
+		path:       filepath.Join(dir, "dlq.jsonl"),
+		maxRecords: maxRecords,
+		index:      make(map[string]*Record),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dlq file: %w", err)
+	}
+	s.file = f
+	return s, nil
+}
+
+// load reads the records in the file
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open dlq file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// Skip a record cut short by a crash
+			continue
+		}
+		s.fileRecords++
+		s.append(&r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read dlq file: %w", err)
+	}
+	return nil
+}
+
+// append adds a record in memory, evicting the oldest over the limit
+func (s *Store) append(r *Record) {
+	if _, exists := s.index[r.ID]; exists {
+		return
+	}
+	s.records = append(s.records, r)
+	s.index[r.ID] = r
+
+	if len(s.records) > s.maxRecords {
+		evicted := s.records[0]
+		s.records[0] = nil
+		s.records = s.records[1:]
+		delete(s.index, evicted.ID)
+	}
+}
+
+// Add stores a record, assigning an ID and failure time when missing
+func (s *Store) Add(r *Record) error {
+	if r.ID == "" {
+		r.ID = newID()
+	}
+	if r.FailedAt.IsZero() {
+		r.FailedAt = time.Now()
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode dlq record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write dlq record: %w", err)
+	}
+	s.fileRecords++
+	s.append(r)
+
+	// Records evicted in memory are still in the file until it is rewritten
+	if s.fileRecords > 2*s.maxRecords {
+		return s.rewrite()
+	}
+	return nil
+}
+
+// newID returns a random record ID
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Get returns a record by ID
+func (s *Store) Get(id string) (*Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.index[id]
+	return r, ok
+}
+
+// List returns the records matching a filter, oldest first, skipping
+// offset records and returning at most limit (all when limit is zero),
+// along with the total number of matches
+func (s *Store) List(filter *Filter, offset, limit int) ([]*Record, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Record, 0)
+	total := 0
+	for _, r := range s.records {
+		if !filter.Match(r) {
+			continue
+		}
+		total++
+		if total <= offset || (limit > 0 && len(result) >= limit) {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result, total
+}
+
+// Delete removes records by ID and returns the number removed
+func (s *Store) Delete(ids ...string) (int, error) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	return s.remove(func(r *Record) bool { return remove[r.ID] })
+}
+
+// Purge removes the records matching a filter and returns the number removed
+func (s *Store) Purge(filter *Filter) (int, error) {
+	return s.remove(filter.Match)
+}
+
+// remove deletes the records selected by fn and rewrites the file
+func (s *Store) remove(fn func(r *Record) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.records[:0]
+	removed := 0
+	for _, r := range s.records {
+		if fn(r) {
+			delete(s.index, r.ID)
+			removed++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	for i := len(kept); i < len(s.records); i++ {
+		s.records[i] = nil
+	}
+	s.records = kept
+
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.rewrite()
+}
+
+// rewrite replaces the file with the records held in memory. The caller
+// holds the lock.
+func (s *Store) rewrite() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite dlq file: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, r := range s.records {
+		if err := encoder.Encode(r); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("failed to rewrite dlq file: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rewrite dlq file: %w", err)
+	}
+	f.Close()
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace dlq file: %w", err)
+	}
+
+	s.file.Close()
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dlq file: %w", err)
+	}
+	s.fileRecords = len(s.records)
+	return nil
+}
+
+// Len returns the number of records
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,149 @@
+package dlq
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func addRecords(t *testing.T, s *Store, n int, pipelineID, stage string) []*Record {
+	t.Helper()
+	records := make([]*Record, 0, n)
+	for i := 0; i < n; i++ {
+		r := &Record{
+			PipelineID: pipelineID,
+			Stage:      stage,
+			Error:      "parser error: invalid JSON",
+			Entry:      &models.LogEntry{Message: fmt.Sprintf("line %d", i), Source: "app.log"},
+		}
+		if err := s.Add(r); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestStoreAddAndList(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	jsonRecords := addRecords(t, s, 3, "json-logs", "parser:json")
+	addRecords(t, s, 2, "nginx-logs", "processor[1]:geoip")
+
+	if jsonRecords[0].ID == "" || jsonRecords[0].FailedAt.IsZero() {
+		t.Error("Expected ID and failure time to be assigned")
+	}
+
+	got, ok := s.Get(jsonRecords[1].ID)
+	if !ok || got.Entry.Message != "line 1" {
+		t.Errorf("Expected to get record by ID, got %+v", got)
+	}
+
+	records, total := s.List(&Filter{PipelineID: "json-logs"}, 1, 1)
+	if total != 3 || len(records) != 1 || records[0].ID != jsonRecords[1].ID {
+		t.Errorf("Unexpected page: total %d, %d records", total, len(records))
+	}
+
+	if _, total := s.List(&Filter{Stage: "processor"}, 0, 0); total != 2 {
+		t.Errorf("Expected 2 processor records, got %d", total)
+	}
+
+	if _, total := s.List(&Filter{Before: jsonRecords[0].FailedAt}, 0, 0); total != 0 {
+		t.Errorf("Expected no records before the first, got %d", total)
+	}
+
+	if _, total := s.List(nil, 0, 0); total != 5 {
+		t.Errorf("Expected 5 records, got %d", total)
+	}
+}
+
+func TestStoreDeleteAndPurge(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	jsonRecords := addRecords(t, s, 3, "json-logs", "parser:json")
+	addRecords(t, s, 2, "nginx-logs", "parser:nginx")
+
+	deleted, err := s.Delete(jsonRecords[0].ID, "missing")
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 record deleted, got %d (%v)", deleted, err)
+	}
+	if _, ok := s.Get(jsonRecords[0].ID); ok {
+		t.Error("Expected deleted record to be gone")
+	}
+
+	purged, err := s.Purge(&Filter{PipelineID: "nginx-logs"})
+	if err != nil || purged != 2 {
+		t.Errorf("Expected 2 records purged, got %d (%v)", purged, err)
+	}
+	if s.Len() != 2 {
+		t.Errorf("Expected 2 records left, got %d", s.Len())
+	}
+}
+
+func TestStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	records := addRecords(t, s, 3, "json-logs", "parser:json")
+	s.Delete(records[1].ID)
+	addRecords(t, s, 1, "json-logs", "parser:json")
+	s.Close()
+
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer s.Close()
+
+	if s.Len() != 3 {
+		t.Fatalf("Expected 3 records after reopen, got %d", s.Len())
+	}
+	if _, ok := s.Get(records[1].ID); ok {
+		t.Error("Expected deleted record to stay deleted")
+	}
+	got, ok := s.Get(records[2].ID)
+	if !ok || got.Entry.Message != "line 2" || got.FailedAt.Before(time.Now().Add(-time.Minute)) {
+		t.Errorf("Unexpected record after reopen: %+v", got)
+	}
+}
+
+func TestStoreMaxRecords(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 3)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	records := addRecords(t, s, 10, "json-logs", "parser:json")
+	if s.Len() != 3 {
+		t.Errorf("Expected 3 records, got %d", s.Len())
+	}
+	if _, ok := s.Get(records[6].ID); ok {
+		t.Error("Expected oldest records to be evicted")
+	}
+	s.Close()
+
+	s, err = Open(dir, 3)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer s.Close()
+
+	list, _ := s.List(nil, 0, 0)
+	if len(list) != 3 || list[0].ID != records[7].ID {
+		t.Errorf("Expected the newest 3 records after reopen, got %d", len(list))
+	}
+}
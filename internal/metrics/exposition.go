@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes every metric in the Prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range r.metrics() {
+		v.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+// writeText writes the metric's series sorted by label values
+func (v *Vec) writeText(w *bufio.Writer) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	name := v.desc.Name
+	if v.desc.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(v.desc.Help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, v.desc.Type)
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := v.series[key]
+		if v.desc.Type != TypeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(v.desc.Labels, s.labels, ""), formatValue(loadFloat(&s.value)))
+			continue
+		}
+
+		// The count is summed from the buckets so that it matches them
+		// while observations are recorded
+		var cumulative uint64
+		for i, bound := range v.desc.Buckets {
+			cumulative += atomic.LoadUint64(&s.counts[i])
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(v.desc.Labels, s.labels, formatValue(bound)), cumulative)
+		}
+		cumulative += atomic.LoadUint64(&s.counts[len(v.desc.Buckets)])
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(v.desc.Labels, s.labels, "+Inf"), cumulative)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(v.desc.Labels, s.labels, ""), formatValue(loadFloat(&s.sum)))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(v.desc.Labels, s.labels, ""), cumulative)
+	}
+}
+
+// formatLabels formats a label set, adding an le label when given
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabelValue(values[i]))
+	}
+	if le != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "le=\"%s\"", le)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatValue formats a sample value
+func formatValue(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp escapes a help string
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// escapeLabelValue escapes a label value
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// Handler returns an HTTP handler serving the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		if err := r.WriteText(w); err != nil {
+			fmt.Printf("metrics write error: %v\n", err)
+		}
+	})
+}
+
+// Server serves a registry over HTTP
+type Server struct {
+	addr     string
+	path     string
+	registry *Registry
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a metrics server. The path defaults to /metrics.
+func NewServer(addr, path string, registry *Registry) *Server {
+	if path == "" {
+		path = "/metrics"
+	}
+	if registry == nil {
+		registry = Default
+	}
+	return &Server{addr: addr, path: path, registry: registry}
+}
+
+// Start starts serving metrics
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(s.path, s.registry.Handler())
+
+	s.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("metrics server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the server
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+
+	s.wg.Wait()
+	return err
+}
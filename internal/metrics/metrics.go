@@ -0,0 +1,432 @@
+// Package metrics holds counters, gauges and histograms derived from logs
+// and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are the histogram buckets used when none are configured
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultMaxSeries is the series limit used when none is configured
+const DefaultMaxSeries = 1000
+
+// ErrSeriesLimit is returned when a new series would exceed a metric's
+// series limit
+var ErrSeriesLimit = errors.New("series limit reached")
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Desc describes a metric
+type Desc struct {
+	// Name is the metric name, e.g. http_requests_total
+	Name string
+
+	// Help is the description shown in the exposition
+	Help string
+
+	// Type is counter, gauge or histogram
+	Type string
+
+	// Labels are the label names, in order
+	Labels []string
+
+	// Buckets are the histogram upper bounds
+	Buckets []float64
+
+	// MaxSeries limits the number of label value combinations
+	MaxSeries int
+}
+
+// validate checks the description and fills in defaults
+func (d *Desc) validate() error {
+	if !metricNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid metric name %q", d.Name)
+	}
+
+	switch d.Type {
+	case TypeCounter, TypeGauge:
+	case TypeHistogram:
+		if len(d.Buckets) == 0 {
+			d.Buckets = DefaultBuckets
+		}
+		if !sort.Float64sAreSorted(d.Buckets) {
+			return fmt.Errorf("buckets of %s must be sorted", d.Name)
+		}
+	default:
+		return fmt.Errorf("invalid type for metric %s: %q", d.Name, d.Type)
+	}
+
+	seen := make(map[string]bool, len(d.Labels))
+	for _, label := range d.Labels {
+		if !labelNamePattern.MatchString(label) || label == "le" || strings.HasPrefix(label, "__") {
+			return fmt.Errorf("invalid label name %q for metric %s", label, d.Name)
+		}
+		if seen[label] {
+			return fmt.Errorf("duplicate label %q for metric %s", label, d.Name)
+		}
+		seen[label] = true
+	}
+
+	if d.MaxSeries <= 0 {
+		d.MaxSeries = DefaultMaxSeries
+	}
+	return nil
+}
+
+// compatible reports whether two descriptions define the same series
+func (d *Desc) compatible(other *Desc) bool {
+	if d.Type != other.Type || len(d.Labels) != len(other.Labels) || len(d.Buckets) != len(other.Buckets) {
+		return false
+	}
+	for i := range d.Labels {
+		if d.Labels[i] != other.Labels[i] {
+			return false
+		}
+	}
+	for i := range d.Buckets {
+		if d.Buckets[i] != other.Buckets[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// series holds the value of one label value combination. Values are
+// updated atomically, so that a series can be updated through a handle
+// without the metric's lock.
+type series struct {
+	labels []string
+
+	// value holds the bits of the counter or gauge value
+	value uint64
+
+	// counts holds histogram observations per bucket, not cumulative;
+	// the last element counts observations above every bucket
+	counts []uint64
+	sum    uint64
+}
+
+// addFloat atomically adds to a float64 stored as bits
+func addFloat(addr *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(addr, old, next) {
+			return
+		}
+	}
+}
+
+// loadFloat atomically loads a float64 stored as bits
+func loadFloat(addr *uint64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(addr))
+}
+
+// Vec is a metric with one series per combination of label values
+type Vec struct {
+	desc     Desc
+	mu       sync.RWMutex
+	series   map[string]*series
+	rejected uint64
+}
+
+// newVec creates an empty metric
+func newVec(desc Desc) *Vec {
+	return &Vec{
+		desc:   desc,
+		series: make(map[string]*series),
+	}
+}
+
+// Desc returns the metric description
+func (v *Vec) Desc() Desc {
+	return v.desc
+}
+
+// Add adds to a counter or gauge. Counters cannot decrease.
+func (v *Vec) Add(value float64, labelValues ...string) error {
+	s, err := v.lookup(labelValues)
+	if err != nil {
+		return err
+	}
+	return v.add(s, value)
+}
+
+// Set sets a gauge
+func (v *Vec) Set(value float64, labelValues ...string) error {
+	s, err := v.lookup(labelValues)
+	if err != nil {
+		return err
+	}
+	return v.set(s, value)
+}
+
+// Observe records a histogram observation
+func (v *Vec) Observe(value float64, labelValues ...string) error {
+	s, err := v.lookup(labelValues)
+	if err != nil {
+		return err
+	}
+	return v.observe(s, value)
+}
+
+// add adds to the value of a counter or gauge series
+func (v *Vec) add(s *series, value float64) error {
+	if v.desc.Type == TypeHistogram {
+		return fmt.Errorf("cannot add to histogram %s", v.desc.Name)
+	}
+	if v.desc.Type == TypeCounter && value < 0 {
+		return fmt.Errorf("counter %s cannot decrease", v.desc.Name)
+	}
+	addFloat(&s.value, value)
+	return nil
+}
+
+// set sets the value of a gauge series
+func (v *Vec) set(s *series, value float64) error {
+	if v.desc.Type != TypeGauge {
+		return fmt.Errorf("cannot set %s %s", v.desc.Type, v.desc.Name)
+	}
+	atomic.StoreUint64(&s.value, math.Float64bits(value))
+	return nil
+}
+
+// observe records an observation in a histogram series
+func (v *Vec) observe(s *series, value float64) error {
+	if v.desc.Type != TypeHistogram {
+		return fmt.Errorf("cannot observe %s %s", v.desc.Type, v.desc.Name)
+	}
+	if math.IsNaN(value) {
+		return fmt.Errorf("cannot observe NaN in %s", v.desc.Name)
+	}
+	i := sort.SearchFloat64s(v.desc.Buckets, value)
+	atomic.AddUint64(&s.counts[i], 1)
+	addFloat(&s.sum, value)
+	return nil
+}
+
+// Series is a handle on one series of a metric. Updating a series through
+// its handle skips looking it up by label values. A nil Series ignores
+// updates, so that callers can keep the one returned with an error.
+type Series struct {
+	vec    *Vec
+	series *series
+}
+
+// With returns the series for a set of label values, creating it while
+// the metric is under its series limit. A series removed by Delete stops
+// being exported; its handle must not be used to export it again.
+func (v *Vec) With(labelValues ...string) (*Series, error) {
+	s, err := v.lookup(labelValues)
+	if err != nil {
+		return nil, err
+	}
+	return &Series{vec: v, series: s}, nil
+}
+
+// Add adds to a counter or gauge series. Counters cannot decrease.
+func (s *Series) Add(value float64) error {
+	if s == nil {
+		return nil
+	}
+	return s.vec.add(s.series, value)
+}
+
+// Set sets a gauge series
+func (s *Series) Set(value float64) error {
+	if s == nil {
+		return nil
+	}
+	return s.vec.set(s.series, value)
+}
+
+// Observe records an observation in a histogram series
+func (s *Series) Observe(value float64) error {
+	if s == nil {
+		return nil
+	}
+	return s.vec.observe(s.series, value)
+}
+
+// lookup returns the series for a set of label values, taking the write
+// lock only to create it
+func (v *Vec) lookup(labelValues []string) (*series, error) {
+	if len(labelValues) != len(v.desc.Labels) {
+		return nil, fmt.Errorf("metric %s expects %d label values, got %d", v.desc.Name, len(v.desc.Labels), len(labelValues))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.get(key, labelValues)
+}
+
+// get returns the series for a set of label values and their key,
+// creating it while the metric is under its series limit. The caller
+// holds the lock.
+func (v *Vec) get(key string, labelValues []string) (*series, error) {
+	if s, ok := v.series[key]; ok {
+		return s, nil
+	}
+
+	if len(v.series) >= v.desc.MaxSeries {
+		atomic.AddUint64(&v.rejected, 1)
+		return nil, fmt.Errorf("metric %s: %w (%d)", v.desc.Name, ErrSeriesLimit, v.desc.MaxSeries)
+	}
+
+	s := &series{labels: append([]string(nil), labelValues...)}
+	if v.desc.Type == TypeHistogram {
+		s.counts = make([]uint64, len(v.desc.Buckets)+1)
+	}
+	v.series[key] = s
+	return s, nil
+}
+
+// Delete removes the series whose label has the given value and returns
+// the number removed
+func (v *Vec) Delete(label, value string) int {
+	index := -1
+	for i, name := range v.desc.Labels {
+		if name == label {
+			index = i
+		}
+	}
+	if index < 0 {
+		return 0
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	removed := 0
+	for key, s := range v.series {
+		if s.labels[index] == value {
+			delete(v.series, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// DeleteFunc removes the series whose label values match and returns the
+// number removed. Label values are given in the order of the labels.
+func (v *Vec) DeleteFunc(match func(labelValues []string) bool) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	removed := 0
+	for key, s := range v.series {
+		if match(s.labels) {
+			delete(v.series, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of series
+func (v *Vec) Len() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return len(v.series)
+}
+
+// Rejected returns the number of updates refused by the series limit
+func (v *Vec) Rejected() uint64 {
+	return atomic.LoadUint64(&v.rejected)
+}
+
+// Registry holds a set of metrics by name
+type Registry struct {
+	mu   sync.RWMutex
+	vecs map[string]*Vec
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{vecs: make(map[string]*Vec)}
+}
+
+// Default is the registry served by the server's metrics endpoint
+var Default = NewRegistry()
+
+// Register adds a metric. Registering a metric that already exists with
+// the same type, labels and buckets returns the existing metric, so that
+// several processors, or a processor recreated on reload, can share it.
+func (r *Registry) Register(desc Desc) (*Vec, error) {
+	desc.Labels = append([]string(nil), desc.Labels...)
+	desc.Buckets = append([]float64(nil), desc.Buckets...)
+	if err := desc.validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.vecs[desc.Name]; ok {
+		if !existing.desc.compatible(&desc) {
+			return nil, fmt.Errorf("metric %s is already registered with a different definition", desc.Name)
+		}
+		return existing, nil
+	}
+
+	v := newVec(desc)
+	r.vecs[desc.Name] = v
+	return v, nil
+}
+
+// Unregister removes a metric and its series
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.vecs, name)
+}
+
+// Get returns a registered metric
+func (r *Registry) Get(name string) (*Vec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.vecs[name]
+	return v, ok
+}
+
+// metrics returns the registered metrics sorted by name
+func (r *Registry) metrics() []*Vec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vecs := make([]*Vec, 0, len(r.vecs))
+	for _, v := range r.vecs {
+		vecs = append(vecs, v)
+	}
+	sort.Slice(vecs, func(i, j int) bool {
+		return vecs[i].desc.Name < vecs[j].desc.Name
+	})
+	return vecs
+}
@@ -0,0 +1,226 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCounterExposition(t *testing.T) {
+	r := NewRegistry()
+	v, err := r.Register(Desc{
+		Name:   "http_requests_total",
+		Help:   "HTTP requests from access logs",
+		Type:   TypeCounter,
+		Labels: []string{"status", "method"},
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	v.Add(1, "200", "GET")
+	v.Add(2, "200", "GET")
+	v.Add(1, "500", "POST")
+	if err := v.Add(-1, "200", "GET"); err == nil {
+		t.Error("Expected error decreasing a counter")
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	expected := `# HELP http_requests_total HTTP requests from access logs
+# TYPE http_requests_total counter
+http_requests_total{status="200",method="GET"} 3
+http_requests_total{status="500",method="POST"} 1
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s", buf.String())
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{
+		Name:    "request_seconds",
+		Type:    TypeHistogram,
+		Buckets: []float64{0.1, 1},
+	})
+
+	v.Observe(0.05)
+	v.Observe(0.1)
+	v.Observe(0.5)
+	v.Observe(3)
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+
+	expected := `# TYPE request_seconds histogram
+request_seconds_bucket{le="0.1"} 2
+request_seconds_bucket{le="1"} 3
+request_seconds_bucket{le="+Inf"} 4
+request_seconds_sum 3.65
+request_seconds_count 4
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s", buf.String())
+	}
+}
+
+func TestGaugeAndEscaping(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{Name: "queue_depth", Type: TypeGauge, Labels: []string{"queue"}})
+
+	v.Set(5, `a"b\c`)
+	v.Set(3, `a"b\c`)
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	if !strings.Contains(buf.String(), `queue_depth{queue="a\"b\\c"} 3`) {
+		t.Errorf("Unexpected exposition:\n%s", buf.String())
+	}
+
+	if err := v.Observe(1, "x"); err == nil {
+		t.Error("Expected error observing a gauge")
+	}
+}
+
+func TestSeriesLimit(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{Name: "by_user_total", Type: TypeCounter, Labels: []string{"user"}, MaxSeries: 2})
+
+	v.Add(1, "a")
+	v.Add(1, "b")
+	if err := v.Add(1, "c"); !errors.Is(err, ErrSeriesLimit) {
+		t.Errorf("Expected ErrSeriesLimit, got %v", err)
+	}
+	if err := v.Add(1, "a"); err != nil {
+		t.Errorf("Expected existing series to be updated, got %v", err)
+	}
+
+	if v.Len() != 2 || v.Rejected() != 1 {
+		t.Errorf("Expected 2 series and 1 rejection, got %d and %d", v.Len(), v.Rejected())
+	}
+}
+
+func TestDelete(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{Name: "stage_total", Type: TypeCounter, Labels: []string{"pipeline", "stage"}})
+
+	v.Add(1, "nginx", "parser:nginx")
+	v.Add(1, "nginx", "processor[0]:geoip")
+	v.Add(1, "json", "parser:json")
+
+	if n := v.Delete("pipeline", "nginx"); n != 2 {
+		t.Errorf("Expected 2 series removed, got %d", n)
+	}
+	if n := v.Delete("unknown", "nginx"); n != 0 {
+		t.Errorf("Expected nothing removed for an unknown label, got %d", n)
+	}
+	if v.Len() != 1 {
+		t.Errorf("Expected 1 series left, got %d", v.Len())
+	}
+}
+
+func TestSeriesHandle(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{Name: "stage_seconds", Type: TypeHistogram, Labels: []string{"stage"}, Buckets: []float64{1}})
+
+	s, err := v.With("parser")
+	if err != nil {
+		t.Fatalf("With failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				s.Observe(0.5)
+				v.Observe(0.5, "parser")
+			}
+		}()
+	}
+	wg.Wait()
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	if !strings.Contains(buf.String(), `stage_seconds_count{stage="parser"} 16000`) ||
+		!strings.Contains(buf.String(), `stage_seconds_sum{stage="parser"} 8000`) {
+		t.Errorf("Expected updates through the handle and the metric to add up:\n%s", buf.String())
+	}
+
+	if err := s.Add(1); err == nil {
+		t.Error("Expected error adding to a histogram series")
+	}
+
+	// A nil series from the series limit ignores updates
+	var limited *Series
+	if err := limited.Observe(1); err != nil {
+		t.Errorf("Expected nil series to ignore updates, got %v", err)
+	}
+}
+
+func TestDeleteFunc(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{Name: "stage_total", Type: TypeCounter, Labels: []string{"pipeline", "stage"}})
+
+	v.Add(1, "nginx", "parser:nginx")
+	v.Add(1, "nginx", "processor[0]:geoip")
+	v.Add(1, "json", "processor[0]:geoip")
+
+	n := v.DeleteFunc(func(labelValues []string) bool {
+		return labelValues[0] == "nginx" && labelValues[1] != "parser:nginx"
+	})
+	if n != 1 || v.Len() != 2 {
+		t.Errorf("Expected 1 series removed and 2 left, got %d and %d", n, v.Len())
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	first, _ := r.Register(Desc{Name: "events_total", Type: TypeCounter, Labels: []string{"source"}})
+
+	second, err := r.Register(Desc{Name: "events_total", Type: TypeCounter, Labels: []string{"source"}})
+	if err != nil || second != first {
+		t.Errorf("Expected identical definition to share the metric, got %v", err)
+	}
+
+	if _, err := r.Register(Desc{Name: "events_total", Type: TypeGauge, Labels: []string{"source"}}); err == nil {
+		t.Error("Expected error for conflicting definition")
+	}
+
+	invalid := []Desc{
+		{Name: "bad-name", Type: TypeCounter},
+		{Name: "ok", Type: "summary"},
+		{Name: "ok", Type: TypeCounter, Labels: []string{"le"}},
+		{Name: "ok", Type: TypeCounter, Labels: []string{"a", "a"}},
+		{Name: "ok", Type: TypeHistogram, Buckets: []float64{1, 0.5}},
+	}
+	for _, desc := range invalid {
+		if _, err := r.Register(desc); err == nil {
+			t.Errorf("Expected error for %+v", desc)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	v, _ := r.Register(Desc{Name: "up", Type: TypeGauge})
+	v.Set(1)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if rec.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "up 1\n") {
+		t.Errorf("Unexpected body:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/processor"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"github.com/UmangDiyora/logpipeline/pkg/parser"
+)
+
+// Defaults for pipelines built from the server configuration
+const (
+	DefaultWorkers   = 4
+	DefaultQueueSize = 1000
+)
+
+// NewConfig converts a pipeline definition from the server configuration
+// into a pipeline configuration
+func NewConfig(def *config.PipelineConfig) (*Config, error) {
+	if def == nil {
+		return nil, fmt.Errorf("pipeline definition is required")
+	}
+	if def.Name == "" {
+		return nil, fmt.Errorf("pipeline name is required")
+	}
+	if def.Workers < 0 {
+		return nil, fmt.Errorf("pipeline %s: workers must not be negative", def.Name)
+	}
+	if def.QueueSize < 0 {
+		return nil, fmt.Errorf("pipeline %s: queue_size must not be negative", def.Name)
+	}
+
+	cfg := &Config{
+		ID:          def.Name,
+		Name:        def.Name,
+		Workers:     def.Workers,
+		Processors:  make([]processor.Config, 0, len(def.Processors)),
+		Overflow:    def.Overflow,
+		SpillDir:    def.SpillDir,
+		PartitionBy: def.PartitionBy,
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = DefaultWorkers
+	}
+
+	if def.Parser != "" {
+		cfg.Parser = parserConfig(def.Parser, def.ParserOptions)
+	} else if def.ParserOptions != nil {
+		return nil, fmt.Errorf("pipeline %s: parser_options require a parser", def.Name)
+	}
+
+	for _, proc := range def.Processors {
+		if proc.Type == "" {
+			return nil, fmt.Errorf("pipeline %s: processor type is required", def.Name)
+		}
+		fields := proc.Config
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		cfg.Processors = append(cfg.Processors, processor.Config{
+			Type:      proc.Type,
+			Fields:    fields,
+			Condition: proc.Condition,
+		})
+	}
+
+	return cfg, nil
+}
+
+// parserConfig converts parser options into a parser configuration
+func parserConfig(parserType string, options *config.ParserOptions) *parser.Config {
+	cfg := &parser.Config{Type: parserType}
+	if options == nil {
+		return cfg
+	}
+
+	cfg.Fields = options.Fields
+	cfg.Patterns = options.Patterns
+	cfg.TimeFormat = options.TimeFormat
+	cfg.TimeFormats = options.TimeFormats
+	cfg.Timezone = options.Timezone
+	cfg.TimeField = options.TimeField
+	cfg.Flatten = options.Flatten
+	cfg.MaxDepth = options.MaxDepth
+	cfg.Separator = options.Separator
+	cfg.ArrayMode = options.ArrayMode
+	cfg.KeepJSON = options.KeepJSON
+	cfg.AppPackages = options.AppPackages
+	if options.Inner != nil {
+		cfg.Inner = parserConfig(options.Inner.Type, &options.Inner.ParserOptions)
+	}
+	return cfg
+}
+
+// Build creates and starts a pipeline from a definition in the server
+// configuration. It returns the pipeline and the queue that feeds it.
+// Failed entries go to deadLetter when it is not nil. Unknown parser or
+// processor types and invalid options are errors.
+func Build(def *config.PipelineConfig, output chan *models.LogEntry, deadLetter *dlq.Store) (*Pipeline, chan *models.LogEntry, error) {
+	cfg, err := NewConfig(def)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.DeadLetter = deadLetter
+
+	input := newInput(def)
+	p, err := New(cfg, input, output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pipeline %s: %w", def.Name, err)
+	}
+	return p, input, nil
+}
+
+// newInput creates the queue that feeds a pipeline
+func newInput(def *config.PipelineConfig) chan *models.LogEntry {
+	queueSize := def.QueueSize
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+	return make(chan *models.LogEntry, queueSize)
+}
+
+// Validate checks a pipeline definition by creating its parser and
+// processors without starting anything
+func Validate(def *config.PipelineConfig) error {
+	if def != nil {
+		if _, err := overflowPolicy(def.Overflow, def.SpillDir); err != nil {
+			return fmt.Errorf("pipeline %s: %w", def.Name, err)
+		}
+		partitionBy, err := partitionFor(def.PartitionBy, def.Parser)
+		if err != nil {
+			return fmt.Errorf("pipeline %s: %w", def.Name, err)
+		}
+		if _, err := partitionKey(partitionBy); err != nil {
+			return fmt.Errorf("pipeline %s: %w", def.Name, err)
+		}
+	}
+
+	s, err := NewSimulator(def)
+	if err != nil {
+		return err
+	}
+	return s.Close()
+}
@@ -0,0 +1,471 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/router"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// DefaultDrainTimeout bounds how long a replaced pipeline may take to
+// process the entries left in its input
+const DefaultDrainTimeout = 30 * time.Second
+
+// instance is a running pipeline with its definition and input
+type instance struct {
+	def      config.PipelineConfig
+	pipeline *Pipeline
+	input    chan *models.LogEntry
+
+	// retired is closed when the instance is taken out of the manager;
+	// senders counts the replays sending to input, which is closed once
+	// they left
+	retired chan struct{}
+	senders sync.WaitGroup
+}
+
+// newInstance creates an instance with the input of a definition
+func newInstance(def config.PipelineConfig) *instance {
+	return &instance{def: def, input: newInput(&def), retired: make(chan struct{})}
+}
+
+// closeInput closes the input once no replay sends to it. The instance
+// must already be out of the manager, so that no replay starts sending.
+func (inst *instance) closeInput() {
+	close(inst.retired)
+	inst.senders.Wait()
+	close(inst.input)
+}
+
+// send sends an entry to the input. It returns false without an error
+// when the instance was retired first.
+func (inst *instance) send(ctx context.Context, entry *models.LogEntry) (bool, error) {
+	defer inst.senders.Done()
+
+	select {
+	case inst.input <- entry:
+		return true, nil
+	case <-inst.retired:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// unchanged reports whether the instance runs the given definition
+func (inst *instance) unchanged(def *config.PipelineConfig) bool {
+	return inst.pipeline != nil && reflect.DeepEqual(inst.def, *def)
+}
+
+// ReloadSummary reports what applying a configuration changed
+type ReloadSummary struct {
+	// Started lists new pipelines
+	Started []string `json:"started"`
+
+	// Restarted lists pipelines whose definition changed
+	Restarted []string `json:"restarted"`
+
+	// Stopped lists pipelines that were removed
+	Stopped []string `json:"stopped"`
+
+	// Unchanged lists pipelines that kept running as they were
+	Unchanged []string `json:"unchanged"`
+}
+
+// Manager runs the server pipelines and the router that feeds them, and
+// replaces them when the configuration changes without losing entries
+type Manager struct {
+	input      <-chan *models.LogEntry
+	output     chan *models.LogEntry
+	deadLetter *dlq.Store
+
+	// DrainTimeout bounds how long replaced pipelines may take to drain
+	DrainTimeout time.Duration
+
+	// applyMu serializes configuration changes
+	applyMu sync.Mutex
+
+	// mu guards the running instances. Replay holds it for reading while
+	// it sends, so that inputs are not closed under it.
+	mu        sync.RWMutex
+	instances map[string]*instance
+	order     []string
+	router    *router.Router
+}
+
+// NewManager creates a manager routing entries from input to pipelines
+// that write to output. Failed entries go to deadLetter when it is not nil.
+func NewManager(input <-chan *models.LogEntry, output chan *models.LogEntry, deadLetter *dlq.Store) *Manager {
+	return &Manager{
+		input:        input,
+		output:       output,
+		deadLetter:   deadLetter,
+		DrainTimeout: DefaultDrainTimeout,
+		instances:    make(map[string]*instance),
+	}
+}
+
+// Apply runs the given pipelines and routing. The whole configuration is
+// validated first; when it is invalid nothing changes. Pipelines whose
+// definition is unchanged keep running. New and changed pipelines are
+// started, the router switches to them at once, and the pipelines they
+// replace process the entries left in their inputs before stopping.
+//
+// A replacement that spills to the directory of the pipeline it replaces
+// opens it once that pipeline stopped. If that fails, the pipeline is
+// removed from routing, the entries waiting for it are routed again, and
+// Apply returns an error without a summary.
+func (m *Manager) Apply(defs []config.PipelineConfig, routing config.RoutingConfig) (*ReloadSummary, error) {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	// Validate everything before touching the running pipelines
+	byName := make(map[string]*config.PipelineConfig, len(defs))
+	spillDirs := make(map[string]string)
+	routes := make([]router.Route, 0, len(defs))
+	for i := range defs {
+		def := &defs[i]
+		if err := Validate(def); err != nil {
+			return nil, err
+		}
+		if _, exists := byName[def.Name]; exists {
+			return nil, fmt.Errorf("duplicate pipeline %q", def.Name)
+		}
+		byName[def.Name] = def
+
+		// Two spill queues in one directory would read each other's entries
+		if def.Overflow == OverflowSpill {
+			dir := filepath.Clean(def.SpillDir)
+			if other, exists := spillDirs[dir]; exists {
+				return nil, fmt.Errorf("pipelines %s and %s use the same spill directory %s", other, def.Name, dir)
+			}
+			spillDirs[dir] = def.Name
+		}
+		routes = append(routes, router.Route{Name: def.Name, Filter: def.Filter})
+	}
+
+	routerConfig := &router.Config{
+		Mode:    routing.Mode,
+		Default: routing.Default,
+	}
+	if err := router.Validate(routerConfig, routes); err != nil {
+		return nil, err
+	}
+
+	summary := &ReloadSummary{
+		Started:   make([]string, 0),
+		Restarted: make([]string, 0),
+		Stopped:   make([]string, 0),
+		Unchanged: make([]string, 0),
+	}
+
+	// Find the pipelines to replace. A replacement using the same spill
+	// directory can only open it once the old pipeline has released it.
+	var retired []*instance
+	retiredSpill := make(map[string]bool)
+	for _, name := range m.order {
+		current := m.instances[name]
+		if def, ok := byName[name]; ok && current.unchanged(def) {
+			continue
+		}
+		retired = append(retired, current)
+		if current.def.Overflow == OverflowSpill {
+			retiredSpill[current.def.SpillDir] = true
+		}
+		if _, ok := byName[name]; !ok {
+			summary.Stopped = append(summary.Stopped, name)
+		}
+	}
+
+	next := make(map[string]*instance, len(defs))
+	order := make([]string, 0, len(defs))
+	var started []*instance
+	deferred := make(map[*instance]*Pipeline)
+	discard := func() {
+		for _, s := range started {
+			s.pipeline.Stop()
+			m.discardMetrics(s.pipeline)
+		}
+		for _, p := range deferred {
+			p.discard()
+			m.discardMetrics(p)
+		}
+	}
+	for i := range defs {
+		def := defs[i]
+		order = append(order, def.Name)
+
+		current, exists := m.instances[def.Name]
+		if exists && current.unchanged(&def) {
+			next[def.Name] = current
+			summary.Unchanged = append(summary.Unchanged, def.Name)
+			continue
+		}
+
+		inst := newInstance(def)
+		next[def.Name] = inst
+		if exists {
+			summary.Restarted = append(summary.Restarted, def.Name)
+		} else {
+			summary.Started = append(summary.Started, def.Name)
+		}
+
+		// Everything but opening the spill directory happens now, so that
+		// only that can fail once the router switched
+		if def.Overflow == OverflowSpill && retiredSpill[def.SpillDir] {
+			p, err := m.build(inst)
+			if err != nil {
+				discard()
+				return nil, err
+			}
+			deferred[inst] = p
+			continue
+		}
+		if err := m.start(inst); err != nil {
+			discard()
+			return nil, err
+		}
+		started = append(started, inst)
+	}
+
+	for i := range routes {
+		routes[i].Output = next[routes[i].Name].input
+	}
+
+	// Switch the router to the new inputs
+	m.mu.Lock()
+	var err error
+	if m.router == nil {
+		var rt *router.Router
+		rt, err = router.New(routerConfig, m.input, routes)
+		if err == nil {
+			m.router = rt
+			rt.Start()
+		}
+	} else {
+		err = m.router.Update(routerConfig, routes)
+	}
+	if err != nil {
+		m.mu.Unlock()
+		discard()
+		return nil, err
+	}
+	m.instances = next
+	m.order = order
+	m.mu.Unlock()
+
+	// Nothing sends to the replaced inputs any more
+	m.drain(retired)
+
+	// Metrics of replaced pipelines continue in their replacements, except
+	// those of stages the replacement does not have
+	for _, inst := range retired {
+		replacement, kept := next[inst.def.Name]
+		switch {
+		case !kept && inst.pipeline != nil:
+			inst.pipeline.removeMetrics()
+		case kept && replacement.pipeline != nil:
+			replacement.pipeline.removeStaleMetrics()
+		case kept:
+			deferred[replacement].removeStaleMetrics()
+		}
+	}
+
+	var failed []*instance
+	var errs []error
+	for inst, p := range deferred {
+		if err := p.start(); err != nil {
+			p.removeMetrics()
+			failed = append(failed, inst)
+			errs = append(errs, fmt.Errorf("pipeline %s: %w", inst.def.Name, err))
+			continue
+		}
+		m.mu.Lock()
+		inst.pipeline = p
+		m.mu.Unlock()
+	}
+	if len(failed) > 0 {
+		m.remove(failed, routerConfig, routes)
+		return nil, errors.Join(errs...)
+	}
+
+	return summary, nil
+}
+
+// discardMetrics removes the series that a pipeline which never took
+// over created. Series of the running pipeline of the same name are kept.
+func (m *Manager) discardMetrics(p *Pipeline) {
+	if current, ok := m.instances[p.name]; ok && current.pipeline != nil {
+		current.pipeline.removeStaleMetrics()
+		return
+	}
+	p.removeMetrics()
+}
+
+// remove takes instances whose pipeline failed to start out of routing
+// and routes the entries waiting in their inputs again
+func (m *Manager) remove(failed []*instance, routerConfig *router.Config, routes []router.Route) {
+	removed := make(map[string]bool, len(failed))
+	for _, inst := range failed {
+		removed[inst.def.Name] = true
+	}
+
+	remaining := make([]router.Route, 0, len(routes))
+	for _, route := range routes {
+		if !removed[route.Name] {
+			remaining = append(remaining, route)
+		}
+	}
+	fallback := *routerConfig
+	if removed[fallback.Default] {
+		fallback.Default = ""
+	}
+
+	m.mu.Lock()
+	if err := m.router.Update(&fallback, remaining); err != nil {
+		fmt.Printf("Error removing failed pipelines from routing: %v\n", err)
+	}
+	order := make([]string, 0, len(m.order))
+	for _, name := range m.order {
+		if removed[name] {
+			delete(m.instances, name)
+			continue
+		}
+		order = append(order, name)
+	}
+	m.order = order
+	m.mu.Unlock()
+
+	for _, inst := range failed {
+		inst.closeInput()
+		for entry := range inst.input {
+			m.router.Route(entry)
+		}
+	}
+}
+
+// build creates the pipeline of an instance without starting it
+func (m *Manager) build(inst *instance) (*Pipeline, error) {
+	cfg, err := NewConfig(&inst.def)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DeadLetter = m.deadLetter
+
+	p, err := build(cfg, inst.input, m.output)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %s: %w", inst.def.Name, err)
+	}
+	return p, nil
+}
+
+// start creates and starts the pipeline of an instance
+func (m *Manager) start(inst *instance) error {
+	p, err := m.build(inst)
+	if err != nil {
+		return err
+	}
+	if err := p.start(); err != nil {
+		return fmt.Errorf("pipeline %s: %w", inst.def.Name, err)
+	}
+	inst.pipeline = p
+	return nil
+}
+
+// drain closes the inputs of replaced pipelines and waits for them to
+// process what is left, up to the drain timeout
+func (m *Manager) drain(retired []*instance) {
+	if len(retired) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.DrainTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, inst := range retired {
+		inst.closeInput()
+		if inst.pipeline == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(inst *instance) {
+			defer wg.Done()
+			if err := inst.pipeline.Drain(ctx); err != nil {
+				fmt.Printf("Error stopping pipeline %s: %v\n", inst.def.Name, err)
+			}
+			if left := len(inst.input); left > 0 {
+				fmt.Printf("Warning: pipeline %s stopped with %d entries unprocessed\n", inst.def.Name, left)
+			}
+		}(inst)
+	}
+	wg.Wait()
+}
+
+// Replay sends an entry to the input of a pipeline, waiting while the
+// input is full. The manager is not locked while it waits; when the
+// pipeline is replaced meanwhile, the entry goes to the replacement.
+func (m *Manager) Replay(ctx context.Context, name string, entry *models.LogEntry) error {
+	for {
+		m.mu.RLock()
+		inst, ok := m.instances[name]
+		if ok {
+			inst.senders.Add(1)
+		}
+		m.mu.RUnlock()
+
+		if !ok {
+			return fmt.Errorf("pipeline %q not found", name)
+		}
+		sent, err := inst.send(ctx, entry)
+		if sent || err != nil {
+			return err
+		}
+	}
+}
+
+// Pipeline returns a running pipeline by name
+func (m *Manager) Pipeline(name string) (*Pipeline, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	inst, ok := m.instances[name]
+	if !ok || inst.pipeline == nil {
+		return nil, false
+	}
+	return inst.pipeline, true
+}
+
+// Stop routes the entries already received and then drains the
+// pipelines, each up to the drain timeout. Whatever feeds the manager's
+// input must have stopped.
+func (m *Manager) Stop() {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	if m.router != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), m.DrainTimeout)
+		m.router.Drain(ctx)
+		cancel()
+	}
+
+	m.mu.Lock()
+	stopped := make([]*instance, 0, len(m.order))
+	for _, name := range m.order {
+		stopped = append(stopped, m.instances[name])
+	}
+	m.instances = make(map[string]*instance)
+	m.order = nil
+	m.mu.Unlock()
+
+	m.drain(stopped)
+}
@@ -0,0 +1,327 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// startManager creates a manager and stops it when the test ends
+func startManager(t *testing.T, input chan *models.LogEntry, output chan *models.LogEntry) *Manager {
+	t.Helper()
+
+	m := NewManager(input, output, nil)
+	t.Cleanup(m.Stop)
+	return m
+}
+
+func TestApplyRejectsSharedSpillDir(t *testing.T) {
+	dir := t.TempDir()
+	m := startManager(t, make(chan *models.LogEntry), make(chan *models.LogEntry, 10))
+
+	defs := []config.PipelineConfig{
+		{Name: "a", Overflow: OverflowSpill, SpillDir: filepath.Join(dir, "spill")},
+		{Name: "b", Filter: "source:b", Overflow: OverflowSpill, SpillDir: filepath.Join(dir, "spill") + "/"},
+	}
+	if _, err := m.Apply(defs, config.RoutingConfig{}); err == nil {
+		t.Fatal("Expected error for pipelines sharing a spill directory")
+	}
+	if _, ok := m.Pipeline("a"); ok {
+		t.Error("Expected nothing to start for an invalid configuration")
+	}
+
+	defs[1].SpillDir = filepath.Join(dir, "spill-b")
+	if _, err := m.Apply(defs, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+}
+
+// tagged returns a pipeline definition that marks its entries with a
+// version field
+func tagged(name, filter, version string) config.PipelineConfig {
+	return config.PipelineConfig{
+		Name:      name,
+		Filter:    filter,
+		Workers:   2,
+		QueueSize: 10,
+		Processors: []config.ProcessorConfig{
+			{Type: "add_fields", Config: map[string]interface{}{"version": version}},
+		},
+	}
+}
+
+func TestApplyReloadLosesNothing(t *testing.T) {
+	input := make(chan *models.LogEntry)
+	output := make(chan *models.LogEntry)
+	m := startManager(t, input, output)
+
+	if _, err := m.Apply([]config.PipelineConfig{tagged("a", "", "v0")}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	const total = 3000
+	go func() {
+		for i := 0; i < total; i++ {
+			entry := models.NewLogEntry()
+			entry.ID = strconv.Itoa(i)
+			input <- entry
+		}
+	}()
+
+	// A slow consumer keeps the pipeline inputs full, so the router waits
+	// on them while the configuration changes
+	received := make(chan map[string]int)
+	go func() {
+		seen := make(map[string]int, total)
+		for n := 0; n < total; n++ {
+			entry := <-output
+			seen[entry.ID]++
+			if n%10 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		received <- seen
+	}()
+
+	for i := 1; i <= 5; i++ {
+		done := make(chan error)
+		go func() {
+			_, err := m.Apply([]config.PipelineConfig{tagged("a", "", fmt.Sprintf("v%d", i))}, config.RoutingConfig{})
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Apply %d failed: %v", i, err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Apply %d blocked", i)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case seen := <-received:
+		for i := 0; i < total; i++ {
+			if n := seen[strconv.Itoa(i)]; n != 1 {
+				t.Fatalf("Expected entry %d once, got it %d times", i, n)
+			}
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Timed out waiting for entries")
+	}
+}
+
+func TestApplyRollsBackFailedSpillStart(t *testing.T) {
+	input := make(chan *models.LogEntry)
+	output := make(chan *models.LogEntry, 10)
+	m := startManager(t, input, output)
+
+	dir := t.TempDir()
+	spilling := tagged("a", "source:a", "a1")
+	spilling.Overflow = OverflowSpill
+	spilling.SpillDir = dir
+	defs := []config.PipelineConfig{spilling, tagged("b", "", "b1")}
+	if _, err := m.Apply(defs, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// The replacement of a can only open the spill directory after a
+	// stopped, and then fails to read it
+	if err := os.Mkdir(filepath.Join(dir, "spill-99999999.jsonl"), 0755); err != nil {
+		t.Fatalf("Failed to break spill directory: %v", err)
+	}
+	defs[0] = tagged("a", "source:a", "a2")
+	defs[0].Overflow = OverflowSpill
+	defs[0].SpillDir = dir
+
+	summary, err := m.Apply(defs, config.RoutingConfig{})
+	if err == nil || summary != nil {
+		t.Fatalf("Expected error without summary, got %v %v", summary, err)
+	}
+	if _, ok := m.Pipeline("a"); ok {
+		t.Error("Expected the failed pipeline to be removed")
+	}
+
+	// Its entries go to the default pipeline instead of blocking
+	entry := models.NewLogEntry()
+	entry.Source = "a"
+	select {
+	case input <- entry:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Routing blocked after the failed start")
+	}
+	routed := receive(t, output, 1)[0]
+	if version, _ := routed.GetField("version"); version != "b1" {
+		t.Errorf("Expected entry to reach the default pipeline, got version %v", version)
+	}
+}
+
+func TestApplyWithStalledOutput(t *testing.T) {
+	input := make(chan *models.LogEntry)
+	output := make(chan *models.LogEntry)
+	m := startManager(t, input, output)
+	m.DrainTimeout = 100 * time.Millisecond
+
+	if _, err := m.Apply([]config.PipelineConfig{tagged("a", "", "v1")}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// Fill the pipeline until the router waits on its input
+	go func() {
+		for {
+			select {
+			case input <- models.NewLogEntry():
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := m.Apply([]config.PipelineConfig{tagged("a", "", "v2")}, config.RoutingConfig{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Apply blocked behind the full pipeline input")
+	}
+}
+
+func TestApplyRemovesStaleStageMetrics(t *testing.T) {
+	m := startManager(t, make(chan *models.LogEntry), make(chan *models.LogEntry, 10))
+
+	exposition := func() string {
+		var buf bytes.Buffer
+		metrics.Default.WriteText(&buf)
+		return buf.String()
+	}
+	lowercase := `{pipeline="stale-stages",stage="processor[1]:lowercase"}`
+
+	def := tagged("stale-stages", "", "v1")
+	def.Processors = append(def.Processors, config.ProcessorConfig{Type: "lowercase", Config: map[string]interface{}{"fields": []interface{}{"level"}}})
+	if _, err := m.Apply([]config.PipelineConfig{def}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !strings.Contains(exposition(), lowercase) {
+		t.Fatal("Expected series of the lowercase stage")
+	}
+
+	// A rejected reload leaves the series of the running pipeline alone
+	invalid := []config.PipelineConfig{tagged("stale-stages", "", "v2")}
+	if _, err := m.Apply(invalid, config.RoutingConfig{Mode: "nope"}); err == nil {
+		t.Fatal("Expected error for an invalid routing mode")
+	}
+	if !strings.Contains(exposition(), lowercase) {
+		t.Error("Expected series of the running pipeline to be kept")
+	}
+
+	if _, err := m.Apply([]config.PipelineConfig{tagged("stale-stages", "", "v2")}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	text := exposition()
+	if strings.Contains(text, lowercase) {
+		t.Error("Expected series of the removed stage to be deleted")
+	}
+	if !strings.Contains(text, `{pipeline="stale-stages",stage="processor[0]:add_fields"}`) {
+		t.Error("Expected series of the kept stage")
+	}
+}
+
+func TestStopProcessesQueuedEntries(t *testing.T) {
+	const total = 500
+	input := make(chan *models.LogEntry, total)
+	output := make(chan *models.LogEntry, total)
+	m := NewManager(input, output, nil)
+
+	if _, err := m.Apply([]config.PipelineConfig{tagged("a", "", "v1")}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	sendSeq(input, 0, total)
+
+	// Entries in the router's input and the pipeline input are processed
+	// before Stop returns
+	m.Stop()
+	if len(output) != total {
+		t.Errorf("Expected %d entries after Stop, got %d", total, len(output))
+	}
+	if err := m.Replay(context.Background(), "a", models.NewLogEntry()); err == nil {
+		t.Error("Expected Replay to fail after Stop")
+	}
+}
+
+func TestReplayDoesNotBlockApply(t *testing.T) {
+	input := make(chan *models.LogEntry)
+	output := make(chan *models.LogEntry)
+	m := startManager(t, input, output)
+	m.DrainTimeout = 100 * time.Millisecond
+
+	def := tagged("a", "", "v1")
+	def.Workers = 1
+	def.QueueSize = 1
+	if _, err := m.Apply([]config.PipelineConfig{def}, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// Replay until the worker waits on the output and the input is full
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := m.Replay(ctx, "a", models.NewLogEntry())
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	replayed := make(chan error)
+	go func() {
+		replayed <- m.Replay(ctx, "a", models.NewLogEntry())
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	applied := make(chan error)
+	go func() {
+		_, err := m.Apply([]config.PipelineConfig{tagged("a", "", "v2")}, config.RoutingConfig{})
+		applied <- err
+	}()
+	select {
+	case err := <-applied:
+		if err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Apply blocked behind a waiting replay")
+	}
+
+	// The replay goes to the replacement
+	select {
+	case err := <-replayed:
+		if err != nil {
+			t.Errorf("Replay failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Replay did not reach the replacement")
+	}
+}
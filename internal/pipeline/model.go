@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputStorage is the output of server pipelines
+const OutputStorage = "storage"
+
+// outputOptions are the options of the storage output
+type outputOptions struct {
+	Overflow string `yaml:"overflow,omitempty"`
+	SpillDir string `yaml:"spill_dir,omitempty"`
+}
+
+// FromModel converts an API pipeline into a pipeline definition. The input
+// type is the parser and the input config holds the parser options; the
+// output config holds the overflow policy and spill directory.
+func FromModel(p *models.Pipeline) (*config.PipelineConfig, error) {
+	if p == nil {
+		return nil, fmt.Errorf("pipeline is required")
+	}
+
+	def := &config.PipelineConfig{
+		Name:        p.Name,
+		Filter:      p.Input.Filter,
+		Parser:      p.Input.Type,
+		Workers:     p.Workers,
+		QueueSize:   p.QueueSize,
+		PartitionBy: p.PartitionBy,
+	}
+
+	if len(p.Input.Config) > 0 {
+		var options config.ParserOptions
+		if err := decodeOptions(p.Input.Config, &options); err != nil {
+			return nil, fmt.Errorf("invalid input config: %w", err)
+		}
+		def.ParserOptions = &options
+	}
+
+	if p.Output.Type != "" && p.Output.Type != OutputStorage {
+		return nil, fmt.Errorf("unsupported output type: %s", p.Output.Type)
+	}
+	var output outputOptions
+	if err := decodeOptions(p.Output.Config, &output); err != nil {
+		return nil, fmt.Errorf("invalid output config: %w", err)
+	}
+	def.Overflow = output.Overflow
+	def.SpillDir = output.SpillDir
+
+	for _, proc := range p.Processors {
+		def.Processors = append(def.Processors, config.ProcessorConfig{
+			Type:      proc.Type,
+			Condition: proc.Condition,
+			Config:    proc.Config,
+		})
+	}
+	return def, nil
+}
+
+// ToModel converts a pipeline definition into an API pipeline
+func ToModel(def *config.PipelineConfig) *models.Pipeline {
+	p := &models.Pipeline{
+		ID:          def.Name,
+		Name:        def.Name,
+		Status:      models.PipelineStatusStopped,
+		Enabled:     true,
+		Processors:  make([]models.ProcessorConfig, 0, len(def.Processors)),
+		Workers:     def.Workers,
+		QueueSize:   def.QueueSize,
+		PartitionBy: def.PartitionBy,
+		Input: models.InputConfig{
+			Type:   def.Parser,
+			Filter: def.Filter,
+		},
+		Output: models.OutputConfig{
+			Type: OutputStorage,
+		},
+	}
+
+	if def.ParserOptions != nil {
+		p.Input.Config = encodeOptions(def.ParserOptions)
+	}
+	if def.Overflow != "" || def.SpillDir != "" {
+		p.Output.Config = encodeOptions(&outputOptions{Overflow: def.Overflow, SpillDir: def.SpillDir})
+	}
+
+	for _, proc := range def.Processors {
+		p.Processors = append(p.Processors, models.ProcessorConfig{
+			Type:      proc.Type,
+			Condition: proc.Condition,
+			Config:    proc.Config,
+		})
+	}
+	return p
+}
+
+// ToMetrics converts pipeline statistics into API metrics
+func ToMetrics(stats *PipelineStats) models.PipelineMetrics {
+	m := models.PipelineMetrics{
+		LogsProcessed:   stats.Processed,
+		LogsFailed:      stats.Failed,
+		LogsFiltered:    stats.Filtered,
+		LogsDropped:     stats.Dropped,
+		AverageLatency:  milliseconds(stats.Latency.Average),
+		P50Latency:      milliseconds(stats.Latency.P50),
+		P95Latency:      milliseconds(stats.Latency.P95),
+		P99Latency:      milliseconds(stats.Latency.P99),
+		QueueDepth:      stats.QueueDepth,
+		QueueCapacity:   stats.QueueCapacity,
+		Errors:          stats.Errors,
+		Stages:          make([]models.StageMetrics, 0, len(stats.Stages)),
+		LastProcessedAt: stats.LastProcessed,
+	}
+	for _, stage := range stats.Stages {
+		m.Stages = append(m.Stages, models.StageMetrics{
+			Stage:          stage.Stage,
+			In:             stage.In,
+			Out:            stage.Out,
+			Dropped:        stage.Dropped,
+			Errors:         stage.Errors,
+			ErrorTypes:     stage.ErrorTypes,
+			AverageLatency: milliseconds(stage.Latency.Average),
+			P99Latency:     milliseconds(stage.Latency.P99),
+		})
+	}
+	return m
+}
+
+// milliseconds converts a duration into fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// decodeOptions decodes a generic map into options with YAML field names,
+// rejecting unknown keys like the configuration file does
+func decodeOptions(options map[string]interface{}, out interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(options)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// encodeOptions encodes options into a generic map with YAML field names
+func encodeOptions(options interface{}) map[string]interface{} {
+	data, err := yaml.Marshal(options)
+	if err != nil {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -2,10 +2,17 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
 	"github.com/UmangDiyora/logpipeline/internal/pipeline/processor"
 	"github.com/UmangDiyora/logpipeline/pkg/models"
 	"github.com/UmangDiyora/logpipeline/pkg/parser"
@@ -18,12 +25,37 @@ type Pipeline struct {
 	parser     parser.Parser
 	processors []processor.Processor
 	input      <-chan *models.LogEntry
-	output     chan<- *models.LogEntry
-	ctx        context.Context
-	cancel     context.CancelFunc
+	output     chan *models.LogEntry
+	overflow   string
+	spill      *spillQueue
+	spillReady chan struct{}
+	queue      chan queuedEntry
+	queued     int64
+	partitions []chan *models.LogEntry
+	deadLetter *dlq.Store
+	redactors  []*processor.RedactProcessor
+	workerN    int
+	spillDir   string
+	key        func(*models.LogEntry) string
 	wg         sync.WaitGroup
-	stats      PipelineStats
-	statsMu    sync.RWMutex
+	workers    sync.WaitGroup
+
+	// ctx stops the workers. sendCtx stops everything that moves
+	// processed entries to the output; it is cancelled after the emitters
+	// flushed, so that their final entries are not lost.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	sendCtx  context.Context
+	stopSend context.CancelFunc
+	stats    PipelineStats
+	statsMu  sync.RWMutex
+
+	// Latency and stage statistics are updated without statsMu
+	latency     *latencyHistogram
+	parserStats *stageStats
+	stageStats  []*stageStats
+	exported    *exportedMetrics
+	series      pipelineSeries
 }
 
 // PipelineStats holds pipeline statistics
@@ -33,8 +65,57 @@ type PipelineStats struct {
 	Dropped        uint64
 	AverageLatency time.Duration
 	LastProcessed  time.Time
+
+	// Filtered counts entries removed on purpose by processors such as
+	// filter, drop, sample and dedupe. Failed counts parser and processor
+	// errors and Dropped counts entries discarded by the drop_oldest and
+	// drop_newest overflow policies.
+	Filtered uint64
+
+	// Spilled is the number of entries waiting on disk under the
+	// spill_to_disk overflow policy
+	Spilled uint64
+
+	// Processors holds parser and processor counters, such as partial
+	// lines evicted by a container parser or redactions per detector,
+	// keyed by parser or processor name
+	Processors map[string]map[string]uint64
+
+	// Latency summarizes the time from an entry entering the pipeline to
+	// reaching the output. AverageLatency is its average.
+	Latency LatencyStats
+
+	// QueueDepth is the number of entries waiting for a worker, in the
+	// input queue and the worker queues of a partitioned pipeline, which
+	// hold up to QueueCapacity entries
+	QueueDepth    int
+	QueueCapacity int
+
+	// Errors breaks Failed down by error type
+	Errors map[string]uint64
+
+	// Stages holds the statistics of the parser and each processor, in
+	// pipeline order
+	Stages []StageStats
 }
 
+// Overflow policies applied when the output is full
+const (
+	// OverflowBlock waits for space, slowing down the pipeline input
+	OverflowBlock = "block"
+
+	// OverflowDropOldest discards the oldest entry waiting in the
+	// pipeline's output queue. The output is shared by all pipelines, so
+	// each drop_oldest pipeline queues its own entries in front of it.
+	OverflowDropOldest = "drop_oldest"
+
+	// OverflowDropNewest discards the entry being sent
+	OverflowDropNewest = "drop_newest"
+
+	// OverflowSpill writes entries to disk until the output has space
+	OverflowSpill = "spill_to_disk"
+)
+
 // Config holds pipeline configuration
 type Config struct {
 	ID         string
@@ -42,62 +123,297 @@ type Config struct {
 	Parser     *parser.Config
 	Processors []processor.Config
 	Workers    int
+
+	// Overflow is the policy when the output is full (default block)
+	Overflow string
+
+	// SpillDir holds entries spilled by the spill_to_disk policy
+	SpillDir string
+
+	// PartitionBy gives all entries with the same key to one worker so
+	// that they are processed in order (default: workers share a queue,
+	// except for parsers that reassemble split lines, which need
+	// source_host)
+	PartitionBy string
+
+	// DeadLetter receives entries that fail parsing or processing
+	DeadLetter *dlq.Store
+
+	// Metrics is the registry the pipeline and its processors export
+	// metrics to; nil means metrics.Default
+	Metrics *metrics.Registry
+}
+
+// queueSampleInterval is how often the queue depth gauges are updated
+const queueSampleInterval = time.Second
+
+// stopFlushTimeout bounds how long Stop waits for the entries flushed by
+// emitters to reach the output
+const stopFlushTimeout = 5 * time.Second
+
+// Partition keys of ordered pipelines
+const (
+	// PartitionSourceHost keeps entries of the same source and host in
+	// order, such as the lines of one file
+	PartitionSourceHost = "source_host"
+
+	// PartitionFieldPrefix keeps entries with the same value of a field in
+	// order, e.g. "field:request_id". The field must be set before the
+	// parser runs, for example by the agent. Entries without it share a
+	// worker.
+	PartitionFieldPrefix = "field:"
+)
+
+// partitionQueueSize is the capacity of each worker queue of a
+// partitioned pipeline
+const partitionQueueSize = 100
+
+// outputQueueSize is the capacity of the output queue of a pipeline with
+// the drop_oldest overflow policy
+const outputQueueSize = 1000
+
+// queuedEntry is an entry waiting in a pipeline's output queue
+type queuedEntry struct {
+	entry *models.LogEntry
+	start time.Time
+}
+
+// stageError is an error raised by one stage of the pipeline
+type stageError struct {
+	stage string
+	err   error
+}
+
+// Error implements error
+func (e *stageError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// New creates and starts a new pipeline
+func New(config *Config, input <-chan *models.LogEntry, output chan *models.LogEntry) (*Pipeline, error) {
+	p, err := build(config, input, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-// New creates a new pipeline
-func New(config *Config, input <-chan *models.LogEntry, output chan<- *models.LogEntry) (*Pipeline, error) {
+// build creates a pipeline with its parser and processors without
+// starting it
+func build(config *Config, input <-chan *models.LogEntry, output chan *models.LogEntry) (*Pipeline, error) {
 	if config == nil {
 		return nil, fmt.Errorf("config is required")
 	}
 
+	overflow, err := overflowPolicy(config.Overflow, config.SpillDir)
+	if err != nil {
+		return nil, err
+	}
+	parserType := ""
+	if config.Parser != nil {
+		parserType = config.Parser.Type
+	}
+	partitionBy, err := partitionFor(config.PartitionBy, parserType)
+	if err != nil {
+		return nil, err
+	}
+	key, err := partitionKey(partitionBy)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := config.Metrics
+	if registry == nil {
+		registry = metrics.Default
+	}
+	exported, err := registerMetrics(registry)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
+	sendCtx, stopSend := context.WithCancel(context.Background())
 
 	p := &Pipeline{
-		id:     config.ID,
-		name:   config.Name,
-		input:  input,
-		output: output,
-		ctx:    ctx,
-		cancel: cancel,
+		id:         config.ID,
+		name:       config.Name,
+		input:      input,
+		output:     output,
+		overflow:   overflow,
+		spillDir:   config.SpillDir,
+		key:        key,
+		workerN:    config.Workers,
+		deadLetter: config.DeadLetter,
+		ctx:        ctx,
+		cancel:     cancel,
+		sendCtx:    sendCtx,
+		stopSend:   stopSend,
+		latency:    newLatencyHistogram(),
+		exported:   exported,
+		series:     exported.series(config.Name),
+	}
+	p.stats.Errors = make(map[string]uint64)
+
+	// Set default workers
+	if p.workerN == 0 {
+		p.workerN = 4
 	}
 
 	// Initialize parser
 	if config.Parser != nil {
-		var err error
 		p.parser, err = parser.New(config.Parser)
 		if err != nil {
+			p.discard()
 			return nil, fmt.Errorf("failed to create parser: %w", err)
 		}
+		p.parserStats = newStageStats(p.name, parserStage(p.parser), exported)
 	}
 
 	// Initialize processors
 	p.processors = make([]processor.Processor, 0, len(config.Processors))
-	for _, procConfig := range config.Processors {
+	for i, procConfig := range config.Processors {
+		if procConfig.Metrics == nil {
+			procConfig.Metrics = registry
+		}
 		proc, err := processor.New(&procConfig)
 		if err != nil {
-			fmt.Printf("warning: failed to create processor: %v\n", err)
-			continue
+			p.discard()
+			return nil, fmt.Errorf("failed to create processor %d (%s): %w", i, procConfig.Type, err)
 		}
 		p.processors = append(p.processors, proc)
+		p.stageStats = append(p.stageStats, newStageStats(p.name, processorStage(i, proc), exported))
+
+		if redactor, ok := unwrap(proc).(*processor.RedactProcessor); ok {
+			p.redactors = append(p.redactors, redactor)
+		}
 	}
 
-	// Set default workers
-	if config.Workers == 0 {
-		config.Workers = 4
+	return p, nil
+}
+
+// discard releases the resources of a pipeline that was built but not
+// started
+func (p *Pipeline) discard() {
+	p.cancel()
+	p.stopSend()
+	p.closeProcessors()
+}
+
+// start opens the spill queue and starts the workers. A pipeline that
+// fails to start is discarded.
+func (p *Pipeline) start() error {
+	if p.overflow == OverflowSpill {
+		spill, err := openSpillQueue(p.spillDir)
+		if err != nil {
+			p.discard()
+			return err
+		}
+		p.spill = spill
+		p.spillReady = make(chan struct{}, 1)
+
+		// Entries left on disk by a previous run are sent first
+		p.wg.Add(1)
+		go p.drainSpill()
 	}
 
-	// Start workers
-	for i := 0; i < config.Workers; i++ {
+	if p.overflow == OverflowDropOldest {
+		p.queue = make(chan queuedEntry, outputQueueSize)
 		p.wg.Add(1)
-		go p.worker()
+		go p.forward()
 	}
 
-	return p, nil
+	// Entries emitted asynchronously continue through the processors that
+	// follow the emitter
+	for i, proc := range p.processors {
+		if emitter, ok := proc.(processor.Emitter); ok {
+			next := i + 1
+			emitter.Start(func(entry *models.LogEntry) {
+				p.emit(entry, next)
+			})
+		}
+	}
+
+	// Start workers. Partitioned pipelines give each worker its own queue.
+	if p.key == nil {
+		for i := 0; i < p.workerN; i++ {
+			p.workers.Add(1)
+			go p.worker(p.input)
+		}
+	} else {
+		p.partitions = make([]chan *models.LogEntry, p.workerN)
+		for i := range p.partitions {
+			p.partitions[i] = make(chan *models.LogEntry, partitionQueueSize)
+			p.workers.Add(1)
+			go p.worker(p.partitions[i])
+		}
+		p.workers.Add(1)
+		go p.dispatch(p.key)
+	}
+
+	p.wg.Add(1)
+	go p.sampleQueues()
+
+	return nil
 }
 
-// worker processes log entries
-func (p *Pipeline) worker() {
-	defer p.wg.Done()
+// partitionFor returns the partition key of a pipeline. Parsers that
+// reassemble lines split by the container runtime must see the lines of a
+// stream in order, so their pipelines are partitioned by source and host.
+func partitionFor(partitionBy, parserType string) (string, error) {
+	if !parser.ReassemblesLines(parserType) || partitionBy == PartitionSourceHost {
+		return partitionBy, nil
+	}
+	if partitionBy != "" {
+		return "", fmt.Errorf("parser %s requires partition_by %s", parserType, PartitionSourceHost)
+	}
+	return PartitionSourceHost, nil
+}
+
+// partitionKey validates a partition key and returns the function that
+// extracts it from an entry, or nil when the pipeline is not partitioned
+func partitionKey(partitionBy string) (func(*models.LogEntry) string, error) {
+	switch {
+	case partitionBy == "":
+		return nil, nil
+
+	case partitionBy == PartitionSourceHost:
+		return func(entry *models.LogEntry) string {
+			return entry.Source + "\x00" + entry.Host
+		}, nil
+
+	case strings.HasPrefix(partitionBy, PartitionFieldPrefix):
+		field := strings.TrimPrefix(partitionBy, PartitionFieldPrefix)
+		if field == "" {
+			return nil, fmt.Errorf("partition_by %q requires a field name", partitionBy)
+		}
+		return func(entry *models.LogEntry) string {
+			value, ok := entry.GetField(field)
+			if !ok {
+				return ""
+			}
+			return fmt.Sprint(value)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported partition_by: %s", partitionBy)
+}
+
+// dispatch sends each entry to the worker queue chosen by its key, so
+// that entries with the same key are processed one at a time and in order
+func (p *Pipeline) dispatch(key func(*models.LogEntry) string) {
+	defer p.workers.Done()
+	defer func() {
+		for _, queue := range p.partitions {
+			close(queue)
+		}
+	}()
 
 	for {
 		select {
@@ -109,82 +425,510 @@ func (p *Pipeline) worker() {
 				return
 			}
 
+			h := fnv.New32a()
+			h.Write([]byte(key(entry)))
+			queue := p.partitions[h.Sum32()%uint32(len(p.partitions))]
+
+			select {
+			case queue <- entry:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// overflowPolicy validates an overflow policy and returns it, defaulting
+// to block
+func overflowPolicy(overflow, spillDir string) (string, error) {
+	if overflow == "" {
+		overflow = OverflowBlock
+	}
+	switch overflow {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+	case OverflowSpill:
+		if spillDir == "" {
+			return "", fmt.Errorf("overflow policy %s requires a spill directory", overflow)
+		}
+	default:
+		return "", fmt.Errorf("unsupported overflow policy: %s", overflow)
+	}
+	return overflow, nil
+}
+
+// worker processes log entries from a queue
+func (p *Pipeline) worker(input <-chan *models.LogEntry) {
+	defer p.workers.Done()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+
+		case entry, ok := <-input:
+			if !ok {
+				return
+			}
+
 			start := time.Now()
 
+			// Keep the entry as received for the dead-letter queue
+			var original *models.LogEntry
+			if p.deadLetter != nil {
+				original = entry.Clone()
+			}
+
 			// Process entry
-			if err := p.processEntry(entry); err != nil {
-				p.recordFailure()
+			entries, err := p.processEntry(entry)
+			if err != nil {
+				// Fragments are held by the parser until the message is complete
+				if errors.Is(err, parser.ErrPartialEntry) {
+					continue
+				}
+				p.recordFailure(errorType(err))
+				p.deadLetterEntry(original, err)
 				continue
 			}
 
 			// Send to output
-			select {
-			case p.output <- entry:
-				p.recordSuccess(time.Since(start))
-			case <-p.ctx.Done():
+			stopped := false
+			for _, result := range entries {
+				if !p.send(p.ctx, result, start) {
+					stopped = true
+				}
+			}
+			if stopped {
 				return
-			default:
-				// Output full, drop entry
-				p.recordDrop()
 			}
 		}
 	}
 }
 
-// processEntry processes a single log entry
-func (p *Pipeline) processEntry(entry *models.LogEntry) error {
+// processEntry processes a single log entry and returns the entries to
+// send to the output
+func (p *Pipeline) processEntry(entry *models.LogEntry) ([]*models.LogEntry, error) {
 	// Apply parser
 	if p.parser != nil {
-		if err := p.parser.Parse(entry); err != nil {
-			return fmt.Errorf("parser error: %w", err)
+		start := time.Now()
+		err := p.parser.Parse(entry)
+		took := time.Since(start)
+		if err != nil {
+			if errors.Is(err, parser.ErrPartialEntry) {
+				p.parserStats.held(took)
+			} else {
+				p.parserStats.failed(err, took)
+			}
+			return nil, &stageError{
+				stage: parserStage(p.parser),
+				err:   fmt.Errorf("parser error: %w", err),
+			}
 		}
+		p.parserStats.done(1, took)
 	}
 
-	// Apply processors
-	for _, proc := range p.processors {
-		if err := proc.Process(entry); err != nil {
-			return fmt.Errorf("processor %s error: %w", proc.Name(), err)
+	return p.applyProcessors(entry, 0)
+}
+
+// applyProcessors runs the processors starting at index from. Each
+// processor may drop an entry or split it into several; entries dropped
+// by a processor are counted as filtered.
+func (p *Pipeline) applyProcessors(entry *models.LogEntry, from int) ([]*models.LogEntry, error) {
+	entries := []*models.LogEntry{entry}
+	for i, proc := range p.processors[from:] {
+		stats := p.stageStats[from+i]
+		next := make([]*models.LogEntry, 0, len(entries))
+		for _, current := range entries {
+			start := time.Now()
+			results, err := proc.Process(current)
+			took := time.Since(start)
+			if err != nil {
+				stats.failed(err, took)
+				return nil, &stageError{
+					stage: processorStage(from+i, proc),
+					err:   fmt.Errorf("processor %s error: %w", proc.Name(), err),
+				}
+			}
+			stats.done(len(results), took)
+			if len(results) == 0 {
+				p.recordFilter()
+			}
+			next = append(next, results...)
+		}
+
+		entries = next
+		if len(entries) == 0 {
+			break
 		}
 	}
+	return entries, nil
+}
 
-	return nil
+// emit processes an entry produced by a processor outside of the workers,
+// starting at the processor after it, and sends it to the output
+func (p *Pipeline) emit(entry *models.LogEntry, from int) {
+	start := time.Now()
+
+	var original *models.LogEntry
+	if p.deadLetter != nil {
+		original = entry.Clone()
+	}
+
+	entries, err := p.applyProcessors(entry, from)
+	if err != nil {
+		p.recordFailure(errorType(err))
+		p.deadLetterEntry(original, err)
+		return
+	}
+
+	for _, result := range entries {
+		p.send(p.sendCtx, result, start)
+	}
+}
+
+// unwrap returns the processor inside a conditional processor
+func unwrap(proc processor.Processor) processor.Processor {
+	if conditional, ok := proc.(*processor.ConditionalProcessor); ok {
+		return conditional.Unwrap()
+	}
+	return proc
+}
+
+// deadLetterEntry stores an entry that failed in the dead-letter queue.
+// The entry is kept as it entered the pipeline, so every redact stage of
+// the pipeline is applied to it first, whatever its condition, and
+// sensitive values are not written to disk.
+func (p *Pipeline) deadLetterEntry(original *models.LogEntry, err error) {
+	if p.deadLetter == nil {
+		return
+	}
+
+	// Errors may quote the values that failed
+	message := err.Error()
+	for _, redactor := range p.redactors {
+		redactor.Redact(original)
+		message = redactor.RedactText(message)
+	}
+
+	stage := "pipeline"
+	var se *stageError
+	if errors.As(err, &se) {
+		stage = se.stage
+	}
+
+	record := &dlq.Record{
+		PipelineID: p.id,
+		Stage:      stage,
+		Error:      message,
+		Entry:      original,
+	}
+	if err := p.deadLetter.Add(record); err != nil {
+		fmt.Printf("pipeline %s: %v\n", p.name, err)
+	}
+}
+
+// send sends an entry to the output, applying the overflow policy when
+// the output is full. It returns false when ctx is done first; the entry
+// is then counted as dropped.
+func (p *Pipeline) send(ctx context.Context, entry *models.LogEntry, start time.Time) bool {
+	// Keep entries in order while earlier ones wait on disk
+	if p.spill != nil && p.spill.Len() > 0 {
+		return p.spillEntry(entry)
+	}
+	if p.queue != nil {
+		return p.enqueue(ctx, entry, start)
+	}
+
+	select {
+	case p.output <- entry:
+		p.recordSuccess(time.Since(start))
+		return true
+	default:
+	}
+
+	switch p.overflow {
+	case OverflowDropNewest:
+		p.recordDrop()
+		return true
+
+	case OverflowSpill:
+		return p.spillEntry(entry)
+	}
+
+	select {
+	case p.output <- entry:
+		p.recordSuccess(time.Since(start))
+		return true
+	case <-ctx.Done():
+		p.recordDrop()
+		return false
+	}
+}
+
+// enqueue adds an entry to the output queue, discarding the oldest
+// queued entries while it is full
+func (p *Pipeline) enqueue(ctx context.Context, entry *models.LogEntry, start time.Time) bool {
+	queued := queuedEntry{entry: entry, start: start}
+	for {
+		if ctx.Err() != nil {
+			p.recordDrop()
+			return false
+		}
+
+		select {
+		case p.queue <- queued:
+			atomic.AddInt64(&p.queued, 1)
+			return true
+		default:
+		}
+
+		select {
+		case <-p.queue:
+			atomic.AddInt64(&p.queued, -1)
+			p.recordDrop()
+		default:
+		}
+	}
+}
+
+// forward moves entries from the output queue to the output. Entries
+// still queued when the pipeline stops are counted as dropped.
+func (p *Pipeline) forward() {
+	defer p.wg.Done()
+	defer func() {
+		for {
+			select {
+			case <-p.queue:
+				atomic.AddInt64(&p.queued, -1)
+				p.recordDrop()
+			default:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-p.sendCtx.Done():
+			return
+		case queued := <-p.queue:
+			select {
+			case p.output <- queued.entry:
+				atomic.AddInt64(&p.queued, -1)
+				p.recordSuccess(time.Since(queued.start))
+			case <-p.sendCtx.Done():
+				atomic.AddInt64(&p.queued, -1)
+				p.recordDrop()
+				return
+			}
+		}
+	}
+}
+
+// spillEntry writes an entry to the spill queue. Entries that cannot be
+// written are counted as failed.
+func (p *Pipeline) spillEntry(entry *models.LogEntry) bool {
+	if err := p.spill.Push(entry); err != nil {
+		fmt.Printf("pipeline %s: %v\n", p.name, err)
+		p.recordFailure(ErrorTypeSpill)
+		return true
+	}
+
+	select {
+	case p.spillReady <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// drainSpill moves spilled entries to the output as space frees up
+func (p *Pipeline) drainSpill() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		entry, ok, err := p.spill.Peek()
+		if err != nil {
+			fmt.Printf("pipeline %s: %v\n", p.name, err)
+			p.recordFailure(ErrorTypeSpill)
+			select {
+			case <-p.sendCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+		if !ok {
+			select {
+			case <-p.sendCtx.Done():
+				return
+			case <-p.spillReady:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		// The entry stays first in the queue until it is sent
+		select {
+		case p.output <- entry:
+			p.spill.Remove()
+			p.recordSent()
+		case <-p.sendCtx.Done():
+			return
+		}
+	}
 }
 
-// recordSuccess records successful processing
+// recordSuccess records an entry sent to the output and the time since
+// it entered the pipeline
 func (p *Pipeline) recordSuccess(latency time.Duration) {
-	p.statsMu.Lock()
-	defer p.statsMu.Unlock()
+	p.recordSent()
+	p.latency.observe(latency)
+	p.series.latency.Observe(latency.Seconds())
+}
 
+// recordSent records an entry sent to the output. Entries sent from the
+// spill queue are not timed.
+func (p *Pipeline) recordSent() {
+	p.statsMu.Lock()
 	p.stats.Processed++
 	p.stats.LastProcessed = time.Now()
-
-	// Update average latency
-	if p.stats.AverageLatency == 0 {
-		p.stats.AverageLatency = latency
-	} else {
-		p.stats.AverageLatency = (p.stats.AverageLatency + latency) / 2
-	}
+	p.statsMu.Unlock()
+	p.series.processed.Add(1)
 }
 
 // recordFailure records failed processing
-func (p *Pipeline) recordFailure() {
+func (p *Pipeline) recordFailure(errType string) {
 	p.statsMu.Lock()
-	defer p.statsMu.Unlock()
 	p.stats.Failed++
+	p.stats.Errors[errType]++
+	p.statsMu.Unlock()
+	p.series.failed.Add(1)
+	p.exported.errors.Add(1, p.name, errType)
+}
+
+// recordFilter records an entry discarded by a processor
+func (p *Pipeline) recordFilter() {
+	p.statsMu.Lock()
+	p.stats.Filtered++
+	p.statsMu.Unlock()
+	p.series.filtered.Add(1)
 }
 
 // recordDrop records dropped entry
 func (p *Pipeline) recordDrop() {
 	p.statsMu.Lock()
-	defer p.statsMu.Unlock()
 	p.stats.Dropped++
+	p.statsMu.Unlock()
+	p.series.dropped.Add(1)
+}
+
+// sampleQueues updates the queue depth gauges until the pipeline stops
+func (p *Pipeline) sampleQueues() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(queueSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		depth, _ := p.queueDepth()
+		p.exported.queueDepth.Set(float64(depth), p.name, "input")
+		if p.spill != nil {
+			p.exported.queueDepth.Set(float64(p.spill.Len()), p.name, "spill")
+		}
+		if p.queue != nil {
+			p.exported.queueDepth.Set(float64(atomic.LoadInt64(&p.queued)), p.name, "output")
+		}
+
+		select {
+		case <-p.sendCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// queueDepth returns the number of entries waiting for a worker and the
+// capacity of the queues holding them
+func (p *Pipeline) queueDepth() (int, int) {
+	depth, capacity := len(p.input), cap(p.input)
+	for _, queue := range p.partitions {
+		depth += len(queue)
+		capacity += cap(queue)
+	}
+	return depth, capacity
+}
+
+// removeMetrics removes the exported metrics of a pipeline that is gone
+func (p *Pipeline) removeMetrics() {
+	p.exported.delete(p.name)
+}
+
+// removeStaleMetrics removes the exported series of stages that another
+// pipeline of the same name had and this one has not, e.g. after its
+// processors changed
+func (p *Pipeline) removeStaleMetrics() {
+	stages := make(map[string]bool, len(p.stageStats)+1)
+	if p.parserStats != nil {
+		stages[p.parserStats.name] = true
+	}
+	for _, s := range p.stageStats {
+		stages[s.name] = true
+	}
+	p.exported.deleteStages(p.name, stages)
 }
 
 // GetStats returns pipeline statistics
 func (p *Pipeline) GetStats() PipelineStats {
 	p.statsMu.RLock()
-	defer p.statsMu.RUnlock()
-	return p.stats
+	stats := p.stats
+	stats.Errors = make(map[string]uint64, len(p.stats.Errors))
+	for errType, n := range p.stats.Errors {
+		stats.Errors[errType] = n
+	}
+	p.statsMu.RUnlock()
+
+	if p.spill != nil {
+		stats.Spilled = uint64(p.spill.Len())
+	}
+
+	stats.Latency = p.latency.stats()
+	stats.AverageLatency = stats.Latency.Average
+	stats.QueueDepth, stats.QueueCapacity = p.queueDepth()
+
+	stats.Stages = make([]StageStats, 0, len(p.stageStats)+1)
+	if p.parserStats != nil {
+		stats.Stages = append(stats.Stages, p.parserStats.stats())
+	}
+	for _, s := range p.stageStats {
+		stats.Stages = append(stats.Stages, s.stats())
+	}
+
+	stats.Processors = make(map[string]map[string]uint64)
+	if provider, ok := p.parser.(processor.StatsProvider); ok {
+		stats.Processors[p.parser.Name()] = provider.Stats()
+	}
+	for _, proc := range p.processors {
+		provider, ok := proc.(processor.StatsProvider)
+		if !ok {
+			continue
+		}
+		counters := provider.Stats()
+		if len(counters) == 0 {
+			continue
+		}
+		// Processors of the same type share a set of counters
+		merged, exists := stats.Processors[proc.Name()]
+		if !exists {
+			merged = make(map[string]uint64, len(counters))
+			stats.Processors[proc.Name()] = merged
+		}
+		for key, value := range counters {
+			merged[key] += value
+		}
+	}
+	return stats
 }
 
 // ID returns the pipeline ID
@@ -197,9 +941,99 @@ func (p *Pipeline) Name() string {
 	return p.name
 }
 
-// Stop stops the pipeline
+// Drain waits for the workers to process the entries left in the input,
+// which the caller has closed, and then stops the pipeline once spilled,
+// queued and flushed entries reached the output. When ctx is done first,
+// the pipeline stops without processing the rest; spilled entries stay on
+// disk and other entries are counted as dropped.
+func (p *Pipeline) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return p.stop(ctx, true)
+}
+
+// pending returns the number of processed entries not yet sent to the
+// output
+func (p *Pipeline) pending() int {
+	n := int(atomic.LoadInt64(&p.queued))
+	if p.spill != nil {
+		n += p.spill.Len()
+	}
+	return n
+}
+
+// waitSent waits until no processed entry waits for the output or ctx is
+// done
+func (p *Pipeline) waitSent(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for p.pending() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the pipeline. Processors that emit entries asynchronously
+// are stopped after the workers so that pending entries are flushed; the
+// flushed entries may wait up to stopFlushTimeout for the output. Then
+// processors holding resources are closed.
 func (p *Pipeline) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopFlushTimeout)
+	defer cancel()
+	return p.stop(ctx, false)
+}
+
+// stop stops the workers, flushes the emitters while entries can still
+// reach the output until ctx is done, and then stops sending. With wait
+// set, it also waits for queued and spilled entries to be sent.
+func (p *Pipeline) stop(ctx context.Context, wait bool) error {
 	p.cancel()
+	p.workers.Wait()
+
+	abort := context.AfterFunc(ctx, p.stopSend)
+	for _, proc := range p.processors {
+		if emitter, ok := proc.(processor.Emitter); ok {
+			emitter.Stop()
+		}
+	}
+	if wait {
+		p.waitSent(ctx)
+	}
+	abort()
+	p.stopSend()
 	p.wg.Wait()
-	return nil
+
+	if p.spill != nil {
+		if err := p.spill.Close(); err != nil {
+			fmt.Printf("pipeline %s: failed to close spill queue: %v\n", p.name, err)
+		}
+	}
+
+	return p.closeProcessors()
+}
+
+// closeProcessors releases resources such as file watchers held by
+// processors and returns the first error
+func (p *Pipeline) closeProcessors() error {
+	var firstErr error
+	for _, proc := range p.processors {
+		if closer, ok := proc.(io.Closer); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to close processor %s: %w", proc.Name(), err)
+			}
+		}
+	}
+	return firstErr
 }
@@ -0,0 +1,465 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/dlq"
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/processor"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"github.com/UmangDiyora/logpipeline/pkg/parser"
+)
+
+// startPipeline creates a pipeline with private metrics and stops it when
+// the test ends
+func startPipeline(t *testing.T, cfg *Config, input chan *models.LogEntry, output chan *models.LogEntry) *Pipeline {
+	t.Helper()
+
+	if cfg.Name == "" {
+		cfg.Name = "test"
+	}
+	cfg.Metrics = metrics.NewRegistry()
+	p, err := New(cfg, input, output)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+	t.Cleanup(func() { p.Stop() })
+	return p
+}
+
+// receive reads n entries from the output
+func receive(t *testing.T, output <-chan *models.LogEntry, n int) []*models.LogEntry {
+	t.Helper()
+
+	entries := make([]*models.LogEntry, 0, n)
+	timeout := time.After(5 * time.Second)
+	for len(entries) < n {
+		select {
+		case entry := <-output:
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatalf("Timed out after %d of %d entries", len(entries), n)
+		}
+	}
+	return entries
+}
+
+func TestContainerParserPipelineKeepsStreamsInOrder(t *testing.T) {
+	input := make(chan *models.LogEntry, 1000)
+	output := make(chan *models.LogEntry, 1000)
+	p := startPipeline(t, &Config{
+		Parser:  &parser.Config{Type: "cri"},
+		Workers: 8,
+	}, input, output)
+
+	const messages = 100
+	go func() {
+		for n := 0; n < messages; n++ {
+			for pod := 0; pod < 4; pod++ {
+				for _, line := range []string{"P a-", "P b-", fmt.Sprintf("F %d", n)} {
+					entry := models.NewLogEntry()
+					entry.Source = fmt.Sprintf("pod-%d", pod)
+					entry.Raw = "2024-01-01T12:00:00Z stdout " + line
+					input <- entry
+				}
+			}
+		}
+	}()
+
+	for _, entry := range receive(t, output, 4*messages) {
+		if !strings.HasPrefix(entry.Message, "a-b-") {
+			t.Fatalf("Expected reassembled message, got '%s'", entry.Message)
+		}
+	}
+
+	counters, ok := p.GetStats().Processors["cri"]
+	if !ok || counters["partials_evicted"] != 0 {
+		t.Errorf("Expected parser counters in stats, got %v", p.GetStats().Processors)
+	}
+}
+
+func TestValidateContainerParserPartition(t *testing.T) {
+	def := &config.PipelineConfig{Name: "pods", Parser: "docker"}
+	if err := Validate(def); err != nil {
+		t.Errorf("Expected docker pipeline without partition_by to be valid, got %v", err)
+	}
+
+	def.PartitionBy = "field:request_id"
+	if err := Validate(def); err == nil {
+		t.Error("Expected error for docker pipeline partitioned by field")
+	}
+}
+
+// sleepProcessor takes a random time per entry so that workers finish
+// entries out of order
+type sleepProcessor struct{}
+
+func (sleepProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+	return []*models.LogEntry{entry}, nil
+}
+
+func (sleepProcessor) Name() string {
+	return "sleep"
+}
+
+func TestPartitionedPipelineKeepsKeysInOrder(t *testing.T) {
+	const keys, perKey = 4, 200
+	input := make(chan *models.LogEntry, keys*perKey)
+	output := make(chan *models.LogEntry, keys*perKey)
+
+	p, err := build(&Config{Name: "test", Workers: 8, PartitionBy: "field:key", Metrics: metrics.NewRegistry()}, input, output)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+	p.processors = append(p.processors, sleepProcessor{})
+	p.stageStats = append(p.stageStats, newStageStats(p.name, processorStage(0, sleepProcessor{}), p.exported))
+	if err := p.start(); err != nil {
+		t.Fatalf("Failed to start pipeline: %v", err)
+	}
+
+	for n := 0; n < perKey; n++ {
+		for k := 0; k < keys; k++ {
+			entry := models.NewLogEntry()
+			entry.AddField("key", fmt.Sprintf("k%d", k))
+			entry.AddField("seq", n)
+			input <- entry
+		}
+	}
+
+	// Closing the input closes the worker queues while they still hold
+	// entries; draining must finish them in order
+	close(input)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	next := make(map[string]int)
+	for _, entry := range receive(t, output, keys*perKey) {
+		key, _ := entry.GetField("key")
+		seq, _ := entry.GetField("seq")
+		if seq != next[key.(string)] {
+			t.Fatalf("Expected %s entry %d, got %v", key, next[key.(string)], seq)
+		}
+		next[key.(string)]++
+	}
+}
+
+// waitFor polls cond until it holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// sendSeq sends entries numbered from to to
+func sendSeq(input chan<- *models.LogEntry, from, to int) {
+	for i := from; i < to; i++ {
+		entry := models.NewLogEntry()
+		entry.ID = strconv.Itoa(i)
+		input <- entry
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	input := make(chan *models.LogEntry, 10)
+	output := make(chan *models.LogEntry)
+	p := startPipeline(t, &Config{Workers: 1}, input, output)
+
+	go sendSeq(input, 0, 50)
+	for i, entry := range receive(t, output, 50) {
+		if entry.ID != strconv.Itoa(i) {
+			t.Fatalf("Expected entry %d, got %s", i, entry.ID)
+		}
+	}
+	// Counters are updated once the send returned
+	waitFor(t, "processed entries", func() bool { return p.GetStats().Processed == 50 })
+	if stats := p.GetStats(); stats.Dropped != 0 {
+		t.Errorf("Expected no drops, got %d", stats.Dropped)
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	input := make(chan *models.LogEntry, 10)
+	output := make(chan *models.LogEntry, 2)
+	p := startPipeline(t, &Config{Workers: 1, Overflow: OverflowDropNewest}, input, output)
+
+	sendSeq(input, 0, 10)
+	waitFor(t, "drops", func() bool { return p.GetStats().Dropped == 8 })
+
+	for i, entry := range receive(t, output, 2) {
+		if entry.ID != strconv.Itoa(i) {
+			t.Errorf("Expected the first entries to be kept, got %s", entry.ID)
+		}
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	// Entries of other pipelines fill the shared output
+	output := make(chan *models.LogEntry, 5)
+	for i := 0; i < cap(output); i++ {
+		other := models.NewLogEntry()
+		other.ID = "other"
+		output <- other
+	}
+
+	input := make(chan *models.LogEntry, 10)
+	p := startPipeline(t, &Config{Workers: 1, Overflow: OverflowDropOldest}, input, output)
+
+	// One entry waits for the output and the queue holds the rest
+	total := outputQueueSize + 20
+	sendSeq(input, 0, total)
+	waitFor(t, "drops", func() bool { return p.GetStats().Dropped == uint64(total-outputQueueSize-1) })
+
+	entries := receive(t, output, cap(output)+outputQueueSize+1)
+	for _, entry := range entries[:cap(output)] {
+		if entry.ID != "other" {
+			t.Fatalf("Expected entries of other pipelines to be kept, got %s", entry.ID)
+		}
+	}
+
+	// The newest entries survive, in order
+	queued := entries[cap(output)+1:]
+	for i, entry := range queued {
+		if expected := strconv.Itoa(total - outputQueueSize + i); entry.ID != expected {
+			t.Fatalf("Expected entry %s, got %s", expected, entry.ID)
+		}
+	}
+}
+
+func TestOverflowDropOldestStops(t *testing.T) {
+	output := make(chan *models.LogEntry)
+	input := make(chan *models.LogEntry, 10)
+	p, err := New(&Config{Name: "test", Workers: 2, Overflow: OverflowDropOldest, Metrics: metrics.NewRegistry()}, input, output)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	go sendSeq(input, 0, outputQueueSize+100)
+	waitFor(t, "drops", func() bool { return p.GetStats().Dropped > 0 })
+
+	stopped := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return while the output was full")
+	}
+
+	// Nothing reached the output, so every entry taken from the input was
+	// dropped
+	stats := p.GetStats()
+	if stats.Processed != 0 || stats.Dropped == 0 {
+		t.Errorf("Expected only drops, got %+v", stats)
+	}
+}
+
+func TestOverflowSpill(t *testing.T) {
+	dir := t.TempDir()
+	input := make(chan *models.LogEntry, 10)
+	output := make(chan *models.LogEntry)
+	p := startPipeline(t, &Config{Workers: 1, Overflow: OverflowSpill, SpillDir: dir}, input, output)
+
+	// Nothing reads the output, so every entry goes to disk and stays
+	// there until it is sent
+	sendSeq(input, 0, 20)
+	waitFor(t, "spilled entries", func() bool { return p.GetStats().Spilled == 20 })
+
+	for i, entry := range receive(t, output, 20) {
+		if entry.ID != strconv.Itoa(i) {
+			t.Fatalf("Expected entry %d, got %s", i, entry.ID)
+		}
+	}
+	if stats := p.GetStats(); stats.Spilled != 0 || stats.Dropped != 0 {
+		t.Errorf("Expected an empty spill queue and no drops, got %+v", stats)
+	}
+}
+
+func TestOverflowSpillStopKeepsOrder(t *testing.T) {
+	dir := t.TempDir()
+	input := make(chan *models.LogEntry, 20)
+	output := make(chan *models.LogEntry)
+	p, err := New(&Config{Name: "test", Workers: 1, Overflow: OverflowSpill, SpillDir: dir, Metrics: metrics.NewRegistry()}, input, output)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	sendSeq(input, 0, 20)
+	waitFor(t, "spilled entries", func() bool { return len(input) == 0 && p.GetStats().Spilled >= 19 })
+
+	// Stop while the spill queue is half sent and its next entry waits
+	// for the output
+	receive(t, output, 5)
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	// The entry that was waiting is still ahead of the newer ones
+	q := openSpill(t, dir)
+	last := -1
+	for {
+		entry, ok, err := q.Pop()
+		if err != nil {
+			t.Fatalf("Pop failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		n, _ := strconv.Atoi(entry.ID)
+		if n <= last {
+			t.Fatalf("Expected spilled entries in order, got %d after %d", n, last)
+		}
+		last = n
+	}
+	if last != 19 {
+		t.Errorf("Expected entries up to 19 on disk, got %d", last)
+	}
+}
+
+func TestDeadLetterRecordsAreRedacted(t *testing.T) {
+	store, err := dlq.Open(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("Failed to open dead-letter store: %v", err)
+	}
+	defer store.Close()
+
+	input := make(chan *models.LogEntry, 10)
+	output := make(chan *models.LogEntry, 10)
+	startPipeline(t, &Config{
+		Parser: &parser.Config{Type: "json"},
+		Processors: []processor.Config{
+			// The condition does not hold for the failing entries
+			{Type: "redact", Condition: `level == "DEBUG"`, Fields: map[string]interface{}{"detectors": []interface{}{"email"}}},
+			{Type: "convert", Fields: map[string]interface{}{
+				"fields":   map[string]interface{}{"user": "int"},
+				"on_error": "fail",
+			}},
+		},
+		DeadLetter: store,
+	}, input, output)
+
+	for _, raw := range []string{
+		`{"message": "login", "user": "bob@example.com"}`,
+		`not json from bob@example.com`,
+	} {
+		entry := models.NewLogEntry()
+		entry.Raw = raw
+		input <- entry
+	}
+
+	var records []*dlq.Record
+	waitFor(t, "dead-letter records", func() bool {
+		records, _ = store.List(nil, 0, 0)
+		return len(records) == 2
+	})
+
+	for _, record := range records {
+		data := record.Error + record.Entry.Raw + record.Entry.Message
+		if strings.Contains(data, "bob@example.com") {
+			t.Errorf("Expected %s record to be redacted, got %q (%s)", record.Stage, record.Entry.Raw, record.Error)
+		}
+		if !strings.Contains(record.Entry.Raw, processor.DefaultRedactMask) {
+			t.Errorf("Expected masked raw line, got %q", record.Entry.Raw)
+		}
+	}
+}
+
+func TestDrainFlushesEmittersToFullOutput(t *testing.T) {
+	output := make(chan *models.LogEntry, 1)
+	other := models.NewLogEntry()
+	other.ID = "other"
+	output <- other
+
+	input := make(chan *models.LogEntry, 10)
+	p := startPipeline(t, &Config{
+		Workers:    1,
+		Processors: []processor.Config{{Type: "dedupe", Fields: map[string]interface{}{"window": "1h"}}},
+	}, input, output)
+
+	for i := 0; i < 3; i++ {
+		entry := models.NewLogEntry()
+		entry.ID = strconv.Itoa(i)
+		entry.Message = "disk full"
+		input <- entry
+	}
+	close(input)
+
+	// A slow consumer keeps the output full while the pipeline stops
+	received := make(chan []*models.LogEntry)
+	go func() {
+		var entries []*models.LogEntry
+		for len(entries) < 3 {
+			time.Sleep(50 * time.Millisecond)
+			entries = append(entries, <-output)
+		}
+		received <- entries
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	select {
+	case entries := <-received:
+		if !entries[2].HasTag(processor.DedupeSummaryTag) {
+			t.Errorf("Expected the dedupe summary last, got %s %v", entries[2].ID, entries[2].Tags)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the dedupe summary")
+	}
+	if stats := p.GetStats(); stats.Dropped != 0 {
+		t.Errorf("Expected no drops, got %d", stats.Dropped)
+	}
+}
+
+func TestStopCountsUnsentFlush(t *testing.T) {
+	output := make(chan *models.LogEntry)
+	input := make(chan *models.LogEntry, 10)
+	p := startPipeline(t, &Config{
+		Workers:    1,
+		Processors: []processor.Config{{Type: "dedupe", Fields: map[string]interface{}{"window": "1h"}}},
+	}, input, output)
+
+	for i := 0; i < 3; i++ {
+		entry := models.NewLogEntry()
+		entry.Message = "disk full"
+		input <- entry
+	}
+	first := receive(t, output, 1)[0]
+	waitFor(t, "suppressed repeats", func() bool {
+		return p.GetStats().Processors["dedupe"]["suppressed"] == 2
+	})
+
+	// Nothing reads the summary, so it is dropped once the deadline passed
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.stop(ctx, true); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if first.HasTag(processor.DedupeSummaryTag) {
+		t.Fatal("Expected the first entry before the summary")
+	}
+	if stats := p.GetStats(); stats.Dropped != 1 {
+		t.Errorf("Expected the summary to be counted as dropped, got %d", stats.Dropped)
+	}
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// ComputeFailureTag is added to entries with a field that could not be computed
+const ComputeFailureTag = "_compute_failure"
+
+// computedField is a field set from an expression
+type computedField struct {
+	target     string
+	expression *expr.Expression
+}
+
+// ComputeProcessor sets fields from expressions over existing ones
+type ComputeProcessor struct {
+	fields  []computedField
+	onError string
+}
+
+// NewComputeProcessor creates a new compute processor.
+//
+// Supported options:
+//   - fields: map of target field to expression, e.g.
+//     kb: round(bytes / 1024, 1)
+//   - on_error: keep, tag, fail or drop (default "tag")
+//
+// Fields are computed in order of their names, so an expression can use a
+// field computed before it. A null result leaves the target unset.
+func NewComputeProcessor(fields map[string]interface{}) (*ComputeProcessor, error) {
+	p := &ComputeProcessor{
+		onError: stringValue(fields, "on_error", OnErrorTag),
+	}
+
+	switch p.onError {
+	case OnErrorKeep, OnErrorTag, OnErrorFail, OnErrorDrop:
+	default:
+		return nil, fmt.Errorf("invalid compute on_error policy: %s", p.onError)
+	}
+
+	expressions := stringMap(fields["fields"])
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("compute requires at least one field")
+	}
+	for target, src := range expressions {
+		compiled, err := expr.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression for %s: %w", target, err)
+		}
+		p.fields = append(p.fields, computedField{target: target, expression: compiled})
+	}
+	sort.Slice(p.fields, func(i, j int) bool {
+		return p.fields[i].target < p.fields[j].target
+	})
+
+	return p, nil
+}
+
+// Process evaluates the expressions and stores their results
+func (p *ComputeProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	for _, f := range p.fields {
+		value, err := f.expression.Eval(entry)
+		if err == nil {
+			if value != nil {
+				entry.AddField(f.target, value)
+			}
+			continue
+		}
+
+		switch p.onError {
+		case OnErrorFail:
+			return nil, fmt.Errorf("failed to compute %s: %w", f.target, err)
+		case OnErrorDrop:
+			return nil, nil
+		case OnErrorTag:
+			if !entry.HasTag(ComputeFailureTag) {
+				entry.AddTag(ComputeFailureTag)
+			}
+		}
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// Name returns the processor name
+func (p *ComputeProcessor) Name() string {
+	return "compute"
+}
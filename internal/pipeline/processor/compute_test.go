@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestComputeFields(t *testing.T) {
+	p, err := NewComputeProcessor(map[string]interface{}{
+		"fields": map[string]interface{}{
+			"a_kb":   "round(bytes / 1024, 1)",
+			"b_mb":   "a_kb / 1024",
+			"label":  "service + \"/\" + host",
+			"absent": "missing",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Service = "checkout"
+	entry.Host = "web-1"
+	entry.AddField("bytes", 2048.0)
+
+	if _, err := p.Process(entry); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if kb, _ := entry.GetField("a_kb"); kb != 2.0 {
+		t.Errorf("Expected a_kb 2, got %v", kb)
+	}
+	// Fields are computed in name order, so b_mb sees a_kb
+	if mb, _ := entry.GetField("b_mb"); mb != 2.0/1024 {
+		t.Errorf("Expected b_mb from a_kb, got %v", mb)
+	}
+	if label, _ := entry.GetField("label"); label != "checkout/web-1" {
+		t.Errorf("Expected label checkout/web-1, got %v", label)
+	}
+	if _, ok := entry.GetField("absent"); ok {
+		t.Error("Expected null result to leave the field unset")
+	}
+}
+
+func TestComputeOnError(t *testing.T) {
+	tests := []struct {
+		policy  string
+		dropped bool
+		failed  bool
+		tagged  bool
+	}{
+		{policy: OnErrorKeep},
+		{policy: OnErrorTag, tagged: true},
+		{policy: OnErrorFail, failed: true},
+		{policy: OnErrorDrop, dropped: true},
+	}
+
+	for _, tt := range tests {
+		p, err := NewComputeProcessor(map[string]interface{}{
+			"fields":   map[string]interface{}{"rate": "errors / total"},
+			"on_error": tt.policy,
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create processor: %v", tt.policy, err)
+		}
+
+		entry := models.NewLogEntry()
+		entry.AddField("errors", 3.0)
+		entry.AddField("total", 0.0)
+		results, err := p.Process(entry)
+
+		if (err != nil) != tt.failed {
+			t.Errorf("%s: expected error %v, got %v", tt.policy, tt.failed, err)
+		}
+		if !tt.failed && (len(results) == 0) != tt.dropped {
+			t.Errorf("%s: expected dropped %v, got %d entries", tt.policy, tt.dropped, len(results))
+		}
+		if entry.HasTag(ComputeFailureTag) != tt.tagged {
+			t.Errorf("%s: expected tagged %v, got %v", tt.policy, tt.tagged, entry.Tags)
+		}
+		if _, ok := entry.GetField("rate"); ok {
+			t.Errorf("%s: expected failed field to stay unset", tt.policy)
+		}
+	}
+}
+
+func TestComputeInvalidConfig(t *testing.T) {
+	invalid := map[string]map[string]interface{}{
+		"no fields":  {},
+		"expression": {"fields": map[string]interface{}{"a": "bytes /"}},
+		"on_error":   {"fields": map[string]interface{}{"a": "1"}, "on_error": "remove"},
+	}
+	for name, fields := range invalid {
+		if _, err := NewComputeProcessor(fields); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// ConditionalProcessor runs a processor only for entries matching a condition
+type ConditionalProcessor struct {
+	processor Processor
+	condition *expr.Expression
+}
+
+// NewConditionalProcessor wraps a processor with a condition expression
+func NewConditionalProcessor(processor Processor, condition string) (*ConditionalProcessor, error) {
+	compiled, err := expr.Compile(condition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition for %s: %w", processor.Name(), err)
+	}
+
+	return &ConditionalProcessor{
+		processor: processor,
+		condition: compiled,
+	}, nil
+}
+
+// Process applies the wrapped processor if the condition holds
+func (p *ConditionalProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	matched, err := p.condition.Match(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate condition: %w", err)
+	}
+	if !matched {
+		return []*models.LogEntry{entry}, nil
+	}
+	return p.processor.Process(entry)
+}
+
+// Name returns the wrapped processor name
+func (p *ConditionalProcessor) Name() string {
+	return p.processor.Name()
+}
+
+// Stats returns the wrapped processor counters, if it keeps any
+func (p *ConditionalProcessor) Stats() map[string]uint64 {
+	if stats, ok := p.processor.(StatsProvider); ok {
+		return stats.Stats()
+	}
+	return nil
+}
+
+// Start starts the wrapped processor if it emits entries asynchronously
+func (p *ConditionalProcessor) Start(emit func(*models.LogEntry)) {
+	if emitter, ok := p.processor.(Emitter); ok {
+		emitter.Start(emit)
+	}
+}
+
+// Stop stops the wrapped processor if it emits entries asynchronously
+func (p *ConditionalProcessor) Stop() {
+	if emitter, ok := p.processor.(Emitter); ok {
+		emitter.Stop()
+	}
+}
+
+// Close closes the wrapped processor if it holds resources
+func (p *ConditionalProcessor) Close() error {
+	if closer, ok := p.processor.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped processor
+func (p *ConditionalProcessor) Unwrap() Processor {
+	return p.processor
+}
@@ -0,0 +1,281 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"github.com/UmangDiyora/logpipeline/pkg/parser"
+)
+
+// ConvertFailureTag is added to entries with a field that could not be converted
+const ConvertFailureTag = "_convert_failure"
+
+// Error policies for convert and compute
+const (
+	OnErrorKeep   = "keep"
+	OnErrorTag    = "tag"
+	OnErrorRemove = "remove"
+	OnErrorFail   = "fail"
+	OnErrorDrop   = "drop"
+)
+
+// Conversion types
+const (
+	ConvertInt       = "int"
+	ConvertFloat     = "float"
+	ConvertBool      = "bool"
+	ConvertString    = "string"
+	ConvertDuration  = "duration"
+	ConvertBytes     = "bytes"
+	ConvertTimestamp = "timestamp"
+)
+
+// durationUnits maps duration_unit values to their length
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// byteUnits maps size suffixes to multipliers. SI suffixes are powers of
+// 1000; IEC suffixes and bare letters are powers of 1024.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"k":   1 << 10,
+	"m":   1 << 20,
+	"g":   1 << 30,
+	"t":   1 << 40,
+	"p":   1 << 50,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// conversion converts one field
+type conversion struct {
+	field  string
+	toType string
+}
+
+// ConvertProcessor converts field values to other types
+type ConvertProcessor struct {
+	conversions  []conversion
+	onError      string
+	durationUnit time.Duration
+	timestamps   *parser.TimestampResolver
+}
+
+// NewConvertProcessor creates a new convert processor.
+//
+// Supported options:
+//   - fields: map of field name to type (int, float, bool, string,
+//     duration, bytes or timestamp)
+//   - on_error: keep, tag, remove, fail or drop (default "tag")
+//   - duration_unit: unit of converted durations (default "ms")
+//   - formats: timestamp formats, as for the date processor
+//   - timezone: timezone for timestamps without one
+//
+// Durations such as "1m30s" become a float in duration_unit; bare numbers
+// are taken to be in that unit already. Byte sizes such as "12KB" or
+// "1.5GiB" become an integer number of bytes. Timestamps are stored as
+// RFC3339 strings.
+func NewConvertProcessor(fields map[string]interface{}) (*ConvertProcessor, error) {
+	p := &ConvertProcessor{
+		onError: stringValue(fields, "on_error", OnErrorTag),
+	}
+
+	switch p.onError {
+	case OnErrorKeep, OnErrorTag, OnErrorRemove, OnErrorFail, OnErrorDrop:
+	default:
+		return nil, fmt.Errorf("invalid convert on_error policy: %s", p.onError)
+	}
+
+	unit := stringValue(fields, "duration_unit", "ms")
+	duration, ok := durationUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("invalid duration_unit: %s", unit)
+	}
+	p.durationUnit = duration
+
+	types := stringMap(fields["fields"])
+	if len(types) == 0 {
+		return nil, fmt.Errorf("convert requires at least one field")
+	}
+	for field, toType := range types {
+		switch toType {
+		case ConvertInt, ConvertFloat, ConvertBool, ConvertString, ConvertDuration, ConvertBytes, ConvertTimestamp:
+		default:
+			return nil, fmt.Errorf("invalid conversion type for %s: %s", field, toType)
+		}
+		p.conversions = append(p.conversions, conversion{field: field, toType: toType})
+	}
+	sort.Slice(p.conversions, func(i, j int) bool {
+		return p.conversions[i].field < p.conversions[j].field
+	})
+
+	timestamps, err := parser.NewTimestampResolver(&parser.TimestampConfig{
+		Formats:  stringList(fields["formats"]),
+		Timezone: stringValue(fields, "timezone", ""),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid convert config: %w", err)
+	}
+	p.timestamps = timestamps
+
+	return p, nil
+}
+
+// Process converts the configured fields
+func (p *ConvertProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	for _, c := range p.conversions {
+		value, exists := entry.GetField(c.field)
+		if !exists || value == nil {
+			continue
+		}
+
+		converted, err := p.convert(value, c.toType)
+		if err == nil {
+			entry.AddField(c.field, converted)
+			continue
+		}
+
+		switch p.onError {
+		case OnErrorFail:
+			return nil, fmt.Errorf("failed to convert %s to %s: %w", c.field, c.toType, err)
+		case OnErrorDrop:
+			return nil, nil
+		case OnErrorRemove:
+			delete(entry.Fields, c.field)
+		case OnErrorTag:
+			if !entry.HasTag(ConvertFailureTag) {
+				entry.AddTag(ConvertFailureTag)
+			}
+		}
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// convert converts a value to the named type
+func (p *ConvertProcessor) convert(value interface{}, toType string) (interface{}, error) {
+	switch toType {
+	case ConvertInt:
+		return toInt(value)
+	case ConvertFloat:
+		f, ok := expr.ToNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("not a number: %q", expr.ToString(value))
+		}
+		return f, nil
+	case ConvertBool:
+		return toBool(value)
+	case ConvertString:
+		return expr.ToString(value), nil
+	case ConvertDuration:
+		return p.toDuration(value)
+	case ConvertBytes:
+		return toBytes(value)
+	case ConvertTimestamp:
+		ts, err := p.timestamps.Resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		return ts.Format(time.RFC3339Nano), nil
+	}
+	return nil, fmt.Errorf("unsupported conversion type: %s", toType)
+}
+
+// toInt converts a value to an int64, truncating fractions
+func toInt(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		s = strings.TrimSpace(s)
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return n, nil
+		}
+	}
+	f, ok := expr.ToNumber(value)
+	if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("not a number: %q", expr.ToString(value))
+	}
+	return int64(f), nil
+}
+
+// toBool converts a value to a bool. Numbers are true when non-zero.
+func toBool(value interface{}) (interface{}, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	if f, ok := expr.ToNumber(value); ok {
+		return f != 0, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(expr.ToString(value))) {
+	case "true", "t", "yes", "y", "on":
+		return true, nil
+	case "false", "f", "no", "n", "off", "":
+		return false, nil
+	}
+	return nil, fmt.Errorf("not a boolean: %q", expr.ToString(value))
+}
+
+// toDuration converts a duration string or number to a float in the
+// configured unit
+func (p *ConvertProcessor) toDuration(value interface{}) (interface{}, error) {
+	if d, ok := value.(time.Duration); ok {
+		return float64(d) / float64(p.durationUnit), nil
+	}
+	if f, ok := expr.ToNumber(value); ok {
+		return f, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(expr.ToString(value)))
+	if err != nil {
+		return nil, err
+	}
+	return float64(d) / float64(p.durationUnit), nil
+}
+
+// toBytes converts a size such as "12KB" or "1.5 GiB" to a number of bytes
+func toBytes(value interface{}) (interface{}, error) {
+	if f, ok := expr.ToNumber(value); ok {
+		return int64(f), nil
+	}
+
+	s := strings.TrimSpace(expr.ToString(value))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid byte size: %q", s)
+	}
+	multiplier, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return nil, fmt.Errorf("invalid byte size unit: %q", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// Name returns the processor name
+func (p *ConvertProcessor) Name() string {
+	return "convert"
+}
@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestConvertTypes(t *testing.T) {
+	p, err := NewConvertProcessor(map[string]interface{}{
+		"fields": map[string]interface{}{
+			"status":  "int",
+			"hex":     "int",
+			"ratio":   "float",
+			"cached":  "bool",
+			"enabled": "bool",
+			"code":    "string",
+			"latency": "duration",
+			"wait":    "duration",
+			"size":    "bytes",
+			"disk":    "bytes",
+			"at":      "timestamp",
+		},
+		"timezone": "UTC",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.AddField("status", " 404 ")
+	entry.AddField("hex", "0x1f")
+	entry.AddField("ratio", "0.25")
+	entry.AddField("cached", "yes")
+	entry.AddField("enabled", 0.0)
+	entry.AddField("code", 503.0)
+	entry.AddField("latency", "1m30s")
+	entry.AddField("wait", "250")
+	entry.AddField("size", "12KB")
+	entry.AddField("disk", "1.5 GiB")
+	entry.AddField("at", "2024-01-01 12:00:00")
+
+	if _, err := p.Process(entry); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"status":  int64(404),
+		"hex":     int64(31),
+		"ratio":   0.25,
+		"cached":  true,
+		"enabled": false,
+		"code":    "503",
+		"latency": 90000.0,
+		"wait":    250.0,
+		"size":    int64(12000),
+		"disk":    int64(1610612736),
+		"at":      "2024-01-01T12:00:00Z",
+	}
+	for field, value := range expected {
+		if got, _ := entry.GetField(field); got != value {
+			t.Errorf("Expected %s %v (%T), got %v (%T)", field, value, value, got, got)
+		}
+	}
+	if entry.HasTag(ConvertFailureTag) {
+		t.Error("Expected no failure tag")
+	}
+}
+
+func TestConvertDurationUnit(t *testing.T) {
+	p, err := NewConvertProcessor(map[string]interface{}{
+		"fields":        map[string]interface{}{"took": "duration"},
+		"duration_unit": "s",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.AddField("took", "1500ms")
+	p.Process(entry)
+
+	if took, _ := entry.GetField("took"); took != 1.5 {
+		t.Errorf("Expected 1.5 seconds, got %v", took)
+	}
+}
+
+func TestConvertOnError(t *testing.T) {
+	tests := []struct {
+		policy   string
+		dropped  bool
+		failed   bool
+		tagged   bool
+		expected interface{}
+	}{
+		{policy: OnErrorKeep, expected: "n/a"},
+		{policy: OnErrorTag, tagged: true, expected: "n/a"},
+		{policy: OnErrorRemove},
+		{policy: OnErrorFail, failed: true, expected: "n/a"},
+		{policy: OnErrorDrop, dropped: true, expected: "n/a"},
+	}
+
+	for _, tt := range tests {
+		p, err := NewConvertProcessor(map[string]interface{}{
+			"fields":   map[string]interface{}{"status": "int"},
+			"on_error": tt.policy,
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create processor: %v", tt.policy, err)
+		}
+
+		entry := models.NewLogEntry()
+		entry.AddField("status", "n/a")
+		results, err := p.Process(entry)
+
+		if (err != nil) != tt.failed {
+			t.Errorf("%s: expected error %v, got %v", tt.policy, tt.failed, err)
+		}
+		if !tt.failed && (len(results) == 0) != tt.dropped {
+			t.Errorf("%s: expected dropped %v, got %d entries", tt.policy, tt.dropped, len(results))
+		}
+		if entry.HasTag(ConvertFailureTag) != tt.tagged {
+			t.Errorf("%s: expected tagged %v, got %v", tt.policy, tt.tagged, entry.Tags)
+		}
+		if value, _ := entry.GetField("status"); value != tt.expected {
+			t.Errorf("%s: expected status %v, got %v", tt.policy, tt.expected, value)
+		}
+	}
+}
+
+func TestConvertInvalidValues(t *testing.T) {
+	invalid := map[string]interface{}{
+		"int":       "12abc",
+		"float":     "abc",
+		"bool":      "maybe",
+		"duration":  "soon",
+		"bytes":     "12 parsecs",
+		"timestamp": "yesterday",
+	}
+
+	for toType, value := range invalid {
+		p, err := NewConvertProcessor(map[string]interface{}{
+			"fields": map[string]interface{}{"value": toType},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create processor: %v", toType, err)
+		}
+
+		entry := models.NewLogEntry()
+		entry.AddField("value", value)
+		p.Process(entry)
+
+		if !entry.HasTag(ConvertFailureTag) {
+			t.Errorf("%s: expected failure tag for %q", toType, value)
+		}
+	}
+}
+
+func TestConvertInvalidConfig(t *testing.T) {
+	invalid := map[string]map[string]interface{}{
+		"no fields":     {},
+		"unknown type":  {"fields": map[string]interface{}{"a": "uuid"}},
+		"on_error":      {"fields": map[string]interface{}{"a": "int"}, "on_error": "ignore"},
+		"duration_unit": {"fields": map[string]interface{}{"a": "duration"}, "duration_unit": "days"},
+		"timezone":      {"fields": map[string]interface{}{"a": "timestamp"}, "timezone": "Mars/Olympus"},
+	}
+	for name, fields := range invalid {
+		if _, err := NewConvertProcessor(fields); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"github.com/UmangDiyora/logpipeline/pkg/parser"
+)
+
+// DateParseFailureTag is added to entries whose date field could not be parsed
+const DateParseFailureTag = "_date_parse_failure"
+
+// DateProcessor parses a field into the entry timestamp
+type DateProcessor struct {
+	field      string
+	target     string
+	timestamps *parser.TimestampResolver
+}
+
+// NewDateProcessor creates a new date processor.
+//
+// Supported options: field (default "timestamp"), formats, timezone and
+// target. When target is empty the parsed time replaces entry.Timestamp,
+// otherwise it is stored in the named field as an RFC3339 string.
+func NewDateProcessor(fields map[string]interface{}) (*DateProcessor, error) {
+	timestamps, err := parser.NewTimestampResolver(&parser.TimestampConfig{
+		Formats:  stringList(fields["formats"]),
+		Timezone: stringValue(fields, "timezone", ""),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid date config: %w", err)
+	}
+
+	return &DateProcessor{
+		field:      stringValue(fields, "field", "timestamp"),
+		target:     stringValue(fields, "target", ""),
+		timestamps: timestamps,
+	}, nil
+}
+
+// Process parses the configured field into a timestamp
+func (p *DateProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	value, exists := entry.GetField(p.field)
+	if !exists {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	ts, err := p.timestamps.Resolve(value)
+	if err != nil {
+		if !entry.HasTag(DateParseFailureTag) {
+			entry.AddTag(DateParseFailureTag)
+		}
+		return []*models.LogEntry{entry}, nil
+	}
+
+	if p.target == "" {
+		entry.Timestamp = ts
+	} else {
+		entry.AddField(p.target, ts.Format(time.RFC3339Nano))
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// Name returns the processor name
+func (p *DateProcessor) Name() string {
+	return "date"
+}
@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestDateReplacesTimestamp(t *testing.T) {
+	p, err := NewDateProcessor(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.AddField("timestamp", "2024-01-01T12:00:00Z")
+	results, err := p.Process(entry)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(results))
+	}
+
+	expected := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, entry.Timestamp)
+	}
+}
+
+func TestDateFormatsAndTimezone(t *testing.T) {
+	p, err := NewDateProcessor(map[string]interface{}{
+		"field":    "logged_at",
+		"formats":  []interface{}{"%d/%m/%Y %H:%M:%S"},
+		"timezone": "Europe/Berlin",
+		"target":   "logged_at_utc",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	original := entry.Timestamp
+	entry.AddField("logged_at", "15/07/2024 14:30:00")
+	p.Process(entry)
+
+	parsed, _ := entry.GetField("logged_at_utc")
+	if parsed != "2024-07-15T14:30:00+02:00" {
+		t.Errorf("Expected target field in RFC3339, got %v", parsed)
+	}
+	if !entry.Timestamp.Equal(original) {
+		t.Errorf("Expected timestamp to be kept when a target is set, got %v", entry.Timestamp)
+	}
+}
+
+func TestDateEpochPrecision(t *testing.T) {
+	p, err := NewDateProcessor(map[string]interface{}{"formats": []interface{}{"UNIX_NS"}})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	for _, value := range []interface{}{"1704110400123456789", int64(1704110400123456789)} {
+		entry := models.NewLogEntry()
+		entry.AddField("timestamp", value)
+		p.Process(entry)
+
+		expected := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+		if !entry.Timestamp.Equal(expected) {
+			t.Errorf("%v: expected %v, got %v", value, expected, entry.Timestamp)
+		}
+	}
+}
+
+func TestDateParseFailure(t *testing.T) {
+	p, err := NewDateProcessor(map[string]interface{}{"formats": []interface{}{"2006-01-02"}})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	original := entry.Timestamp
+	entry.AddField("timestamp", "2024")
+	p.Process(entry)
+	p.Process(entry)
+
+	if !entry.Timestamp.Equal(original) {
+		t.Errorf("Expected timestamp to be kept on failure, got %v", entry.Timestamp)
+	}
+	tags := 0
+	for _, tag := range entry.Tags {
+		if tag == DateParseFailureTag {
+			tags++
+		}
+	}
+	if tags != 1 {
+		t.Errorf("Expected one failure tag, got %v", entry.Tags)
+	}
+
+	missing := models.NewLogEntry()
+	p.Process(missing)
+	if missing.HasTag(DateParseFailureTag) {
+		t.Error("Expected entry without the field to pass untagged")
+	}
+}
+
+func TestDateInvalidConfig(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"timezone": {"timezone": "Not/AZone"},
+		"format":   {"formats": []interface{}{"%Q"}},
+	}
+
+	for name, fields := range tests {
+		if _, err := NewDateProcessor(fields); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
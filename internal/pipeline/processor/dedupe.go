@@ -0,0 +1,255 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Message key modes for deduplication
+const (
+	DedupeTemplate = "template"
+	DedupeExact    = "exact"
+	DedupeNone     = "none"
+)
+
+// DedupeSummaryTag marks summary entries emitted by the dedupe processor
+const DedupeSummaryTag = "_dedupe_summary"
+
+// templatePatterns replace variable parts of a message with placeholders,
+// most specific first
+var templatePatterns = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{8,}\b`), "<hex>"},
+	{regexp.MustCompile(`"[^"]*"|'[^']*'`), "<str>"},
+	{regexp.MustCompile(`\d+(?:\.\d+)?`), "<num>"},
+}
+
+// normalizeTemplate reduces a message to its template so that lines which
+// differ only in IDs, addresses or numbers share a key
+func normalizeTemplate(message string) string {
+	for _, p := range templatePatterns {
+		message = p.re.ReplaceAllString(message, p.placeholder)
+	}
+	return message
+}
+
+// DedupeProcessor collapses repeated messages within a window. The first
+// occurrence passes through; repeats are dropped and reported in a summary
+// entry when the window closes.
+type DedupeProcessor struct {
+	fields  []string
+	message string
+	window  time.Duration
+	maxKeys int
+
+	mu     sync.Mutex
+	groups map[string]*dedupeGroup
+	emit   func(*models.LogEntry)
+	done   chan struct{}
+	wg     sync.WaitGroup
+	now    func() time.Time
+
+	suppressed uint64
+	summaries  uint64
+}
+
+// dedupeGroup tracks the repeats of one key within a window
+type dedupeGroup struct {
+	first     *models.LogEntry
+	count     uint64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// NewDedupeProcessor creates a new dedupe processor.
+//
+// Supported options:
+//   - fields: fields that make up the key (default service and level)
+//   - message: how the message contributes to the key: template (default)
+//     normalizes numbers, IDs and addresses, exact uses it verbatim and
+//     none ignores it
+//   - window: how long repeats are collapsed (default 1m)
+//   - max_keys: distinct keys tracked at once (default 10000); entries
+//     beyond the limit pass through
+//
+// Summaries copy the first occurrence and add repeat_count (the number of
+// repeats dropped), first_seen and last_seen.
+func NewDedupeProcessor(fields map[string]interface{}) (*DedupeProcessor, error) {
+	window, err := durationValue(fields["window"], time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dedupe window: %w", err)
+	}
+
+	p := &DedupeProcessor{
+		fields:  stringList(fields["fields"]),
+		message: stringValue(fields, "message", DedupeTemplate),
+		window:  window,
+		maxKeys: 10000,
+		groups:  make(map[string]*dedupeGroup),
+		now:     time.Now,
+	}
+
+	if _, ok := fields["fields"]; !ok {
+		p.fields = []string{"service", "level"}
+	}
+
+	switch p.message {
+	case DedupeTemplate, DedupeExact, DedupeNone:
+	default:
+		return nil, fmt.Errorf("invalid dedupe message mode: %s", p.message)
+	}
+
+	if maxKeys, ok := floatValue(fields["max_keys"]); ok && maxKeys > 0 {
+		p.maxKeys = int(maxKeys)
+	}
+
+	return p, nil
+}
+
+// Process passes the first occurrence of a key and drops repeats
+func (p *DedupeProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	key := p.key(entry)
+	now := p.now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if group, exists := p.groups[key]; exists {
+		group.count++
+		group.lastSeen = now
+		atomic.AddUint64(&p.suppressed, 1)
+		return nil, nil
+	}
+
+	if len(p.groups) >= p.maxKeys {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	p.groups[key] = &dedupeGroup{
+		first:     entry.Clone(),
+		firstSeen: now,
+		lastSeen:  now,
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// key builds the deduplication key for an entry
+func (p *DedupeProcessor) key(entry *models.LogEntry) string {
+	var b strings.Builder
+	for _, field := range p.fields {
+		value, _ := expr.Lookup(entry, field)
+		b.WriteString(expr.ToString(value))
+		b.WriteByte(0)
+	}
+
+	switch p.message {
+	case DedupeTemplate:
+		b.WriteString(normalizeTemplate(entry.Message))
+	case DedupeExact:
+		b.WriteString(entry.Message)
+	}
+	return b.String()
+}
+
+// Start begins closing expired windows. Summaries are passed to emit.
+func (p *DedupeProcessor) Start(emit func(*models.LogEntry)) {
+	p.emit = emit
+	p.done = make(chan struct{})
+
+	interval := p.window / 4
+	if interval > time.Second {
+		interval = time.Second
+	}
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.flush(false)
+			}
+		}
+	}()
+}
+
+// Stop closes all open windows and stops the background goroutine
+func (p *DedupeProcessor) Stop() {
+	if p.done != nil {
+		close(p.done)
+		p.wg.Wait()
+		p.done = nil
+	}
+	p.flush(true)
+}
+
+// flush closes expired windows, or all of them if force is set, and emits
+// a summary for every group that saw repeats
+func (p *DedupeProcessor) flush(force bool) {
+	now := p.now()
+	summaries := make([]*models.LogEntry, 0)
+
+	p.mu.Lock()
+	for key, group := range p.groups {
+		if !force && now.Sub(group.firstSeen) < p.window {
+			continue
+		}
+		delete(p.groups, key)
+		if group.count > 0 {
+			summaries = append(summaries, summarize(group))
+		}
+	}
+	p.mu.Unlock()
+
+	if p.emit == nil {
+		return
+	}
+	for _, summary := range summaries {
+		atomic.AddUint64(&p.summaries, 1)
+		p.emit(summary)
+	}
+}
+
+// summarize builds the summary entry for a group
+func summarize(group *dedupeGroup) *models.LogEntry {
+	summary := group.first.Clone()
+	summary.ID = group.first.ID + "-summary"
+	summary.Timestamp = group.lastSeen
+	summary.AddField("repeat_count", group.count)
+	summary.AddField("first_seen", group.firstSeen.Format(time.RFC3339Nano))
+	summary.AddField("last_seen", group.lastSeen.Format(time.RFC3339Nano))
+	summary.AddTag(DedupeSummaryTag)
+	return summary
+}
+
+// Stats returns the number of suppressed repeats and emitted summaries
+func (p *DedupeProcessor) Stats() map[string]uint64 {
+	return map[string]uint64{
+		"suppressed": atomic.LoadUint64(&p.suppressed),
+		"summaries":  atomic.LoadUint64(&p.summaries),
+	}
+}
+
+// Name returns the processor name
+func (p *DedupeProcessor) Name() string {
+	return "dedupe"
+}
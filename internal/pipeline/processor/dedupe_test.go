@@ -0,0 +1,234 @@
+package processor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// testClock is a settable clock shared with the flush goroutine
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// summaryCollector records the entries emitted by a processor
+type summaryCollector struct {
+	mu      sync.Mutex
+	entries []*models.LogEntry
+}
+
+func (c *summaryCollector) emit(entry *models.LogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, entry)
+}
+
+func (c *summaryCollector) get() []*models.LogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*models.LogEntry(nil), c.entries...)
+}
+
+func newDedupe(t *testing.T, fields map[string]interface{}) (*DedupeProcessor, *testClock, *summaryCollector) {
+	t.Helper()
+
+	p, err := NewDedupeProcessor(fields)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	clock := newTestClock()
+	p.now = clock.Now
+
+	collector := &summaryCollector{}
+	p.Start(collector.emit)
+	t.Cleanup(p.Stop)
+	return p, clock, collector
+}
+
+func dedupeEntry(service, message string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Service = service
+	entry.Level = models.LogLevelError
+	entry.Message = message
+	return entry
+}
+
+// passed reports whether the processor let the entry through
+func passed(t *testing.T, p Processor, entry *models.LogEntry) bool {
+	t.Helper()
+
+	results, err := p.Process(entry)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	return len(results) == 1
+}
+
+func TestDedupeTemplate(t *testing.T) {
+	p, _, _ := newDedupe(t, map[string]interface{}{})
+
+	tests := []struct {
+		service string
+		message string
+		passes  bool
+	}{
+		{"api", "user 17 failed login from 10.0.0.1", true},
+		{"api", "user 42 failed login from 10.0.0.2", false},
+		{"api", `order 550e8400-e29b-41d4-a716-446655440000 rejected: "card declined"`, true},
+		{"api", `order 6ba7b810-9dad-11d1-80b4-00c04fd430c8 rejected: "no stock"`, false},
+		{"api", "connection reset", true},
+		{"web", "user 17 failed login from 10.0.0.1", true},
+	}
+	for _, tt := range tests {
+		if got := passed(t, p, dedupeEntry(tt.service, tt.message)); got != tt.passes {
+			t.Errorf("%s %q: expected pass %v, got %v", tt.service, tt.message, tt.passes, got)
+		}
+	}
+}
+
+func TestDedupeExactAndFields(t *testing.T) {
+	exact, _, _ := newDedupe(t, map[string]interface{}{"message": "exact"})
+	passed(t, exact, dedupeEntry("api", "user 17 logged in"))
+	if !passed(t, exact, dedupeEntry("api", "user 18 logged in")) {
+		t.Error("Expected exact mode to tell numbers apart")
+	}
+	if passed(t, exact, dedupeEntry("api", "user 18 logged in")) {
+		t.Error("Expected exact repeat to be dropped")
+	}
+
+	byFields, _, _ := newDedupe(t, map[string]interface{}{
+		"message": "none",
+		"fields":  []interface{}{"service", "fields.code"},
+	})
+	first := dedupeEntry("api", "timeout")
+	first.AddField("code", 504)
+	second := dedupeEntry("api", "gateway gave up")
+	second.AddField("code", 504)
+	other := dedupeEntry("api", "timeout")
+	other.AddField("code", 503)
+
+	passed(t, byFields, first)
+	if passed(t, byFields, second) {
+		t.Error("Expected same fields to be a repeat whatever the message")
+	}
+	if !passed(t, byFields, other) {
+		t.Error("Expected different field value to pass")
+	}
+}
+
+func TestDedupeWindowSummary(t *testing.T) {
+	p, clock, collector := newDedupe(t, map[string]interface{}{"window": "1m"})
+
+	first := dedupeEntry("api", "disk 91% full")
+	first.AddField("host_group", "db")
+	passed(t, p, first)
+	firstSeen := clock.Now()
+
+	clock.Advance(10 * time.Second)
+	passed(t, p, dedupeEntry("api", "disk 92% full"))
+	clock.Advance(20 * time.Second)
+	passed(t, p, dedupeEntry("api", "disk 95% full"))
+	lastSeen := clock.Now()
+
+	// Without repeats no summary is emitted
+	passed(t, p, dedupeEntry("api", "backup done"))
+
+	// The window is still open
+	p.flush(false)
+	if n := len(collector.get()); n != 0 {
+		t.Fatalf("Expected no summary before the window closes, got %d", n)
+	}
+
+	clock.Advance(31 * time.Second)
+	p.flush(false)
+
+	summaries := collector.get()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary, got %d", len(summaries))
+	}
+	summary := summaries[0]
+	if count, _ := summary.GetField("repeat_count"); count != uint64(2) {
+		t.Errorf("Expected repeat_count 2, got %v", count)
+	}
+	if seen, _ := summary.GetField("first_seen"); seen != firstSeen.Format(time.RFC3339Nano) {
+		t.Errorf("Expected first_seen %s, got %v", firstSeen.Format(time.RFC3339Nano), seen)
+	}
+	if seen, _ := summary.GetField("last_seen"); seen != lastSeen.Format(time.RFC3339Nano) {
+		t.Errorf("Expected last_seen %s, got %v", lastSeen.Format(time.RFC3339Nano), seen)
+	}
+	if !summary.Timestamp.Equal(lastSeen) {
+		t.Errorf("Expected summary at last_seen, got %v", summary.Timestamp)
+	}
+	if summary.Message != "disk 91% full" || !summary.HasTag(DedupeSummaryTag) {
+		t.Errorf("Expected tagged copy of the first occurrence, got %q %v", summary.Message, summary.Tags)
+	}
+	if group, _ := summary.GetField("host_group"); group != "db" {
+		t.Errorf("Expected fields of the first occurrence, got %v", group)
+	}
+
+	// A new window starts with the next occurrence
+	if !passed(t, p, dedupeEntry("api", "disk 96% full")) {
+		t.Error("Expected first occurrence of a new window to pass")
+	}
+
+	stats := p.Stats()
+	if stats["suppressed"] != 2 || stats["summaries"] != 1 {
+		t.Errorf("Expected 2 suppressed and 1 summary, got %v", stats)
+	}
+}
+
+func TestDedupeStopFlushes(t *testing.T) {
+	p, _, collector := newDedupe(t, map[string]interface{}{"window": "1h"})
+
+	for i := 0; i < 3; i++ {
+		passed(t, p, dedupeEntry("api", "retrying"))
+	}
+	p.Stop()
+
+	summaries := collector.get()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected open window to be summarized on stop, got %d", len(summaries))
+	}
+	if count, _ := summaries[0].GetField("repeat_count"); count != uint64(2) {
+		t.Errorf("Expected repeat_count 2, got %v", count)
+	}
+}
+
+func TestDedupeMaxKeys(t *testing.T) {
+	p, _, _ := newDedupe(t, map[string]interface{}{"max_keys": 1, "message": "exact"})
+
+	passed(t, p, dedupeEntry("api", "a"))
+	for i := 0; i < 2; i++ {
+		if !passed(t, p, dedupeEntry("api", "b")) {
+			t.Error("Expected entries beyond max_keys to pass")
+		}
+	}
+}
+
+func TestDedupeInvalidConfig(t *testing.T) {
+	if _, err := NewDedupeProcessor(map[string]interface{}{"window": "soon"}); err == nil {
+		t.Error("Expected error for invalid window")
+	}
+	if _, err := NewDedupeProcessor(map[string]interface{}{"message": "fuzzy"}); err == nil {
+		t.Error("Expected error for invalid message mode")
+	}
+}
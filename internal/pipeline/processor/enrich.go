@@ -0,0 +1,436 @@
+package processor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// reloadDelay batches the events editors generate while saving a file
+const reloadDelay = 100 * time.Millisecond
+
+// lookupTable is an immutable, indexed copy of a lookup file
+type lookupTable struct {
+	exact map[string]map[string]interface{}
+	cidrs []*cidrRow
+}
+
+// cidrRow is a row with at least one CIDR key, matched by scanning
+type cidrRow struct {
+	keys    []string
+	nets    []*netip.Prefix
+	bits    int
+	columns map[string]interface{}
+}
+
+// matches reports whether the row matches the given key values
+func (r *cidrRow) matches(values []string) bool {
+	for i, value := range values {
+		if r.nets[i] == nil {
+			if r.keys[i] != value {
+				return false
+			}
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil || !r.nets[i].Contains(addr.Unmap()) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup finds the row for the given key values. Exact matches win over
+// CIDR matches, and narrower networks over wider ones.
+func (t *lookupTable) lookup(values []string) (map[string]interface{}, bool) {
+	if row, ok := t.exact[compositeKey(values)]; ok {
+		return row, true
+	}
+	for _, row := range t.cidrs {
+		if row.matches(values) {
+			return row.columns, true
+		}
+	}
+	return nil, false
+}
+
+// compositeKey joins key values into a map key
+func compositeKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+// EnrichProcessor adds columns from a lookup file to matching entries and
+// reloads the file when it changes
+type EnrichProcessor struct {
+	path      string
+	format    string
+	fields    []string
+	keys      []string
+	columns   []string
+	prefix    string
+	overwrite bool
+
+	table   atomic.Pointer[lookupTable]
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	hits    uint64
+	misses  uint64
+	reloads uint64
+}
+
+// NewEnrichProcessor creates a new enrich processor.
+//
+// Supported options:
+//   - file: path of the lookup file (required)
+//   - format: csv, json or yaml (default from the file extension)
+//   - fields: entry fields to match on, e.g. service or host (required)
+//   - keys: table columns matched against fields (default the field names);
+//     key values that are CIDR ranges match any address inside them
+//   - columns: columns to add (default all non-key columns)
+//   - prefix: prefix for the added field names
+//   - overwrite: replace fields that are already set (default false)
+//   - watch: reload the file when it changes (default true)
+//
+// CSV files need a header row. JSON and YAML files hold either a list of
+// rows or a map from the value of the first key to its row.
+func NewEnrichProcessor(fields map[string]interface{}) (*EnrichProcessor, error) {
+	p := &EnrichProcessor{
+		path:    stringValue(fields, "file", ""),
+		fields:  stringList(fields["fields"]),
+		keys:    stringList(fields["keys"]),
+		columns: stringList(fields["columns"]),
+		prefix:  stringValue(fields, "prefix", ""),
+	}
+	p.overwrite, _ = fields["overwrite"].(bool)
+
+	if p.path == "" {
+		return nil, fmt.Errorf("enrich requires a file")
+	}
+	if len(p.fields) == 0 {
+		return nil, fmt.Errorf("enrich requires at least one field")
+	}
+	if len(p.keys) == 0 {
+		p.keys = p.fields
+	}
+	if len(p.keys) != len(p.fields) {
+		return nil, fmt.Errorf("enrich keys must match fields one to one")
+	}
+
+	p.format = stringValue(fields, "format", strings.TrimPrefix(strings.ToLower(filepath.Ext(p.path)), "."))
+	switch p.format {
+	case "csv", "json":
+	case "yaml", "yml":
+		p.format = "yaml"
+	default:
+		return nil, fmt.Errorf("unsupported lookup format: %s", p.format)
+	}
+
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	if watch, ok := fields["watch"].(bool); !ok || watch {
+		if err := p.watch(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Process adds the columns of the matching row to the entry
+func (p *EnrichProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	values := make([]string, len(p.fields))
+	for i, field := range p.fields {
+		value, exists := expr.Lookup(entry, field)
+		if !exists {
+			atomic.AddUint64(&p.misses, 1)
+			return []*models.LogEntry{entry}, nil
+		}
+		values[i] = expr.ToString(value)
+	}
+
+	row, found := p.table.Load().lookup(values)
+	if !found {
+		atomic.AddUint64(&p.misses, 1)
+		return []*models.LogEntry{entry}, nil
+	}
+	atomic.AddUint64(&p.hits, 1)
+
+	for column, value := range row {
+		name := p.prefix + column
+		if _, exists := entry.GetField(name); exists && !p.overwrite {
+			continue
+		}
+		entry.AddField(name, value)
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// reload reads the lookup file and swaps in the new table
+func (p *EnrichProcessor) reload() error {
+	rows, err := p.readRows()
+	if err != nil {
+		return fmt.Errorf("failed to load lookup file %s: %w", p.path, err)
+	}
+
+	table, err := p.buildTable(rows)
+	if err != nil {
+		return fmt.Errorf("failed to load lookup file %s: %w", p.path, err)
+	}
+
+	p.table.Store(table)
+	atomic.AddUint64(&p.reloads, 1)
+	return nil
+}
+
+// readRows decodes the lookup file into rows
+func (p *EnrichProcessor) readRows() ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.format == "csv" {
+		return readCSVRows(data)
+	}
+
+	var doc interface{}
+	if p.format == "json" {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := doc.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("row %d is not an object", i)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case map[string]interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for key, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("row %q is not an object", key)
+			}
+			withKey := make(map[string]interface{}, len(row)+1)
+			for column, value := range row {
+				withKey[column] = value
+			}
+			withKey[p.keys[0]] = key
+			rows = append(rows, withKey)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("expected a list or map of rows")
+}
+
+// readCSVRows decodes CSV data with a header row
+func readCSVRows(data []byte) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// buildTable indexes rows by their key columns
+func (p *EnrichProcessor) buildTable(rows []map[string]interface{}) (*lookupTable, error) {
+	table := &lookupTable{exact: make(map[string]map[string]interface{})}
+
+	for i, row := range rows {
+		keys := make([]string, len(p.keys))
+		nets := make([]*netip.Prefix, len(p.keys))
+		isCIDR := false
+		bits := 0
+
+		for j, column := range p.keys {
+			value, ok := row[column]
+			if !ok {
+				return nil, fmt.Errorf("row %d has no %s column", i+1, column)
+			}
+			keys[j] = expr.ToString(value)
+			if strings.Contains(keys[j], "/") {
+				prefix, err := netip.ParsePrefix(keys[j])
+				if err != nil {
+					return nil, fmt.Errorf("row %d has invalid CIDR %q: %w", i+1, keys[j], err)
+				}
+				prefix = prefix.Masked()
+				nets[j] = &prefix
+				bits += prefix.Bits()
+				isCIDR = true
+			}
+		}
+
+		columns := p.selectColumns(row)
+		if isCIDR {
+			table.cidrs = append(table.cidrs, &cidrRow{keys: keys, nets: nets, bits: bits, columns: columns})
+		} else {
+			table.exact[compositeKey(keys)] = columns
+		}
+	}
+
+	// Most specific networks first
+	sort.SliceStable(table.cidrs, func(i, j int) bool {
+		return table.cidrs[i].bits > table.cidrs[j].bits
+	})
+	return table, nil
+}
+
+// selectColumns picks the columns to add from a row
+func (p *EnrichProcessor) selectColumns(row map[string]interface{}) map[string]interface{} {
+	columns := make(map[string]interface{})
+	if len(p.columns) > 0 {
+		for _, column := range p.columns {
+			if value, ok := row[column]; ok {
+				columns[column] = value
+			}
+		}
+		return columns
+	}
+
+	for column, value := range row {
+		isKey := false
+		for _, key := range p.keys {
+			if key == column {
+				isKey = true
+				break
+			}
+		}
+		if !isKey {
+			columns[column] = value
+		}
+	}
+	return columns
+}
+
+// watch reloads the table when the lookup file changes. The directory is
+// watched so that files replaced by rename are picked up.
+func (p *EnrichProcessor) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(p.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", p.path, err)
+	}
+
+	p.watcher = watcher
+	p.done = make(chan struct{})
+	target := filepath.Clean(p.path)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != target {
+					continue
+				}
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+					timer.Reset(reloadDelay)
+				}
+
+			case <-timer.C:
+				// A failed reload keeps the previous table
+				if err := p.reload(); err != nil {
+					fmt.Printf("warning: %v\n", err)
+				}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("watcher error: %v\n", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close stops watching the lookup file
+func (p *EnrichProcessor) Close() error {
+	if p.watcher == nil {
+		return nil
+	}
+	close(p.done)
+	p.wg.Wait()
+	err := p.watcher.Close()
+	p.watcher = nil
+	return err
+}
+
+// Stats returns lookup hits and misses and the number of loads
+func (p *EnrichProcessor) Stats() map[string]uint64 {
+	return map[string]uint64{
+		"hits":    atomic.LoadUint64(&p.hits),
+		"misses":  atomic.LoadUint64(&p.misses),
+		"reloads": atomic.LoadUint64(&p.reloads),
+	}
+}
+
+// Name returns the processor name
+func (p *EnrichProcessor) Name() string {
+	return "enrich"
+}
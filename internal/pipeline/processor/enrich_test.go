@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func writeLookup(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write lookup file: %v", err)
+	}
+	return path
+}
+
+func newEnrich(t *testing.T, fields map[string]interface{}) *EnrichProcessor {
+	t.Helper()
+
+	p, err := NewEnrichProcessor(fields)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func enrichService(t *testing.T, p *EnrichProcessor, service string) *models.LogEntry {
+	t.Helper()
+
+	entry := models.NewLogEntry()
+	entry.Service = service
+	if _, err := p.Process(entry); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	return entry
+}
+
+func TestEnrichFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"owners.csv":  "service, team, tier\ncheckout, payments, 1\nsearch, discovery, 2\n",
+		"owners.json": `[{"service": "checkout", "team": "payments", "tier": 1}]`,
+		"owners.yaml": "checkout:\n  team: payments\n  tier: 1\n",
+	}
+
+	for name, content := range files {
+		p := newEnrich(t, map[string]interface{}{
+			"file":   writeLookup(t, dir, name, content),
+			"fields": []interface{}{"service"},
+			"prefix": "owner.",
+			"watch":  false,
+		})
+
+		entry := enrichService(t, p, "checkout")
+		if team, _ := entry.GetField("owner.team"); team != "payments" {
+			t.Errorf("%s: expected owner.team payments, got %v", name, team)
+		}
+		if _, ok := entry.GetField("owner.service"); ok {
+			t.Errorf("%s: expected key column not to be added", name)
+		}
+
+		missing := enrichService(t, p, "billing")
+		if _, ok := missing.GetField("owner.team"); ok {
+			t.Errorf("%s: expected no columns for unknown service", name)
+		}
+	}
+}
+
+func TestEnrichCIDR(t *testing.T) {
+	path := writeLookup(t, t.TempDir(), "networks.csv",
+		"client_ip, zone\n10.0.0.0/8, internal\n10.1.0.0/16, office\n203.0.113.7, partner\n")
+	p := newEnrich(t, map[string]interface{}{"file": path, "fields": []interface{}{"client_ip"}, "watch": false})
+
+	tests := map[string]interface{}{
+		"10.2.3.4":    "internal",
+		"10.1.2.3":    "office",
+		"203.0.113.7": "partner",
+		"192.0.2.1":   nil,
+	}
+	for ip, expected := range tests {
+		entry := models.NewLogEntry()
+		entry.AddField("client_ip", ip)
+		p.Process(entry)
+		if zone, _ := entry.GetField("zone"); zone != expected {
+			t.Errorf("%s: expected zone %v, got %v", ip, expected, zone)
+		}
+	}
+
+	stats := p.Stats()
+	if stats["hits"] != 3 || stats["misses"] != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %v", stats)
+	}
+}
+
+func TestEnrichColumnsAndOverwrite(t *testing.T) {
+	path := writeLookup(t, t.TempDir(), "owners.csv", "service,team,pager\ncheckout,payments,oncall-pay\n")
+
+	keep := newEnrich(t, map[string]interface{}{
+		"file": path, "fields": []interface{}{"service"}, "columns": []interface{}{"team"}, "watch": false,
+	})
+	entry := models.NewLogEntry()
+	entry.Service = "checkout"
+	entry.AddField("team", "platform")
+	keep.Process(entry)
+	if team, _ := entry.GetField("team"); team != "platform" {
+		t.Errorf("Expected existing field to be kept, got %v", team)
+	}
+	if _, ok := entry.GetField("pager"); ok {
+		t.Error("Expected unselected column not to be added")
+	}
+
+	overwrite := newEnrich(t, map[string]interface{}{
+		"file": path, "fields": []interface{}{"service"}, "overwrite": true, "watch": false,
+	})
+	overwrite.Process(entry)
+	if team, _ := entry.GetField("team"); team != "payments" {
+		t.Errorf("Expected field to be overwritten, got %v", team)
+	}
+}
+
+func TestEnrichReloadsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLookup(t, dir, "owners.csv", "service,team\ncheckout,payments\n")
+	p := newEnrich(t, map[string]interface{}{"file": path, "fields": []interface{}{"service"}})
+
+	// Replace the file by rename, as editors and config management do
+	writeLookup(t, dir, "owners.csv.tmp", "service,team\ncheckout,billing\n")
+	if err := os.Rename(filepath.Join(dir, "owners.csv.tmp"), path); err != nil {
+		t.Fatalf("Failed to replace lookup file: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry := enrichService(t, p, "checkout")
+		if team, _ := entry.GetField("team"); team == "billing" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the lookup file to reload")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// A broken file keeps the previous table
+	writeLookup(t, dir, "owners.csv", "service,team\n\"checkout,broken\n")
+	time.Sleep(5 * reloadDelay)
+	if team, _ := enrichService(t, p, "checkout").GetField("team"); team != "billing" {
+		t.Errorf("Expected previous table after a failed reload, got %v", team)
+	}
+}
+
+func TestEnrichInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := writeLookup(t, dir, "owners.csv", "service,team\ncheckout,payments\n")
+	badCIDR := writeLookup(t, dir, "networks.csv", "ip,zone\n10.0.0.0/33,internal\n")
+
+	invalid := map[string]map[string]interface{}{
+		"no file":        {"fields": []interface{}{"service"}},
+		"missing file":   {"file": filepath.Join(dir, "none.csv"), "fields": []interface{}{"service"}},
+		"no fields":      {"file": csvPath},
+		"keys mismatch":  {"file": csvPath, "fields": []interface{}{"service"}, "keys": []interface{}{"a", "b"}},
+		"unknown format": {"file": csvPath, "fields": []interface{}{"service"}, "format": "xml"},
+		"missing column": {"file": csvPath, "fields": []interface{}{"host"}},
+		"invalid cidr":   {"file": badCIDR, "fields": []interface{}{"ip"}},
+	}
+	for name, fields := range invalid {
+		fields["watch"] = false
+		if _, err := NewEnrichProcessor(fields); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
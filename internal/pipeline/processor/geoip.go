@@ -0,0 +1,275 @@
+package processor
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/lru"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// GeoIPFailureTag is added to entries whose address field is not an IP
+const GeoIPFailureTag = "_geoip_lookup_failure"
+
+// carrierGradeNAT is the shared address space of RFC 6598, which is not
+// covered by netip.Addr.IsPrivate
+var carrierGradeNAT = netip.MustParsePrefix("100.64.0.0/10")
+
+// cityRecord holds the parts of a GeoIP2/GeoLite2 City or Country record
+// that are added to entries
+type cityRecord struct {
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+}
+
+// asnRecord is a GeoLite2 ASN record
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// GeoIPProcessor adds location and network owner details for an IP
+// address from local MaxMind databases
+type GeoIPProcessor struct {
+	field    string
+	target   string
+	language string
+	networks []netip.Prefix
+
+	city  *maxminddb.Reader
+	asn   *maxminddb.Reader
+	cache *lru.Cache[netip.Addr, map[string]interface{}]
+
+	hits        uint64
+	misses      uint64
+	privateHits uint64
+}
+
+// NewGeoIPProcessor creates a new geoip processor.
+//
+// Supported options:
+//   - field: field holding the IP address (default "remote_addr")
+//   - database: path of a City or Country .mmdb file
+//   - asn_database: path of an ASN .mmdb file
+//   - target: prefix for the added fields (default "geo")
+//   - language: language of place names (default "en")
+//   - cache_size: number of lookups to cache (default 10000)
+//   - private_networks: extra CIDR ranges to treat as private
+//
+// At least one database is required. Added fields are country_code,
+// country_name, continent_code, region, region_code, city, lat, lon,
+// timezone, asn and as_org, when present in the database. Private, loopback
+// and link-local addresses are not looked up; they get a private field set
+// to true.
+func NewGeoIPProcessor(fields map[string]interface{}) (*GeoIPProcessor, error) {
+	p := &GeoIPProcessor{
+		field:    stringValue(fields, "field", "remote_addr"),
+		target:   stringValue(fields, "target", "geo"),
+		language: stringValue(fields, "language", "en"),
+	}
+	if p.target != "" && !strings.HasSuffix(p.target, ".") {
+		p.target += "."
+	}
+
+	for _, network := range stringList(fields["private_networks"]) {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private network %q: %w", network, err)
+		}
+		p.networks = append(p.networks, prefix.Masked())
+	}
+
+	cacheSize := 10000
+	if size, ok := floatValue(fields["cache_size"]); ok && size >= 0 {
+		cacheSize = int(size)
+	}
+	p.cache = lru.New[netip.Addr, map[string]interface{}](cacheSize)
+
+	if path := stringValue(fields, "database", ""); path != "" {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open geoip database: %w", err)
+		}
+		p.city = reader
+	}
+
+	if path := stringValue(fields, "asn_database", ""); path != "" {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to open asn database: %w", err)
+		}
+		p.asn = reader
+	}
+
+	if p.city == nil && p.asn == nil {
+		return nil, fmt.Errorf("geoip requires database or asn_database")
+	}
+
+	return p, nil
+}
+
+// Process adds geographic fields for the entry's IP address
+func (p *GeoIPProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	value, exists := expr.Lookup(entry, p.field)
+	if !exists {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	addr, err := parseAddr(expr.ToString(value))
+	if err != nil {
+		if !entry.HasTag(GeoIPFailureTag) {
+			entry.AddTag(GeoIPFailureTag)
+		}
+		return []*models.LogEntry{entry}, nil
+	}
+
+	if p.isPrivate(addr) {
+		atomic.AddUint64(&p.privateHits, 1)
+		entry.AddField(p.target+"private", true)
+		return []*models.LogEntry{entry}, nil
+	}
+
+	result, cached := p.cache.Get(addr)
+	if cached {
+		atomic.AddUint64(&p.hits, 1)
+	} else {
+		atomic.AddUint64(&p.misses, 1)
+		result, err = p.lookup(addr)
+		if err != nil {
+			return nil, fmt.Errorf("geoip lookup failed: %w", err)
+		}
+		p.cache.Add(addr, result)
+	}
+
+	for key, v := range result {
+		entry.AddField(p.target+key, v)
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// parseAddr parses an IP address, allowing a port or IPv6 zone
+func parseAddr(s string) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.WithZone("").Unmap(), nil
+}
+
+// isPrivate reports whether an address has no public location
+func (p *GeoIPProcessor) isPrivate(addr netip.Addr) bool {
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() ||
+		carrierGradeNAT.Contains(addr) {
+		return true
+	}
+	for _, prefix := range p.networks {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup reads the fields for an address from the databases
+func (p *GeoIPProcessor) lookup(addr netip.Addr) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	ip := net.IP(addr.AsSlice())
+
+	if p.city != nil {
+		var record cityRecord
+		if err := p.city.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		setString(result, "continent_code", record.Continent.Code)
+		setString(result, "country_code", record.Country.ISOCode)
+		setString(result, "country_name", record.Country.Names[p.language])
+		if len(record.Subdivisions) > 0 {
+			setString(result, "region", record.Subdivisions[0].Names[p.language])
+			setString(result, "region_code", record.Subdivisions[0].ISOCode)
+		}
+		setString(result, "city", record.City.Names[p.language])
+		setString(result, "timezone", record.Location.TimeZone)
+		if record.Location.Latitude != nil && record.Location.Longitude != nil {
+			result["lat"] = *record.Location.Latitude
+			result["lon"] = *record.Location.Longitude
+		}
+	}
+
+	if p.asn != nil {
+		var record asnRecord
+		if err := p.asn.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		if record.Number != 0 {
+			result["asn"] = int(record.Number)
+		}
+		setString(result, "as_org", record.Organization)
+	}
+
+	return result, nil
+}
+
+// setString stores a non-empty string
+func setString(result map[string]interface{}, key, value string) {
+	if value != "" {
+		result[key] = value
+	}
+}
+
+// Close closes the databases
+func (p *GeoIPProcessor) Close() error {
+	var firstErr error
+	for _, reader := range []*maxminddb.Reader{p.city, p.asn} {
+		if reader == nil {
+			continue
+		}
+		if err := reader.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.city, p.asn = nil, nil
+	return firstErr
+}
+
+// Stats returns cache hits and misses and the number of private addresses
+func (p *GeoIPProcessor) Stats() map[string]uint64 {
+	return map[string]uint64{
+		"cache_hits":   atomic.LoadUint64(&p.hits),
+		"cache_misses": atomic.LoadUint64(&p.misses),
+		"private":      atomic.LoadUint64(&p.privateHits),
+	}
+}
+
+// Name returns the processor name
+func (p *GeoIPProcessor) Name() string {
+	return "geoip"
+}
@@ -0,0 +1,248 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// writeMMDB writes a minimal IPv4 MaxMind database that holds record for
+// every address in network
+func writeMMDB(t *testing.T, network string, record map[string]interface{}) string {
+	t.Helper()
+
+	prefix := netip.MustParsePrefix(network)
+	bits := prefix.Bits()
+	ip := prefix.Addr().As4()
+	nodeCount := uint32(bits)
+
+	// One node per prefix bit; the other branch of each node is empty
+	var tree bytes.Buffer
+	for i := 0; i < bits; i++ {
+		next := uint32(i + 1)
+		if i == bits-1 {
+			next = nodeCount + 16
+		}
+		records := [2]uint32{nodeCount, nodeCount}
+		records[ip[i/8]>>(7-i%8)&1] = next
+		for _, r := range records {
+			tree.Write([]byte{byte(r >> 16), byte(r >> 8), byte(r)})
+		}
+	}
+
+	var db bytes.Buffer
+	db.Write(tree.Bytes())
+	db.Write(make([]byte, 16))
+	db.Write(mmdbEncode(record))
+	db.WriteString("\xAB\xCD\xEFMaxMind.com")
+	db.Write(mmdbEncode(map[string]interface{}{
+		"node_count":                  nodeCount,
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               "Test",
+		"languages":                   []interface{}{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1704110400),
+		"description":                 map[string]interface{}{"en": "test"},
+	}))
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, db.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+	return path
+}
+
+// mmdbEncode encodes a value in the MaxMind DB data format
+func mmdbEncode(value interface{}) []byte {
+	control := func(typ, size int) []byte {
+		// Sizes from 29 to 284 take one extra byte
+		var extra []byte
+		if size >= 29 {
+			extra = []byte{byte(size - 29)}
+			size = 29
+		}
+		out := []byte{byte(typ<<5 | size)}
+		if typ > 7 {
+			out = []byte{byte(size), byte(typ - 7)}
+		}
+		return append(out, extra...)
+	}
+	uint := func(typ int, v uint64) []byte {
+		var payload []byte
+		for ; v > 0; v >>= 8 {
+			payload = append([]byte{byte(v)}, payload...)
+		}
+		return append(control(typ, len(payload)), payload...)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case float64:
+		out := control(3, 8)
+		return binary.BigEndian.AppendUint64(out, math.Float64bits(v))
+	case uint16:
+		return uint(5, uint64(v))
+	case uint32:
+		return uint(6, uint64(v))
+	case uint64:
+		return uint(9, v)
+	case []interface{}:
+		out := control(11, len(v))
+		for _, item := range v {
+			out = append(out, mmdbEncode(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := control(7, len(v))
+		for _, key := range keys {
+			out = append(out, mmdbEncode(key)...)
+			out = append(out, mmdbEncode(v[key])...)
+		}
+		return out
+	}
+	panic("unsupported mmdb value")
+}
+
+func newGeoIP(t *testing.T, fields map[string]interface{}) *GeoIPProcessor {
+	t.Helper()
+
+	p, err := NewGeoIPProcessor(fields)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func geoLookup(t *testing.T, p *GeoIPProcessor, addr string) *models.LogEntry {
+	t.Helper()
+
+	entry := models.NewLogEntry()
+	entry.AddField("remote_addr", addr)
+	if _, err := p.Process(entry); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	return entry
+}
+
+func TestGeoIPLookup(t *testing.T) {
+	city := writeMMDB(t, "81.2.69.0/24", map[string]interface{}{
+		"continent": map[string]interface{}{"code": "EU"},
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+			"names":    map[string]interface{}{"en": "United Kingdom", "de": "Vereinigtes Königreich"},
+		},
+		"city":     map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		"location": map[string]interface{}{"latitude": 51.5142, "longitude": -0.0931, "time_zone": "Europe/London"},
+	})
+	asn := writeMMDB(t, "81.2.69.0/24", map[string]interface{}{
+		"autonomous_system_number":       uint32(64500),
+		"autonomous_system_organization": "Example Networks",
+	})
+	p := newGeoIP(t, map[string]interface{}{"database": city, "asn_database": asn})
+
+	entry := geoLookup(t, p, "81.2.69.160:443")
+	expected := map[string]interface{}{
+		"geo.country_code":   "GB",
+		"geo.country_name":   "United Kingdom",
+		"geo.continent_code": "EU",
+		"geo.city":           "London",
+		"geo.timezone":       "Europe/London",
+		"geo.lat":            51.5142,
+		"geo.asn":            64500,
+		"geo.as_org":         "Example Networks",
+	}
+	for key, value := range expected {
+		if got, _ := entry.GetField(key); got != value {
+			t.Errorf("Expected %s %v, got %v", key, value, got)
+		}
+	}
+
+	// The same address again is served from the cache
+	geoLookup(t, p, "81.2.69.160")
+	if stats := p.Stats(); stats["cache_hits"] != 1 || stats["cache_misses"] != 1 {
+		t.Errorf("Expected 1 cache hit and 1 miss, got %v", stats)
+	}
+
+	// Addresses outside the database get no fields
+	entry = geoLookup(t, p, "8.8.8.8")
+	if _, ok := entry.GetField("geo.country_code"); ok {
+		t.Error("Expected no fields for an unknown address")
+	}
+}
+
+func TestGeoIPLanguageAndTarget(t *testing.T) {
+	city := writeMMDB(t, "81.2.69.0/24", map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+			"names":    map[string]interface{}{"en": "United Kingdom", "de": "Vereinigtes Königreich"},
+		},
+	})
+	p := newGeoIP(t, map[string]interface{}{"database": city, "language": "de", "target": "client"})
+
+	entry := geoLookup(t, p, "81.2.69.1")
+	if name, _ := entry.GetField("client.country_name"); name != "Vereinigtes Königreich" {
+		t.Errorf("Expected German country name under client., got %v", name)
+	}
+}
+
+func TestGeoIPPrivateAndInvalid(t *testing.T) {
+	city := writeMMDB(t, "81.2.69.0/24", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "GB"},
+	})
+	p := newGeoIP(t, map[string]interface{}{
+		"database":         city,
+		"private_networks": []interface{}{"81.2.69.128/25"},
+	})
+
+	for _, addr := range []string{"10.1.2.3", "127.0.0.1", "100.64.0.1", "fe80::1%eth0", "81.2.69.200"} {
+		entry := geoLookup(t, p, addr)
+		if private, _ := entry.GetField("geo.private"); private != true {
+			t.Errorf("%s: expected private address, got %v", addr, entry.Fields)
+		}
+		if _, ok := entry.GetField("geo.country_code"); ok {
+			t.Errorf("%s: expected private address not to be looked up", addr)
+		}
+	}
+	if stats := p.Stats(); stats["private"] != 5 || stats["cache_misses"] != 0 {
+		t.Errorf("Expected 5 private addresses and no lookups, got %v", stats)
+	}
+
+	entry := geoLookup(t, p, "not-an-ip")
+	if !entry.HasTag(GeoIPFailureTag) {
+		t.Error("Expected failure tag for an invalid address")
+	}
+
+	missing := models.NewLogEntry()
+	if results, _ := p.Process(missing); len(results) != 1 || missing.HasTag(GeoIPFailureTag) {
+		t.Error("Expected entries without the field to pass through untagged")
+	}
+}
+
+func TestGeoIPInvalidConfig(t *testing.T) {
+	invalid := map[string]map[string]interface{}{
+		"no database":      {},
+		"missing database": {"database": filepath.Join(t.TempDir(), "none.mmdb")},
+		"invalid network":  {"database": "unused.mmdb", "private_networks": []interface{}{"10.0.0.0/40"}},
+	}
+	for name, fields := range invalid {
+		if _, err := NewGeoIPProcessor(fields); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync/atomic"
+
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// invalidLabelChars matches characters not allowed in label names
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// logMetric is a metric updated from matching entries
+type logMetric struct {
+	vec       *metrics.Vec
+	fields    []string
+	value     *expr.Expression
+	condition *expr.Expression
+}
+
+// MetricsProcessor derives counters, gauges and histograms from entries.
+// Entries pass through unchanged.
+type MetricsProcessor struct {
+	metrics []*logMetric
+
+	observed    uint64
+	valueErrors uint64
+}
+
+// NewMetricsProcessor creates a new metrics processor registered with
+// the default metrics registry. Use NewMetricsProcessorWithRegistry to
+// register elsewhere.
+//
+// Supported options:
+//   - metrics: list of metric definitions
+//   - max_series: default series limit per metric (default 1000)
+//
+// Each metric definition has:
+//   - name: metric name, e.g. http_requests_total
+//   - type: counter, gauge or histogram (default "counter")
+//   - help: description of the metric
+//   - labels: list of fields used as labels, or a map of label name to
+//     field
+//   - value: expression giving the value; counters add 1 without one,
+//     gauges and histograms require it
+//   - condition: expression selecting the entries to count
+//   - buckets: histogram upper bounds
+//   - max_series: series limit for this metric
+//
+// Updates that would create a series beyond the limit are discarded.
+func NewMetricsProcessor(fields map[string]interface{}) (*MetricsProcessor, error) {
+	return NewMetricsProcessorWithRegistry(fields, metrics.Default)
+}
+
+// NewMetricsProcessorWithRegistry creates a metrics processor registered
+// with the given registry
+func NewMetricsProcessorWithRegistry(fields map[string]interface{}, registry *metrics.Registry) (*MetricsProcessor, error) {
+	p := &MetricsProcessor{}
+
+	maxSeries := metrics.DefaultMaxSeries
+	if n, ok := floatValue(fields["max_series"]); ok && n > 0 {
+		maxSeries = int(n)
+	}
+
+	definitions, _ := fields["metrics"].([]interface{})
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("metrics requires at least one metric")
+	}
+
+	for i, item := range definitions {
+		definition, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("metric %d must be a map", i)
+		}
+		m, err := newLogMetric(registry, definition, maxSeries)
+		if err != nil {
+			return nil, err
+		}
+		p.metrics = append(p.metrics, m)
+	}
+
+	return p, nil
+}
+
+// newLogMetric registers one metric definition
+func newLogMetric(registry *metrics.Registry, definition map[string]interface{}, maxSeries int) (*logMetric, error) {
+	desc := metrics.Desc{
+		Name:      stringValue(definition, "name", ""),
+		Help:      stringValue(definition, "help", ""),
+		Type:      stringValue(definition, "type", metrics.TypeCounter),
+		MaxSeries: maxSeries,
+	}
+	if n, ok := floatValue(definition["max_series"]); ok && n > 0 {
+		desc.MaxSeries = int(n)
+	}
+	if buckets, ok := definition["buckets"].([]interface{}); ok {
+		for _, b := range buckets {
+			bound, ok := floatValue(b)
+			if !ok {
+				return nil, fmt.Errorf("invalid bucket for metric %s: %v", desc.Name, b)
+			}
+			desc.Buckets = append(desc.Buckets, bound)
+		}
+	}
+
+	m := &logMetric{}
+	if mapping := stringMap(definition["labels"]); len(mapping) > 0 {
+		for label := range mapping {
+			desc.Labels = append(desc.Labels, label)
+		}
+		sort.Strings(desc.Labels)
+		for _, label := range desc.Labels {
+			m.fields = append(m.fields, mapping[label])
+		}
+	} else {
+		for _, field := range stringList(definition["labels"]) {
+			desc.Labels = append(desc.Labels, invalidLabelChars.ReplaceAllString(field, "_"))
+			m.fields = append(m.fields, field)
+		}
+	}
+
+	if src := stringValue(definition, "value", ""); src != "" {
+		compiled, err := expr.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for metric %s: %w", desc.Name, err)
+		}
+		m.value = compiled
+	} else if desc.Type != metrics.TypeCounter {
+		return nil, fmt.Errorf("%s %s requires a value", desc.Type, desc.Name)
+	}
+
+	if src := stringValue(definition, "condition", ""); src != "" {
+		compiled, err := expr.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition for metric %s: %w", desc.Name, err)
+		}
+		m.condition = compiled
+	}
+
+	vec, err := registry.Register(desc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metric: %w", err)
+	}
+	m.vec = vec
+	return m, nil
+}
+
+// Process updates the metrics matching the entry
+func (p *MetricsProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	for _, m := range p.metrics {
+		if err := p.update(m, entry); err != nil && !errors.Is(err, metrics.ErrSeriesLimit) {
+			atomic.AddUint64(&p.valueErrors, 1)
+		}
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// update applies an entry to one metric
+func (p *MetricsProcessor) update(m *logMetric, entry *models.LogEntry) error {
+	if m.condition != nil {
+		matched, err := m.condition.Match(entry)
+		if err != nil || !matched {
+			return err
+		}
+	}
+
+	value := 1.0
+	if m.value != nil {
+		v, err := m.value.Eval(entry)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		f, ok := expr.ToNumber(v)
+		if !ok {
+			return fmt.Errorf("metric %s value is not a number: %q", m.vec.Desc().Name, expr.ToString(v))
+		}
+		value = f
+	}
+
+	labelValues := make([]string, len(m.fields))
+	for i, field := range m.fields {
+		if v, ok := expr.Lookup(entry, field); ok {
+			labelValues[i] = expr.ToString(v)
+		}
+	}
+
+	var err error
+	switch m.vec.Desc().Type {
+	case metrics.TypeCounter:
+		err = m.vec.Add(value, labelValues...)
+	case metrics.TypeGauge:
+		err = m.vec.Set(value, labelValues...)
+	case metrics.TypeHistogram:
+		err = m.vec.Observe(value, labelValues...)
+	}
+	if err == nil {
+		atomic.AddUint64(&p.observed, 1)
+	}
+	return err
+}
+
+// Stats returns the number of updates, updates refused by series limits
+// and values that could not be used
+func (p *MetricsProcessor) Stats() map[string]uint64 {
+	var rejected uint64
+	for _, m := range p.metrics {
+		rejected += m.vec.Rejected()
+	}
+	return map[string]uint64{
+		"observed":        atomic.LoadUint64(&p.observed),
+		"series_rejected": rejected,
+		"value_errors":    atomic.LoadUint64(&p.valueErrors),
+	}
+}
+
+// Name returns the processor name
+func (p *MetricsProcessor) Name() string {
+	return "metrics"
+}
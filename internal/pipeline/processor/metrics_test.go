@@ -0,0 +1,229 @@
+package processor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// newMetricsProcessor creates a metrics processor with its own registry
+func newMetricsProcessor(t *testing.T, registry *metrics.Registry, definitions ...map[string]interface{}) *MetricsProcessor {
+	t.Helper()
+
+	list := make([]interface{}, len(definitions))
+	for i, d := range definitions {
+		list[i] = d
+	}
+	p, err := NewMetricsProcessorWithRegistry(map[string]interface{}{"metrics": list}, registry)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	return p
+}
+
+// metricEntry creates an entry with the given fields
+func metricEntry(fields map[string]interface{}) *models.LogEntry {
+	entry := models.NewLogEntry()
+	for k, v := range fields {
+		entry.AddField(k, v)
+	}
+	return entry
+}
+
+// exposition returns the text exposition of a registry
+func exposition(t *testing.T, registry *metrics.Registry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := registry.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	return buf.String()
+}
+
+// expectLines checks that every line appears in the exposition
+func expectLines(t *testing.T, text string, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("Expected %q in exposition:\n%s", line, text)
+		}
+	}
+}
+
+func TestMetricsCounterGaugeHistogram(t *testing.T) {
+	registry := metrics.NewRegistry()
+	p := newMetricsProcessor(t, registry,
+		map[string]interface{}{"name": "requests_total"},
+		map[string]interface{}{"name": "queue_depth", "type": "gauge", "value": "depth"},
+		map[string]interface{}{
+			"name":    "request_seconds",
+			"type":    "histogram",
+			"value":   "duration_ms / 1000",
+			"buckets": []interface{}{0.1, 1},
+		},
+	)
+
+	for _, fields := range []map[string]interface{}{
+		{"depth": 5, "duration_ms": 50},
+		{"depth": 3, "duration_ms": 500},
+		{"depth": 7, "duration_ms": 2000},
+	} {
+		entry := metricEntry(fields)
+		results, err := p.Process(entry)
+		if err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		if len(results) != 1 || results[0] != entry {
+			t.Fatal("Expected entry to pass through unchanged")
+		}
+	}
+
+	expectLines(t, exposition(t, registry),
+		"requests_total 3",
+		"queue_depth 7",
+		`request_seconds_bucket{le="0.1"} 1`,
+		`request_seconds_bucket{le="1"} 2`,
+		`request_seconds_bucket{le="+Inf"} 3`,
+		"request_seconds_sum 2.55",
+		"request_seconds_count 3",
+	)
+	if stats := p.Stats(); stats["observed"] != 9 {
+		t.Errorf("Expected 9 updates, got %v", stats)
+	}
+}
+
+func TestMetricsLabels(t *testing.T) {
+	registry := metrics.NewRegistry()
+	p := newMetricsProcessor(t, registry,
+		map[string]interface{}{"name": "by_field_total", "labels": []interface{}{"http.status", "method"}},
+		map[string]interface{}{"name": "by_label_total", "labels": map[string]interface{}{"code": "http.status", "verb": "method"}},
+	)
+
+	p.Process(metricEntry(map[string]interface{}{"http": map[string]interface{}{"status": 200}, "method": "GET"}))
+	p.Process(metricEntry(map[string]interface{}{"http": map[string]interface{}{"status": 200}, "method": "GET"}))
+	p.Process(metricEntry(map[string]interface{}{"method": "POST"}))
+
+	expectLines(t, exposition(t, registry),
+		`by_field_total{http_status="200",method="GET"} 2`,
+		`by_field_total{http_status="",method="POST"} 1`,
+		`by_label_total{code="200",verb="GET"} 2`,
+		`by_label_total{code="",verb="POST"} 1`,
+	)
+}
+
+func TestMetricsCondition(t *testing.T) {
+	registry := metrics.NewRegistry()
+	p := newMetricsProcessor(t, registry, map[string]interface{}{
+		"name":      "errors_total",
+		"condition": `level == "ERROR"`,
+	})
+
+	for _, level := range []models.LogLevel{models.LogLevelError, models.LogLevelInfo, models.LogLevelError} {
+		entry := models.NewLogEntry()
+		entry.Level = level
+		p.Process(entry)
+	}
+
+	expectLines(t, exposition(t, registry), "errors_total 2")
+	if stats := p.Stats(); stats["observed"] != 2 {
+		t.Errorf("Expected 2 updates, got %v", stats)
+	}
+}
+
+func TestMetricsSeriesLimit(t *testing.T) {
+	registry := metrics.NewRegistry()
+	p := newMetricsProcessor(t, registry, map[string]interface{}{
+		"name":       "users_total",
+		"labels":     []interface{}{"user"},
+		"max_series": 2,
+	})
+
+	for _, user := range []string{"a", "b", "c", "a", "d"} {
+		p.Process(metricEntry(map[string]interface{}{"user": user}))
+	}
+
+	text := exposition(t, registry)
+	expectLines(t, text, `users_total{user="a"} 2`, `users_total{user="b"} 1`)
+	if strings.Contains(text, `user="c"`) || strings.Contains(text, `user="d"`) {
+		t.Errorf("Expected series beyond the limit to be discarded:\n%s", text)
+	}
+
+	stats := p.Stats()
+	if stats["series_rejected"] != 2 {
+		t.Errorf("Expected 2 rejected updates, got %v", stats)
+	}
+	if stats["value_errors"] != 0 {
+		t.Errorf("Expected rejected series not to count as value errors, got %v", stats)
+	}
+}
+
+func TestMetricsNonNumericValue(t *testing.T) {
+	registry := metrics.NewRegistry()
+	p := newMetricsProcessor(t, registry, map[string]interface{}{
+		"name":  "bytes_total",
+		"value": "bytes",
+	})
+
+	p.Process(metricEntry(map[string]interface{}{"bytes": 100}))
+	p.Process(metricEntry(map[string]interface{}{"bytes": "25"}))
+	p.Process(metricEntry(map[string]interface{}{"bytes": "many"}))
+	p.Process(metricEntry(map[string]interface{}{"other": 1}))
+
+	expectLines(t, exposition(t, registry), "bytes_total 125")
+	stats := p.Stats()
+	if stats["value_errors"] != 1 {
+		t.Errorf("Expected 1 value error, got %v", stats)
+	}
+	if stats["observed"] != 2 {
+		t.Errorf("Expected entries without the value to be skipped, got %v", stats)
+	}
+}
+
+func TestMetricsReregistration(t *testing.T) {
+	registry := metrics.NewRegistry()
+	definition := map[string]interface{}{
+		"name":   "requests_total",
+		"labels": []interface{}{"status"},
+	}
+
+	first := newMetricsProcessor(t, registry, definition)
+	first.Process(metricEntry(map[string]interface{}{"status": "200"}))
+
+	// A reloaded pipeline recreates the processor and keeps the series
+	second := newMetricsProcessor(t, registry, definition)
+	second.Process(metricEntry(map[string]interface{}{"status": "200"}))
+	expectLines(t, exposition(t, registry), `requests_total{status="200"} 2`)
+
+	for name, changed := range map[string]map[string]interface{}{
+		"type":   {"name": "requests_total", "type": "gauge", "value": "1", "labels": []interface{}{"status"}},
+		"labels": {"name": "requests_total", "labels": []interface{}{"code"}},
+	} {
+		_, err := NewMetricsProcessorWithRegistry(map[string]interface{}{"metrics": []interface{}{changed}}, registry)
+		if err == nil {
+			t.Errorf("Expected error re-registering with a different %s", name)
+		}
+	}
+}
+
+func TestMetricsInvalidConfig(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no metrics":        {},
+		"not a map":         {"metrics": []interface{}{"requests_total"}},
+		"gauge no value":    {"metrics": []interface{}{map[string]interface{}{"name": "depth", "type": "gauge"}}},
+		"invalid bucket":    {"metrics": []interface{}{map[string]interface{}{"name": "s", "type": "histogram", "value": "x", "buckets": []interface{}{"fast"}}}},
+		"invalid value":     {"metrics": []interface{}{map[string]interface{}{"name": "total", "value": "a +"}}},
+		"invalid type":      {"metrics": []interface{}{map[string]interface{}{"name": "total", "type": "summary"}}},
+		"invalid condition": {"metrics": []interface{}{map[string]interface{}{"name": "total", "condition": "=="}}},
+	}
+
+	for name, fields := range tests {
+		if _, err := NewMetricsProcessorWithRegistry(fields, metrics.NewRegistry()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -2,20 +2,42 @@ package processor
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
 	"github.com/UmangDiyora/logpipeline/pkg/models"
 )
 
 // Processor is the interface for log processors
 type Processor interface {
-	// Process processes a log entry
-	Process(entry *models.LogEntry) error
+	// Process processes a log entry and returns the resulting entries:
+	// none to drop it, one to pass it on, or several to split it
+	Process(entry *models.LogEntry) ([]*models.LogEntry, error)
 
 	// Name returns the processor name
 	Name() string
 }
 
+// StatsProvider is implemented by processors that keep their own counters,
+// which are reported in pipeline statistics
+type StatsProvider interface {
+	// Stats returns a snapshot of the processor counters
+	Stats() map[string]uint64
+}
+
+// Emitter is implemented by processors that produce entries outside of
+// Process, such as summaries emitted when a time window closes
+type Emitter interface {
+	// Start begins background work; produced entries are passed to emit
+	Start(emit func(*models.LogEntry))
+
+	// Stop flushes pending entries and stops background work
+	Stop()
+}
+
 // Config holds processor configuration
 type Config struct {
 	// Type is the processor type
@@ -26,6 +48,10 @@ type Config struct {
 
 	// Condition for processing
 	Condition string
+
+	// Metrics is the registry metrics processors register with; nil
+	// means metrics.Default
+	Metrics *metrics.Registry
 }
 
 // AddFieldsProcessor adds fields to log entries
@@ -41,11 +67,11 @@ func NewAddFieldsProcessor(fields map[string]interface{}) *AddFieldsProcessor {
 }
 
 // Process adds fields to the log entry
-func (p *AddFieldsProcessor) Process(entry *models.LogEntry) error {
+func (p *AddFieldsProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
 	for key, value := range p.fields {
 		entry.AddField(key, value)
 	}
-	return nil
+	return []*models.LogEntry{entry}, nil
 }
 
 // Name returns the processor name
@@ -66,7 +92,7 @@ func NewRenameFieldsProcessor(mapping map[string]string) *RenameFieldsProcessor
 }
 
 // Process renames fields in the log entry
-func (p *RenameFieldsProcessor) Process(entry *models.LogEntry) error {
+func (p *RenameFieldsProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
 	for oldName, newName := range p.mapping {
 		if value, exists := entry.GetField(oldName); exists {
 			entry.AddField(newName, value)
@@ -74,7 +100,7 @@ func (p *RenameFieldsProcessor) Process(entry *models.LogEntry) error {
 			delete(entry.Fields, oldName)
 		}
 	}
-	return nil
+	return []*models.LogEntry{entry}, nil
 }
 
 // Name returns the processor name
@@ -95,11 +121,11 @@ func NewDropFieldsProcessor(fields []string) *DropFieldsProcessor {
 }
 
 // Process removes fields from the log entry
-func (p *DropFieldsProcessor) Process(entry *models.LogEntry) error {
+func (p *DropFieldsProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
 	for _, field := range p.fields {
 		delete(entry.Fields, field)
 	}
-	return nil
+	return []*models.LogEntry{entry}, nil
 }
 
 // Name returns the processor name
@@ -120,7 +146,7 @@ func NewLowercaseProcessor(fields []string) *LowercaseProcessor {
 }
 
 // Process converts specified fields to lowercase
-func (p *LowercaseProcessor) Process(entry *models.LogEntry) error {
+func (p *LowercaseProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
 	for _, field := range p.fields {
 		if value, exists := entry.GetField(field); exists {
 			if strValue, ok := value.(string); ok {
@@ -128,7 +154,7 @@ func (p *LowercaseProcessor) Process(entry *models.LogEntry) error {
 			}
 		}
 	}
-	return nil
+	return []*models.LogEntry{entry}, nil
 }
 
 // Name returns the processor name
@@ -149,7 +175,7 @@ func NewTrimProcessor(fields []string) *TrimProcessor {
 }
 
 // Process trims whitespace from specified fields
-func (p *TrimProcessor) Process(entry *models.LogEntry) error {
+func (p *TrimProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
 	for _, field := range p.fields {
 		if value, exists := entry.GetField(field); exists {
 			if strValue, ok := value.(string); ok {
@@ -157,7 +183,7 @@ func (p *TrimProcessor) Process(entry *models.LogEntry) error {
 			}
 		}
 	}
-	return nil
+	return []*models.LogEntry{entry}, nil
 }
 
 // Name returns the processor name
@@ -170,6 +196,7 @@ type FilterProcessor struct {
 	dropIfMatch bool
 	field       string
 	pattern     string
+	expression  *expr.Expression
 }
 
 // NewFilterProcessor creates a new filter processor
@@ -181,17 +208,36 @@ func NewFilterProcessor(field, pattern string, dropIfMatch bool) *FilterProcesso
 	}
 }
 
+// NewExpressionFilterProcessor creates a filter that keeps entries for
+// which the expression holds
+func NewExpressionFilterProcessor(expression *expr.Expression) *FilterProcessor {
+	return &FilterProcessor{
+		expression: expression,
+	}
+}
+
 // Process filters the log entry
-func (p *FilterProcessor) Process(entry *models.LogEntry) error {
-	if value, exists := entry.GetField(p.field); exists {
+func (p *FilterProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	if p.expression != nil {
+		matched, err := p.expression.Match(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate filter: %w", err)
+		}
+		if !matched {
+			return nil, nil
+		}
+		return []*models.LogEntry{entry}, nil
+	}
+
+	if value, exists := expr.Lookup(entry, p.field); exists {
 		if strValue, ok := value.(string); ok {
 			matches := strings.Contains(strValue, p.pattern)
 			if (p.dropIfMatch && matches) || (!p.dropIfMatch && !matches) {
-				return fmt.Errorf("entry filtered out")
+				return nil, nil
 			}
 		}
 	}
-	return nil
+	return []*models.LogEntry{entry}, nil
 }
 
 // Name returns the processor name
@@ -199,12 +245,53 @@ func (p *FilterProcessor) Name() string {
 	return "filter"
 }
 
-// New creates a new processor based on configuration
+// DropProcessor drops every entry; combine it with a condition to drop
+// matching entries
+type DropProcessor struct{}
+
+// NewDropProcessor creates a new drop processor
+func NewDropProcessor() *DropProcessor {
+	return &DropProcessor{}
+}
+
+// Process drops the log entry
+func (p *DropProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	return nil, nil
+}
+
+// Name returns the processor name
+func (p *DropProcessor) Name() string {
+	return "drop"
+}
+
+// FileOptions lists the options of each processor type that name a file
+// the server reads
+var FileOptions = map[string][]string{
+	"enrich":     {"file"},
+	"geoip":      {"database", "asn_database"},
+	"user_agent": {"regexes_file"},
+}
+
+// New creates a new processor based on configuration. Processors with a
+// condition only run for entries matching it.
 func New(config *Config) (Processor, error) {
 	if config == nil {
 		return nil, fmt.Errorf("config is required")
 	}
 
+	proc, err := newProcessor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Condition != "" {
+		return NewConditionalProcessor(proc, config.Condition)
+	}
+	return proc, nil
+}
+
+// newProcessor creates the processor for a configuration type
+func newProcessor(config *Config) (Processor, error) {
 	switch config.Type {
 	case "add_fields":
 		return NewAddFieldsProcessor(config.Fields), nil
@@ -219,39 +306,163 @@ func New(config *Config) (Processor, error) {
 		return NewRenameFieldsProcessor(mapping), nil
 
 	case "drop_fields":
-		fields := make([]string, 0)
-		if fieldList, ok := config.Fields["fields"].([]interface{}); ok {
-			for _, f := range fieldList {
-				if strField, ok := f.(string); ok {
-					fields = append(fields, strField)
-				}
-			}
-		}
+		fields := stringList(config.Fields["fields"])
 		return NewDropFieldsProcessor(fields), nil
 
 	case "lowercase":
-		fields := make([]string, 0)
-		if fieldList, ok := config.Fields["fields"].([]interface{}); ok {
-			for _, f := range fieldList {
-				if strField, ok := f.(string); ok {
-					fields = append(fields, strField)
-				}
-			}
-		}
+		fields := stringList(config.Fields["fields"])
 		return NewLowercaseProcessor(fields), nil
 
 	case "trim":
-		fields := make([]string, 0)
-		if fieldList, ok := config.Fields["fields"].([]interface{}); ok {
-			for _, f := range fieldList {
-				if strField, ok := f.(string); ok {
-					fields = append(fields, strField)
-				}
+		fields := stringList(config.Fields["fields"])
+		return NewTrimProcessor(fields), nil
+
+	case "date":
+		return NewDateProcessor(config.Fields)
+
+	case "convert":
+		return NewConvertProcessor(config.Fields)
+
+	case "compute":
+		return NewComputeProcessor(config.Fields)
+
+	case "filter":
+		if expression := stringValue(config.Fields, "expression", ""); expression != "" {
+			compiled, err := expr.Compile(expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter expression: %w", err)
 			}
+			return NewExpressionFilterProcessor(compiled), nil
 		}
-		return NewTrimProcessor(fields), nil
+		pattern := stringValue(config.Fields, "pattern", "")
+		if pattern == "" {
+			return nil, fmt.Errorf("filter requires an expression or a pattern")
+		}
+		field := stringValue(config.Fields, "field", "message")
+		dropIfMatch, _ := config.Fields["drop_if_match"].(bool)
+		return NewFilterProcessor(field, pattern, dropIfMatch), nil
+
+	case "drop":
+		return NewDropProcessor(), nil
+
+	case "split":
+		return NewSplitProcessor(config.Fields)
+
+	case "enrich":
+		return NewEnrichProcessor(config.Fields)
+
+	case "geoip":
+		return NewGeoIPProcessor(config.Fields)
+
+	case "user_agent":
+		return NewUserAgentProcessor(config.Fields)
+
+	case "redact":
+		return NewRedactProcessor(config.Fields)
+
+	case "sample":
+		return NewSampleProcessor(config.Fields)
+
+	case "dedupe":
+		return NewDedupeProcessor(config.Fields)
+
+	case "metrics":
+		if config.Metrics != nil {
+			return NewMetricsProcessorWithRegistry(config.Fields, config.Metrics)
+		}
+		return NewMetricsProcessor(config.Fields)
 
 	default:
 		return nil, fmt.Errorf("unsupported processor type: %s", config.Type)
 	}
 }
+
+// stringList converts a config value to a list of strings
+func stringList(value interface{}) []string {
+	values := make([]string, 0)
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+	case []string:
+		values = append(values, v...)
+	case string:
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// stringMap converts a config value to a map of strings
+func stringMap(value interface{}) map[string]string {
+	values := make(map[string]string)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if str, ok := item.(string); ok {
+				values[key] = str
+			}
+		}
+	case map[string]string:
+		for key, item := range v {
+			values[key] = item
+		}
+	}
+	return values
+}
+
+// floatValue converts a numeric config value to float64
+func floatValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// durationValue converts a config value such as "5s" or a number of
+// seconds to a duration
+func durationValue(value interface{}, def time.Duration) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return def, nil
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, err
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("duration must be positive: %s", v)
+		}
+		return d, nil
+	}
+	if seconds, ok := floatValue(value); ok && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("invalid duration: %v", value)
+}
+
+// stringValue returns a string config value or a default
+func stringValue(fields map[string]interface{}, key, def string) string {
+	if v, ok := fields[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestFilterExpression(t *testing.T) {
+	p, err := New(&Config{Type: "filter", Fields: map[string]interface{}{"expression": `level == "ERROR"`}})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	tests := []struct {
+		level  models.LogLevel
+		passes bool
+	}{
+		{models.LogLevelError, true},
+		{models.LogLevelInfo, false},
+	}
+	for _, tt := range tests {
+		entry := models.NewLogEntry()
+		entry.Level = tt.level
+		results, err := p.Process(entry)
+		if err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		if (len(results) == 1) != tt.passes {
+			t.Errorf("%s: expected pass %v, got %d entries", tt.level, tt.passes, len(results))
+		}
+	}
+}
+
+func TestFilterPattern(t *testing.T) {
+	keep, _ := New(&Config{Type: "filter", Fields: map[string]interface{}{"pattern": "payment"}})
+	drop, _ := New(&Config{Type: "filter", Fields: map[string]interface{}{"pattern": "health", "drop_if_match": true}})
+
+	tests := []struct {
+		processor Processor
+		message   string
+		passes    bool
+	}{
+		{keep, "payment declined", true},
+		{keep, "user logged in", false},
+		{drop, "GET /health 200", false},
+		{drop, "GET /orders 200", true},
+	}
+	for _, tt := range tests {
+		entry := models.NewLogEntry()
+		entry.Message = tt.message
+		results, _ := tt.processor.Process(entry)
+		if (len(results) == 1) != tt.passes {
+			t.Errorf("%q: expected pass %v, got %d entries", tt.message, tt.passes, len(results))
+		}
+	}
+}
+
+func TestDropWithCondition(t *testing.T) {
+	p, err := New(&Config{Type: "drop", Condition: `fields.debug == true`})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	debug := models.NewLogEntry()
+	debug.AddField("debug", true)
+	if results, _ := p.Process(debug); len(results) != 0 {
+		t.Error("Expected matching entry to be dropped")
+	}
+
+	other := models.NewLogEntry()
+	if results, _ := p.Process(other); len(results) != 1 {
+		t.Error("Expected entry not matching the condition to pass")
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	invalid := map[string]*Config{
+		"unknown type":        {Type: "explode"},
+		"filter without args": {Type: "filter", Fields: map[string]interface{}{}},
+		"invalid expression":  {Type: "filter", Fields: map[string]interface{}{"expression": "level =="}},
+		"invalid condition":   {Type: "drop", Condition: "level =="},
+	}
+	for name, config := range invalid {
+		if _, err := New(config); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Redaction actions
+const (
+	RedactMask    = "mask"
+	RedactPartial = "partial"
+	RedactHash    = "hash"
+	RedactDrop    = "drop"
+)
+
+// DefaultRedactMask replaces values redacted with the mask action
+const DefaultRedactMask = "[REDACTED]"
+
+// detector finds one kind of sensitive value
+type detector struct {
+	name  string
+	re    *regexp.Regexp
+	group int
+	valid func(match string) bool
+}
+
+// builtinDetectors are applied in order; more specific patterns run first
+// so that, for example, card numbers are not reported as phone numbers
+var builtinDetectors = []*detector{
+	{
+		name:  "bearer_token",
+		re:    regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+		group: 1,
+	},
+	{
+		name: "email",
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		name:  "iban",
+		re:    regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		valid: validIBAN,
+	},
+	{
+		name:  "credit_card",
+		re:    regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid: validLuhn,
+	},
+	{
+		// The number must not continue a word or a longer number; RE2 has
+		// no lookbehind, so the preceding character is matched outside the
+		// group
+		name:  "phone",
+		re:    regexp.MustCompile(`(?:^|[^\w+(])((?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\d{2,4}[ .\-]?\d{3,4}(?:[ .\-]?\d{3,4})?)\b`),
+		group: 1,
+		valid: validPhone,
+	},
+}
+
+// RedactProcessor masks, hashes or drops sensitive values found in the
+// message, raw line and selected fields
+type RedactProcessor struct {
+	detectors []*detector
+	actions   map[string]string
+	fields    []string
+	allFields bool
+	mask      string
+	hashKey   []byte
+	counts    map[string]*uint64
+}
+
+// NewRedactProcessor creates a new redact processor.
+//
+// Supported options:
+//   - detectors: built-in detectors to enable (default all of bearer_token,
+//     email, iban, credit_card and phone)
+//   - patterns: map of custom detector name to regex; when the regex has a
+//     capture group only the first group is redacted
+//   - fields: fields to scan besides message and raw, or "*" for all;
+//     dotted names reach into nested objects, and objects and arrays are
+//     scanned recursively
+//   - action: default action (mask, partial, hash or drop)
+//   - actions: map of detector name to action
+//   - mask: replacement text for the mask action
+//   - hash_key: HMAC-SHA256 key, required by the hash action
+//
+// The drop action removes a matching field, object key or array element;
+// in the message and raw line,
+// where there is nothing to drop, matches are masked instead.
+func NewRedactProcessor(fields map[string]interface{}) (*RedactProcessor, error) {
+	p := &RedactProcessor{
+		actions: make(map[string]string),
+		mask:    stringValue(fields, "mask", DefaultRedactMask),
+		hashKey: []byte(stringValue(fields, "hash_key", "")),
+		counts:  make(map[string]*uint64),
+	}
+
+	enabled := stringList(fields["detectors"])
+	if _, ok := fields["detectors"]; !ok {
+		for _, d := range builtinDetectors {
+			enabled = append(enabled, d.name)
+		}
+	}
+	for _, name := range enabled {
+		d := findDetector(name)
+		if d == nil {
+			return nil, fmt.Errorf("unknown redact detector: %s", name)
+		}
+		p.detectors = append(p.detectors, d)
+	}
+
+	patterns := stringMap(fields["patterns"])
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pattern := patterns[name]
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for %s: %w", name, err)
+		}
+		d := &detector{name: name, re: re}
+		if re.NumSubexp() > 0 {
+			d.group = 1
+		}
+		p.detectors = append(p.detectors, d)
+	}
+
+	if len(p.detectors) == 0 {
+		return nil, fmt.Errorf("redact requires at least one detector")
+	}
+
+	defaultAction := stringValue(fields, "action", RedactMask)
+	overrides := stringMap(fields["actions"])
+	for _, d := range p.detectors {
+		action := defaultAction
+		if override, ok := overrides[d.name]; ok {
+			action = override
+		}
+		switch action {
+		case RedactMask, RedactPartial, RedactDrop:
+		case RedactHash:
+			if len(p.hashKey) == 0 {
+				return nil, fmt.Errorf("hash action for %s requires hash_key", d.name)
+			}
+		default:
+			return nil, fmt.Errorf("invalid redact action for %s: %s", d.name, action)
+		}
+		p.actions[d.name] = action
+		p.counts[d.name] = new(uint64)
+	}
+
+	for _, field := range stringList(fields["fields"]) {
+		if field == "*" {
+			p.allFields = true
+			continue
+		}
+		p.fields = append(p.fields, field)
+	}
+
+	return p, nil
+}
+
+// findDetector returns the built-in detector with the given name
+func findDetector(name string) *detector {
+	for _, d := range builtinDetectors {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Process redacts sensitive values from the entry
+func (p *RedactProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	p.redactEntry(entry, true)
+	return []*models.LogEntry{entry}, nil
+}
+
+// Redact redacts an entry without counting the redactions. It is used
+// for copies kept outside the pipeline, such as dead-letter records.
+func (p *RedactProcessor) Redact(entry *models.LogEntry) {
+	p.redactEntry(entry, false)
+}
+
+// RedactText redacts a string without counting the redactions
+func (p *RedactProcessor) RedactText(s string) string {
+	s, _ = p.redact(s, false)
+	return s
+}
+
+// redactEntry redacts the message, raw line and selected fields
+func (p *RedactProcessor) redactEntry(entry *models.LogEntry, count bool) {
+	entry.Message, _ = p.redact(entry.Message, count)
+	entry.Raw, _ = p.redact(entry.Raw, count)
+
+	if p.allFields {
+		for key := range entry.Fields {
+			p.redactField(entry.Fields, key, count)
+		}
+		return
+	}
+
+	for _, key := range p.fields {
+		p.redactField(entry.Fields, key, count)
+	}
+}
+
+// redactField redacts a field, removing it if a drop action matched. The
+// name is resolved like expr.Lookup: a literal key first, then a dotted
+// path into nested maps. Nested maps are copied before they are changed,
+// since entry clones share them.
+func (p *RedactProcessor) redactField(fields map[string]interface{}, name string, count bool) {
+	if fields == nil {
+		return
+	}
+	if value, ok := fields[name]; ok {
+		redacted, drop := p.redactValue(value, count)
+		if drop {
+			delete(fields, name)
+			return
+		}
+		fields[name] = redacted
+		return
+	}
+
+	head, rest, found := strings.Cut(name, ".")
+	if !found {
+		return
+	}
+	child, ok := fields[head].(map[string]interface{})
+	if !ok {
+		return
+	}
+	child = copyMap(child)
+	p.redactField(child, rest, count)
+	fields[head] = child
+}
+
+// redactValue redacts a string, or every string inside nested maps and
+// slices. Keys and elements matching a drop action are removed; the
+// result reports whether the value itself should be dropped.
+func (p *RedactProcessor) redactValue(value interface{}, count bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		return p.redact(v, count)
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			child, drop := p.redactValue(child, count)
+			if !drop {
+				redacted[key] = child
+			}
+		}
+		return redacted, false
+	case []interface{}:
+		redacted := make([]interface{}, 0, len(v))
+		for _, child := range v {
+			child, drop := p.redactValue(child, count)
+			if !drop {
+				redacted = append(redacted, child)
+			}
+		}
+		return redacted, false
+	default:
+		return value, false
+	}
+}
+
+// copyMap returns a shallow copy of m
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// redact applies every detector to s. It reports whether a detector with
+// the drop action matched.
+func (p *RedactProcessor) redact(s string, count bool) (string, bool) {
+	if s == "" {
+		return s, false
+	}
+
+	drop := false
+	for _, d := range p.detectors {
+		action := p.actions[d.name]
+		s = replaceMatches(d, s, func(match string) string {
+			if count {
+				atomic.AddUint64(p.counts[d.name], 1)
+			}
+			if action == RedactDrop {
+				drop = true
+			}
+			return p.apply(d.name, action, match)
+		})
+	}
+	return s, drop
+}
+
+// apply transforms a single match according to its action
+func (p *RedactProcessor) apply(name, action, match string) string {
+	switch action {
+	case RedactPartial:
+		return partialMask(name, match)
+	case RedactHash:
+		mac := hmac.New(sha256.New, p.hashKey)
+		mac.Write([]byte(match))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:32]
+	default:
+		return p.mask
+	}
+}
+
+// replaceMatches replaces validated matches of a detector, or only the
+// detector's capture group when it has one
+func replaceMatches(d *detector, s string, replace func(string) string) string {
+	matches := d.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*d.group], m[2*d.group+1]
+		if start < 0 {
+			continue
+		}
+		value := s[start:end]
+		if d.valid != nil && !d.valid(value) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(replace(value))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// partialMask hides most of a value while keeping enough to tell values
+// apart: the first character and domain of an email, or the last four
+// characters of anything else
+func partialMask(name, value string) string {
+	if name == "email" {
+		if at := strings.LastIndex(value, "@"); at > 0 {
+			return value[:1] + strings.Repeat("*", at-1) + value[at:]
+		}
+	}
+
+	keep := 4
+	if len(value) <= keep {
+		return strings.Repeat("*", len(value))
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value)-keep; i++ {
+		c := value[i]
+		if c == ' ' || c == '-' || c == '.' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('*')
+		}
+	}
+	b.WriteString(value[len(value)-keep:])
+	return b.String()
+}
+
+// Stats returns the number of redactions per detector
+func (p *RedactProcessor) Stats() map[string]uint64 {
+	stats := make(map[string]uint64, len(p.counts))
+	for name, count := range p.counts {
+		stats[name] = atomic.LoadUint64(count)
+	}
+	return stats
+}
+
+// Name returns the processor name
+func (p *RedactProcessor) Name() string {
+	return "redact"
+}
+
+// digitsOnly strips everything but digits
+func digitsOnly(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// validLuhn checks a card number against the Luhn checksum
+func validLuhn(s string) bool {
+	digits := digitsOnly(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks an IBAN against its ISO 7064 mod 97 checksum
+func validIBAN(s string) bool {
+	iban := strings.ReplaceAll(s, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for i := 0; i < len(rearranged); i++ {
+		c := rearranged[i]
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// validPhone requires a plausible number of digits written the way phone
+// numbers are: with a country code, an area code in parentheses or
+// separators between digit groups. Bare digit runs are more often
+// timestamps, IDs or byte counts.
+func validPhone(s string) bool {
+	n := len(digitsOnly(s))
+	if n < 10 || n > 15 {
+		return false
+	}
+	return strings.HasPrefix(s, "+") || strings.ContainsAny(s, "() .-")
+}
@@ -0,0 +1,177 @@
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Sampling modes
+const (
+	SampleFixed   = "fixed"
+	SampleHash    = "hash"
+	SampleDynamic = "dynamic"
+)
+
+// SampleRateField records how many events a kept entry stands for
+const SampleRateField = "sample_rate"
+
+// SampleProcessor drops a share of log entries. Kept entries carry a
+// sample_rate field (the inverse of the keep probability) so counts can be
+// re-weighted.
+type SampleProcessor struct {
+	mode  string
+	rate  float64
+	field string
+
+	// Dynamic mode state
+	target float64
+	window time.Duration
+	mu     sync.Mutex
+	start  time.Time
+	keys   map[string]*sampleKey
+	now    func() time.Time
+}
+
+// sampleKey tracks the traffic of one key in dynamic mode
+type sampleKey struct {
+	count       uint64
+	probability float64
+}
+
+// NewSampleProcessor creates a new sample processor.
+//
+// Supported options:
+//   - mode: fixed (default), hash or dynamic
+//   - rate: share of entries to keep between 0 and 1, for fixed and hash
+//   - field: for hash mode, the field whose value decides (e.g. trace_id),
+//     so that related entries are kept or dropped together; for dynamic
+//     mode, the key to balance (default "service")
+//   - target: for dynamic mode, events per second to keep per key
+//   - window: for dynamic mode, how often rates are recomputed (default 1s)
+func NewSampleProcessor(fields map[string]interface{}) (*SampleProcessor, error) {
+	p := &SampleProcessor{
+		mode: stringValue(fields, "mode", SampleFixed),
+		now:  time.Now,
+	}
+
+	switch p.mode {
+	case SampleFixed, SampleHash:
+		rate, ok := floatValue(fields["rate"])
+		if !ok || rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("sample rate must be between 0 and 1")
+		}
+		p.rate = rate
+		if p.mode == SampleHash {
+			p.field = stringValue(fields, "field", "")
+			if p.field == "" {
+				return nil, fmt.Errorf("hash sampling requires a field")
+			}
+		}
+
+	case SampleDynamic:
+		target, ok := floatValue(fields["target"])
+		if !ok || target <= 0 {
+			return nil, fmt.Errorf("dynamic sampling requires a positive target")
+		}
+		window, err := durationValue(fields["window"], time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample window: %w", err)
+		}
+		p.target = target
+		p.window = window
+		p.field = stringValue(fields, "field", "service")
+		p.keys = make(map[string]*sampleKey)
+		p.start = p.now()
+
+	default:
+		return nil, fmt.Errorf("unsupported sample mode: %s", p.mode)
+	}
+
+	return p, nil
+}
+
+// Process keeps or drops the entry
+func (p *SampleProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	var probability float64
+	keep := false
+
+	switch p.mode {
+	case SampleFixed:
+		probability = p.rate
+		keep = rand.Float64() < probability
+
+	case SampleHash:
+		probability = p.rate
+		value, exists := expr.Lookup(entry, p.field)
+		if !exists {
+			// Without a key there is nothing to keep together
+			keep = rand.Float64() < probability
+		} else {
+			h := fnv.New64a()
+			h.Write([]byte(expr.ToString(value)))
+			keep = probability >= 1 || float64(h.Sum64()) < probability*math.MaxUint64
+		}
+
+	case SampleDynamic:
+		value, _ := expr.Lookup(entry, p.field)
+		probability = p.dynamicProbability(expr.ToString(value))
+		keep = probability >= 1 || rand.Float64() < probability
+	}
+
+	if !keep {
+		return nil, nil
+	}
+
+	rate := 1 / probability
+	// Entries sampled more than once stand for the product of both rates
+	if existing, ok := entry.GetField(SampleRateField); ok {
+		if prior, ok := floatValue(existing); ok && prior > 0 {
+			rate *= prior
+		}
+	}
+	entry.AddField(SampleRateField, rate)
+	return []*models.LogEntry{entry}, nil
+}
+
+// dynamicProbability counts an event for key and returns its current keep
+// probability. Probabilities are recomputed every window from the traffic
+// seen since the last recomputation, which spans more than one window
+// after a quiet period; new keys are kept in full until then.
+func (p *SampleProcessor) dynamicProbability(key string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now := p.now(); now.Sub(p.start) >= p.window {
+		allowed := p.target * now.Sub(p.start).Seconds()
+		for k, state := range p.keys {
+			if state.count == 0 {
+				// Idle keys are forgotten
+				delete(p.keys, k)
+				continue
+			}
+			state.probability = math.Min(1, allowed/float64(state.count))
+			state.count = 0
+		}
+		p.start = now
+	}
+
+	state, exists := p.keys[key]
+	if !exists {
+		state = &sampleKey{probability: 1}
+		p.keys[key] = state
+	}
+	state.count++
+	return state.probability
+}
+
+// Name returns the processor name
+func (p *SampleProcessor) Name() string {
+	return "sample"
+}
@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// sampleEntries runs n entries through a sampler and returns those kept
+func sampleEntries(t *testing.T, p *SampleProcessor, n int, setup func(i int, entry *models.LogEntry)) []*models.LogEntry {
+	t.Helper()
+
+	kept := make([]*models.LogEntry, 0)
+	for i := 0; i < n; i++ {
+		entry := models.NewLogEntry()
+		if setup != nil {
+			setup(i, entry)
+		}
+		results, err := p.Process(entry)
+		if err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		kept = append(kept, results...)
+	}
+	return kept
+}
+
+func TestSampleFixed(t *testing.T) {
+	p, err := NewSampleProcessor(map[string]interface{}{"rate": 0.1})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	kept := sampleEntries(t, p, 10000, nil)
+	if len(kept) < 800 || len(kept) > 1200 {
+		t.Errorf("Expected about 1000 of 10000 entries kept, got %d", len(kept))
+	}
+	if rate, _ := kept[0].GetField(SampleRateField); rate != 10.0 {
+		t.Errorf("Expected sample_rate 10, got %v", rate)
+	}
+}
+
+func TestSampleFixedKeepsAll(t *testing.T) {
+	p, _ := NewSampleProcessor(map[string]interface{}{"rate": 1})
+
+	if kept := sampleEntries(t, p, 100, nil); len(kept) != 100 {
+		t.Errorf("Expected every entry kept at rate 1, got %d", len(kept))
+	}
+}
+
+func TestSampleRateMultiplies(t *testing.T) {
+	p, _ := NewSampleProcessor(map[string]interface{}{"rate": 0.5})
+
+	kept := sampleEntries(t, p, 100, func(i int, entry *models.LogEntry) {
+		entry.AddField(SampleRateField, 4.0)
+	})
+	if len(kept) == 0 {
+		t.Fatal("Expected some entries kept")
+	}
+	if rate, _ := kept[0].GetField(SampleRateField); rate != 8.0 {
+		t.Errorf("Expected sample_rate 8 after sampling twice, got %v", rate)
+	}
+}
+
+func TestSampleHash(t *testing.T) {
+	p, err := NewSampleProcessor(map[string]interface{}{"mode": "hash", "rate": 0.25, "field": "trace_id"})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	// Entries of a trace are kept or dropped together
+	keptTraces := make(map[string]int)
+	kept := sampleEntries(t, p, 4000, func(i int, entry *models.LogEntry) {
+		entry.AddField("trace_id", fmt.Sprintf("trace-%d", i%1000))
+	})
+	for _, entry := range kept {
+		trace, _ := entry.GetField("trace_id")
+		keptTraces[trace.(string)]++
+	}
+	for trace, n := range keptTraces {
+		if n != 4 {
+			t.Fatalf("Expected all 4 entries of %s kept, got %d", trace, n)
+		}
+	}
+	if len(keptTraces) < 200 || len(keptTraces) > 300 {
+		t.Errorf("Expected about 250 of 1000 traces kept, got %d", len(keptTraces))
+	}
+
+	// Another processor makes the same decisions
+	other, _ := NewSampleProcessor(map[string]interface{}{"mode": "hash", "rate": 0.25, "field": "trace_id"})
+	for trace := range keptTraces {
+		entry := models.NewLogEntry()
+		entry.AddField("trace_id", trace)
+		if results, _ := other.Process(entry); len(results) != 1 {
+			t.Fatalf("Expected %s kept by every processor", trace)
+		}
+	}
+}
+
+func TestSampleDynamic(t *testing.T) {
+	p, err := NewSampleProcessor(map[string]interface{}{"mode": "dynamic", "target": 10, "window": "1s"})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	p.start = now
+
+	noisy := func(i int, entry *models.LogEntry) { entry.Service = "noisy" }
+	quiet := func(i int, entry *models.LogEntry) { entry.Service = "quiet" }
+
+	// New keys are kept in full during the first window
+	if kept := sampleEntries(t, p, 1000, noisy); len(kept) != 1000 {
+		t.Errorf("Expected new key kept in full, got %d", len(kept))
+	}
+	sampleEntries(t, p, 5, quiet)
+
+	// 1000 events in a second against a target of 10 per second
+	now = now.Add(time.Second)
+	kept := sampleEntries(t, p, 1000, noisy)
+	if len(kept) < 3 || len(kept) > 25 {
+		t.Errorf("Expected about 10 noisy entries kept, got %d", len(kept))
+	}
+	if rate, _ := kept[0].GetField(SampleRateField); rate != 100.0 {
+		t.Errorf("Expected sample_rate 100, got %v", rate)
+	}
+	if kept := sampleEntries(t, p, 5, quiet); len(kept) != 5 {
+		t.Errorf("Expected quiet key below target kept in full, got %d", len(kept))
+	}
+}
+
+func TestSampleDynamicAfterQuietPeriod(t *testing.T) {
+	p, _ := NewSampleProcessor(map[string]interface{}{"mode": "dynamic", "target": 10, "window": "1s"})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	p.start = now
+
+	service := func(i int, entry *models.LogEntry) { entry.Service = "api" }
+
+	// 100 events spread over ten seconds are exactly the target rate
+	sampleEntries(t, p, 100, service)
+	now = now.Add(10 * time.Second)
+	if kept := sampleEntries(t, p, 100, service); len(kept) != 100 {
+		t.Errorf("Expected traffic at the target rate kept in full, got %d", len(kept))
+	}
+}
+
+func TestSampleInvalidConfig(t *testing.T) {
+	invalid := map[string]map[string]interface{}{
+		"no rate":        {},
+		"rate above 1":   {"rate": 1.5},
+		"zero rate":      {"rate": 0},
+		"hash no field":  {"mode": "hash", "rate": 0.5},
+		"dynamic target": {"mode": "dynamic"},
+		"bad window":     {"mode": "dynamic", "target": 1, "window": "soon"},
+		"unknown mode":   {"mode": "reservoir", "rate": 0.5},
+	}
+	for name, fields := range invalid {
+		if _, err := NewSampleProcessor(fields); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// SplitIndexField records the position of a split entry in its source
+const SplitIndexField = "split_index"
+
+// SplitProcessor splits one entry into one entry per element of an array,
+// such as a batch of events logged as a single JSON array
+type SplitProcessor struct {
+	field     string
+	target    string
+	separator string
+}
+
+// NewSplitProcessor creates a new split processor.
+//
+// Supported options:
+//   - field: field holding the array, either as a list or as a JSON array
+//     string (default "message"); message and raw name the entry payload
+//   - separator: split a string on this separator instead of parsing JSON
+//   - target: field that receives each element; when empty, object
+//     elements are merged into Fields (a "message" key becomes the
+//     message) and other elements become the message
+func NewSplitProcessor(fields map[string]interface{}) (*SplitProcessor, error) {
+	return &SplitProcessor{
+		field:     stringValue(fields, "field", "message"),
+		target:    stringValue(fields, "target", ""),
+		separator: stringValue(fields, "separator", ""),
+	}, nil
+}
+
+// Process splits the entry. Entries without an array, including strings
+// that start with "[" but are not a JSON array, such as "[INFO] started",
+// are passed on unchanged and an empty array drops the entry.
+func (p *SplitProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	value, exists := expr.Lookup(entry, p.field)
+	if !exists {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	items := p.elements(value)
+	if items == nil {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	payload := p.field == "message" || p.field == "raw"
+	results := make([]*models.LogEntry, 0, len(items))
+	for i, item := range items {
+		split := entry.Clone()
+		if entry.ID != "" {
+			split.ID = fmt.Sprintf("%s-%d", entry.ID, i)
+		}
+		if !payload {
+			delete(split.Fields, p.field)
+		}
+		p.apply(split, item, payload)
+		split.AddField(SplitIndexField, i)
+		results = append(results, split)
+	}
+	return results, nil
+}
+
+// elements extracts the array elements from a value. It returns nil when
+// the value is not an array.
+func (p *SplitProcessor) elements(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+
+	case string:
+		if p.separator != "" {
+			parts := strings.Split(v, p.separator)
+			items := make([]interface{}, 0, len(parts))
+			for _, part := range parts {
+				if strings.TrimSpace(part) != "" {
+					items = append(items, part)
+				}
+			}
+			return items
+		}
+
+		// Plain text often starts with a bracket, e.g. "[INFO] started"
+		trimmed := strings.TrimSpace(v)
+		if !strings.HasPrefix(trimmed, "[") {
+			return nil
+		}
+		var items []interface{}
+		if err := json.Unmarshal([]byte(trimmed), &items); err != nil {
+			return nil
+		}
+		return items
+	}
+
+	return nil
+}
+
+// apply stores one element on a split entry
+func (p *SplitProcessor) apply(entry *models.LogEntry, item interface{}, payload bool) {
+	if payload {
+		text, ok := item.(string)
+		if !ok {
+			text = toJSONString(item)
+		}
+		entry.Raw = text
+		entry.Message = text
+	}
+
+	if p.target != "" {
+		entry.AddField(p.target, item)
+		return
+	}
+
+	switch v := item.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if message, ok := value.(string); ok && key == "message" {
+				entry.Message = message
+				continue
+			}
+			entry.AddField(key, value)
+		}
+	case string:
+		entry.Message = v
+	default:
+		entry.Message = expr.ToString(v)
+	}
+}
+
+// Name returns the processor name
+func (p *SplitProcessor) Name() string {
+	return "split"
+}
+
+// toJSONString encodes a value as JSON
+func toJSONString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
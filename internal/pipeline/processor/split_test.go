@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func split(t *testing.T, fields map[string]interface{}, entry *models.LogEntry) []*models.LogEntry {
+	t.Helper()
+
+	p, err := NewSplitProcessor(fields)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	results, err := p.Process(entry)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	return results
+}
+
+func TestSplitJSONMessage(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.ID = "batch"
+	entry.Service = "checkout"
+	entry.Message = `[{"message": "paid", "amount": 5}, {"message": "shipped"}]`
+
+	results := split(t, map[string]interface{}{}, entry)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(results))
+	}
+
+	first := results[0]
+	if first.Message != "paid" || first.ID != "batch-0" || first.Service != "checkout" {
+		t.Errorf("Expected first element with copied entry, got %q %s %s", first.Message, first.ID, first.Service)
+	}
+	if amount, _ := first.GetField("amount"); amount != 5.0 {
+		t.Errorf("Expected amount field, got %v", amount)
+	}
+	if index, _ := results[1].GetField(SplitIndexField); index != 1 {
+		t.Errorf("Expected split_index 1, got %v", index)
+	}
+	if _, ok := results[1].GetField("amount"); ok {
+		t.Error("Expected elements not to share fields")
+	}
+}
+
+func TestSplitFieldList(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.Message = "batch"
+	entry.AddField("events", []interface{}{"login", "logout"})
+
+	results := split(t, map[string]interface{}{"field": "events", "target": "event"}, entry)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(results))
+	}
+	for i, expected := range []string{"login", "logout"} {
+		if event, _ := results[i].GetField("event"); event != expected {
+			t.Errorf("Entry %d: expected event %s, got %v", i, expected, event)
+		}
+		if _, ok := results[i].GetField("events"); ok {
+			t.Errorf("Entry %d: expected source field to be removed", i)
+		}
+		if results[i].Message != "batch" {
+			t.Errorf("Entry %d: expected message to be kept, got %q", i, results[i].Message)
+		}
+	}
+}
+
+func TestSplitSeparator(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.Message = "first\nsecond\n\nthird\n"
+
+	results := split(t, map[string]interface{}{"separator": "\n"}, entry)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(results))
+	}
+	if results[2].Message != "third" || results[2].Raw != "third" {
+		t.Errorf("Expected third line as message and raw, got %q %q", results[2].Message, results[2].Raw)
+	}
+}
+
+func TestSplitPassesAndDrops(t *testing.T) {
+	plain := models.NewLogEntry()
+	plain.Message = "not an array"
+	if results := split(t, map[string]interface{}{}, plain); len(results) != 1 || results[0] != plain {
+		t.Error("Expected entry without an array to pass unchanged")
+	}
+
+	missing := models.NewLogEntry()
+	if results := split(t, map[string]interface{}{"field": "events"}, missing); len(results) != 1 {
+		t.Error("Expected entry without the field to pass")
+	}
+
+	empty := models.NewLogEntry()
+	empty.Message = "[]"
+	if results := split(t, map[string]interface{}{}, empty); len(results) != 0 {
+		t.Errorf("Expected empty array to drop the entry, got %d", len(results))
+	}
+
+	// Text that only starts with a bracket is not an array
+	for _, message := range []string{"[INFO] started", "[2024-01-01] job done", "[1, 2"} {
+		entry := models.NewLogEntry()
+		entry.Message = message
+		if results := split(t, map[string]interface{}{}, entry); len(results) != 1 || results[0].Message != message {
+			t.Errorf("Expected %q to pass unchanged", message)
+		}
+	}
+}
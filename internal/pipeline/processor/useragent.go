@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/lru"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"github.com/UmangDiyora/logpipeline/pkg/useragent"
+)
+
+// UserAgentProcessor breaks a user agent string into browser, operating
+// system and device fields
+type UserAgentProcessor struct {
+	field  string
+	target string
+	parser *useragent.Parser
+	cache  *lru.Cache[string, *useragent.Client]
+}
+
+// NewUserAgentProcessor creates a new user agent processor.
+//
+// Supported options:
+//   - field: field holding the user agent (default "http_user_agent")
+//   - target: prefix for the added fields (default "user_agent")
+//   - regexes_file: uap-core regexes.yaml to use instead of the embedded
+//     set. The embedded set is a hand-picked subset of uap-core covering
+//     common browsers, operating systems, devices, bots and HTTP clients;
+//     point this at the full uap-core file for finer device and crawler
+//     detection
+//   - cache_size: number of parsed user agents to cache (default 10000)
+//
+// Added fields are browser, browser_version, os, os_version, device,
+// device_type (desktop, mobile, tablet, bot or other) and is_bot.
+func NewUserAgentProcessor(fields map[string]interface{}) (*UserAgentProcessor, error) {
+	p := &UserAgentProcessor{
+		field:  stringValue(fields, "field", "http_user_agent"),
+		target: stringValue(fields, "target", "user_agent"),
+		parser: useragent.Default(),
+	}
+	if p.target != "" && !strings.HasSuffix(p.target, ".") {
+		p.target += "."
+	}
+
+	if path := stringValue(fields, "regexes_file", ""); path != "" {
+		parser, err := useragent.NewFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user agent regexes: %w", err)
+		}
+		p.parser = parser
+	}
+
+	cacheSize := 10000
+	if size, ok := floatValue(fields["cache_size"]); ok && size >= 0 {
+		cacheSize = int(size)
+	}
+	p.cache = lru.New[string, *useragent.Client](cacheSize)
+
+	return p, nil
+}
+
+// Process parses the user agent field
+func (p *UserAgentProcessor) Process(entry *models.LogEntry) ([]*models.LogEntry, error) {
+	value, exists := expr.Lookup(entry, p.field)
+	if !exists {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	ua := expr.ToString(value)
+	if ua == "" || ua == "-" {
+		return []*models.LogEntry{entry}, nil
+	}
+
+	client, cached := p.cache.Get(ua)
+	if !cached {
+		client = p.parser.Parse(ua)
+		p.cache.Add(ua, client)
+	}
+
+	entry.AddField(p.target+"browser", client.Family)
+	entry.AddField(p.target+"os", client.OS)
+	entry.AddField(p.target+"device", client.Device)
+	entry.AddField(p.target+"device_type", client.DeviceType)
+	entry.AddField(p.target+"is_bot", client.Bot)
+	if version := client.Version(); version != "" {
+		entry.AddField(p.target+"browser_version", version)
+	}
+	if version := client.OSVersion(); version != "" {
+		entry.AddField(p.target+"os_version", version)
+	}
+	return []*models.LogEntry{entry}, nil
+}
+
+// Name returns the processor name
+func (p *UserAgentProcessor) Name() string {
+	return "user_agent"
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+const chromeWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36"
+
+// parseUserAgent runs one user agent through a processor
+func parseUserAgent(t *testing.T, p *UserAgentProcessor, field, ua string) *models.LogEntry {
+	t.Helper()
+
+	entry := models.NewLogEntry()
+	entry.AddField(field, ua)
+	results, err := p.Process(entry)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(results))
+	}
+	return entry
+}
+
+// expectFields checks field values on an entry
+func expectFields(t *testing.T, entry *models.LogEntry, expected map[string]interface{}) {
+	t.Helper()
+
+	for field, want := range expected {
+		if got, _ := entry.GetField(field); got != want {
+			t.Errorf("Expected %s = %v, got %v", field, want, got)
+		}
+	}
+}
+
+func TestUserAgentDefaultFields(t *testing.T) {
+	p, err := NewUserAgentProcessor(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := parseUserAgent(t, p, "http_user_agent", chromeWindows)
+	expectFields(t, entry, map[string]interface{}{
+		"user_agent.browser":         "Chrome",
+		"user_agent.browser_version": "120.0.6099",
+		"user_agent.os":              "Windows",
+		"user_agent.device_type":     "desktop",
+		"user_agent.is_bot":          false,
+	})
+}
+
+func TestUserAgentTarget(t *testing.T) {
+	tests := map[string]string{
+		"client":  "client.browser",
+		"client.": "client.browser",
+	}
+
+	for target, field := range tests {
+		p, err := NewUserAgentProcessor(map[string]interface{}{"field": "ua", "target": target})
+		if err != nil {
+			t.Fatalf("Failed to create processor: %v", err)
+		}
+		entry := parseUserAgent(t, p, "ua", chromeWindows)
+		if browser, _ := entry.GetField(field); browser != "Chrome" {
+			t.Errorf("target %q: expected %s to be set, got %v", target, field, entry.Fields)
+		}
+	}
+}
+
+func TestUserAgentBots(t *testing.T) {
+	p, err := NewUserAgentProcessor(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	tests := map[string]string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": "Googlebot",
+		"curl/8.4.0": "curl",
+		"Mozilla/5.0 (compatible; ExampleCrawler/1.0)": "ExampleCrawler",
+	}
+
+	for ua, family := range tests {
+		entry := parseUserAgent(t, p, "http_user_agent", ua)
+		expectFields(t, entry, map[string]interface{}{
+			"user_agent.browser":     family,
+			"user_agent.device_type": "bot",
+			"user_agent.is_bot":      true,
+		})
+	}
+}
+
+func TestUserAgentMissingOrEmpty(t *testing.T) {
+	p, err := NewUserAgentProcessor(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	for _, ua := range []string{"", "-"} {
+		entry := parseUserAgent(t, p, "http_user_agent", ua)
+		if _, ok := entry.GetField("user_agent.browser"); ok {
+			t.Errorf("Expected %q to be skipped", ua)
+		}
+	}
+
+	entry := models.NewLogEntry()
+	p.Process(entry)
+	if len(entry.Fields) != 0 {
+		t.Errorf("Expected entry without the field to pass unchanged, got %v", entry.Fields)
+	}
+}
+
+func TestUserAgentRegexesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regexes.yaml")
+	regexes := `user_agent_parsers:
+  - regex: '(ShopApp)/(\d+)\.(\d+)'
+    family_replacement: 'Shop App'
+os_parsers:
+  - regex: '(Android) (\d+)'
+`
+	if err := os.WriteFile(path, []byte(regexes), 0644); err != nil {
+		t.Fatalf("Failed to write regexes file: %v", err)
+	}
+
+	p, err := NewUserAgentProcessor(map[string]interface{}{"regexes_file": path})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	entry := parseUserAgent(t, p, "http_user_agent", "ShopApp/4.2 (Android 14; Mobile)")
+	expectFields(t, entry, map[string]interface{}{
+		"user_agent.browser":         "Shop App",
+		"user_agent.browser_version": "4.2",
+		"user_agent.os":              "Android",
+		"user_agent.os_version":      "14",
+		"user_agent.device_type":     "mobile",
+	})
+
+	// The embedded set is not consulted once a file is given
+	entry = parseUserAgent(t, p, "http_user_agent", chromeWindows)
+	expectFields(t, entry, map[string]interface{}{"user_agent.browser": "Other"})
+}
+
+func TestUserAgentInvalidRegexesFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"invalid regex": "user_agent_parsers:\n  - regex: '(unclosed'\n",
+		"no parsers":    "os_parsers:\n  - regex: '(Linux)'\n",
+		"invalid yaml":  "user_agent_parsers: [\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write regexes file: %v", err)
+		}
+		if _, err := NewUserAgentProcessor(map[string]interface{}{"regexes_file": path}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := NewUserAgentProcessor(map[string]interface{}{"regexes_file": filepath.Join(dir, "missing.yaml")}); err == nil {
+		t.Error("Expected error for a missing regexes file")
+	}
+}
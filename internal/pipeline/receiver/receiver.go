@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +29,10 @@ type Config struct {
 	// Timeout for requests
 	Timeout time.Duration
 
+	// RetryAfter is suggested to clients whose batch did not fit in the
+	// output buffer
+	RetryAfter time.Duration
+
 	// Authentication
 	RequireAuth bool
 	APIKeys     map[string]string
@@ -40,6 +45,7 @@ func DefaultConfig() *Config {
 		MaxBatchSize: 10000,
 		RateLimit:    1000,
 		Timeout:      30 * time.Second,
+		RetryAfter:   time.Second,
 		RequireAuth:  false,
 		APIKeys:      make(map[string]string),
 	}
@@ -56,6 +62,12 @@ type Receiver struct {
 	stats      Stats
 	rateLimits map[string]*rateLimiter
 	rlMu       sync.RWMutex
+
+	// enqueueMu makes each batch enqueue as a whole
+	enqueueMu sync.Mutex
+
+	// handlers are extra API endpoints served next to ingestion
+	handlers map[string]http.Handler
 }
 
 // Stats holds receiver statistics
@@ -65,6 +77,9 @@ type Stats struct {
 	BytesReceived    uint64
 	Errors           uint64
 	LastReceived     time.Time
+
+	// Throttled counts batches refused because the output was full
+	Throttled uint64
 }
 
 // rateLimiter implements a simple token bucket rate limiter
@@ -89,17 +104,28 @@ func New(config *Config, output chan *models.LogEntry) (*Receiver, error) {
 		ctx:        ctx,
 		cancel:     cancel,
 		rateLimits: make(map[string]*rateLimiter),
+		handlers:   make(map[string]http.Handler),
 	}
 
 	return r, nil
 }
 
+// Handle registers an extra API endpoint. Patterns follow http.ServeMux
+// and handlers are subject to the same authentication as ingestion. It
+// must be called before Start.
+func (r *Receiver) Handle(pattern string, handler http.Handler) {
+	r.handlers[pattern] = handler
+}
+
 // Start starts the receiver
 func (r *Receiver) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/v1/logs/ingest", r.handleIngest)
 	mux.HandleFunc("/api/v1/health", r.handleHealth)
 	mux.HandleFunc("/api/v1/stats", r.handleStats)
+	for pattern, handler := range r.handlers {
+		mux.Handle(pattern, handler)
+	}
 
 	r.server = &http.Server{
 		Addr:         r.config.HTTPAddr,
@@ -200,6 +226,7 @@ func (r *Receiver) handleIngest(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if !r.checkRateLimit(agentID) {
+		w.Header().Set("Retry-After", "1")
 		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 		atomic.AddUint64(&r.stats.Errors, 1)
 		return
@@ -244,20 +271,33 @@ func (r *Receiver) handleIngest(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Send entries to output channel
+	if len(batch.Entries) > cap(r.output) {
+		http.Error(w, "Batch larger than output buffer", http.StatusRequestEntityTooLarge)
+		atomic.AddUint64(&r.stats.Errors, 1)
+		return
+	}
+
+	if r.ctx.Err() != nil {
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Accept the whole batch or none of it. The receiver is the only
+	// writer to the output, so free space cannot shrink while the batch
+	// is enqueued.
+	r.enqueueMu.Lock()
+	if cap(r.output)-len(r.output) < len(batch.Entries) {
+		r.enqueueMu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(r.retryAfterSeconds()))
+		http.Error(w, "Output buffer full", http.StatusTooManyRequests)
+		atomic.AddUint64(&r.stats.Throttled, 1)
+		return
+	}
 	for _, entry := range batch.Entries {
-		select {
-		case r.output <- entry:
-			atomic.AddUint64(&r.stats.LogsReceived, 1)
-		case <-r.ctx.Done():
-			http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
-			return
-		default:
-			http.Error(w, "Output buffer full", http.StatusServiceUnavailable)
-			atomic.AddUint64(&r.stats.Errors, 1)
-			return
-		}
+		r.output <- entry
 	}
+	r.enqueueMu.Unlock()
+	atomic.AddUint64(&r.stats.LogsReceived, uint64(len(batch.Entries)))
 
 	r.stats.LastReceived = time.Now()
 
@@ -269,6 +309,15 @@ func (r *Receiver) handleIngest(w http.ResponseWriter, req *http.Request) {
 	})
 }
 
+// retryAfterSeconds returns the Retry-After value in whole seconds
+func (r *Receiver) retryAfterSeconds() int {
+	seconds := int((r.config.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
 // handleHealth handles health check requests
 func (r *Receiver) handleHealth(w http.ResponseWriter, req *http.Request) {
 	health := map[string]interface{}{
@@ -287,6 +336,7 @@ func (r *Receiver) handleStats(w http.ResponseWriter, req *http.Request) {
 		"logs_received":     atomic.LoadUint64(&r.stats.LogsReceived),
 		"bytes_received":    atomic.LoadUint64(&r.stats.BytesReceived),
 		"errors":            atomic.LoadUint64(&r.stats.Errors),
+		"throttled":         atomic.LoadUint64(&r.stats.Throttled),
 		"last_received":     r.stats.LastReceived,
 	}
 
@@ -0,0 +1,406 @@
+// Package registry keeps pipelines managed through the API next to the
+// pipelines of the configuration file, and runs the enabled ones.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/processor"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+var (
+	// ErrExists is returned when creating a pipeline whose name is taken
+	ErrExists = errors.New("pipeline already exists")
+
+	// ErrReadOnly is returned when changing a pipeline of the
+	// configuration file
+	ErrReadOnly = errors.New("pipeline is defined in the configuration file")
+)
+
+// namePattern matches valid pipeline names, which are also file names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Registry holds the pipeline definitions and applies them to a manager.
+// Pipelines of the configuration file are read-only; pipelines created
+// through the API are stored as JSON files in a directory.
+type Registry struct {
+	mu       sync.Mutex
+	dir      string
+	files    string
+	manager  *pipeline.Manager
+	static   []config.PipelineConfig
+	routing  config.RoutingConfig
+	managed  map[string]*models.Pipeline
+	loadedAt time.Time
+}
+
+// Open opens the store in dir and loads the pipelines saved there. They
+// run once Load is called with the pipelines of the configuration file.
+// Files that stored pipelines read or write must be in the files
+// directory.
+func Open(dir, files string, manager *pipeline.Manager) (*Registry, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create pipeline directory: %w", err)
+	}
+	files, err := filepath.Abs(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve pipeline files directory: %w", err)
+	}
+
+	r := &Registry{
+		dir:     dir,
+		files:   files,
+		manager: manager,
+		managed: make(map[string]*models.Pipeline),
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range paths {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pipeline: %w", err)
+		}
+		var p models.Pipeline
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("failed to parse pipeline %s: %w", filepath.Base(file), err)
+		}
+		r.managed[p.ID] = &p
+	}
+
+	return r, nil
+}
+
+// Load runs the pipelines and routing of the configuration file together
+// with the enabled stored pipelines. It is used at startup and on reload.
+func (r *Registry) Load(static []config.PipelineConfig, routing config.RoutingConfig) (*pipeline.ReloadSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, def := range static {
+		if _, ok := r.managed[def.Name]; ok {
+			return nil, fmt.Errorf("pipeline %q is defined in the configuration file and through the API", def.Name)
+		}
+	}
+
+	summary, err := r.apply(static, routing, r.managed)
+	if err != nil {
+		return nil, err
+	}
+	r.static = static
+	r.routing = routing
+	r.loadedAt = time.Now()
+	return summary, nil
+}
+
+// apply runs a set of definitions. On error the definitions are not in
+// effect and must not be recorded.
+func (r *Registry) apply(static []config.PipelineConfig, routing config.RoutingConfig, managed map[string]*models.Pipeline) (*pipeline.ReloadSummary, error) {
+	defs := make([]config.PipelineConfig, 0, len(static)+len(managed))
+	defs = append(defs, static...)
+
+	// Stored pipelines are routed after the configuration file, oldest first
+	ordered := make([]*models.Pipeline, 0, len(managed))
+	for _, p := range managed {
+		if p.Enabled {
+			ordered = append(ordered, p)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if !ordered[i].CreatedAt.Equal(ordered[j].CreatedAt) {
+			return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+	for _, p := range ordered {
+		def, err := pipeline.FromModel(p)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", p.ID, err)
+		}
+		defs = append(defs, *def)
+	}
+
+	return r.manager.Apply(defs, routing)
+}
+
+// List returns every pipeline with its live status and metrics, those of
+// the configuration file first
+func (r *Registry) List() []*models.Pipeline {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]*models.Pipeline, 0, len(r.static)+len(r.managed))
+	for i := range r.static {
+		result = append(result, r.staticModel(&r.static[i]))
+	}
+
+	ids := make([]string, 0, len(r.managed))
+	for id := range r.managed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		result = append(result, r.withStatus(r.managed[id]))
+	}
+	return result
+}
+
+// Get returns a pipeline by ID
+func (r *Registry) Get(id string) (*models.Pipeline, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.managed[id]; ok {
+		return r.withStatus(p), nil
+	}
+	for i := range r.static {
+		if r.static[i].Name == id {
+			return r.staticModel(&r.static[i]), nil
+		}
+	}
+	return nil, models.ErrPipelineNotFound
+}
+
+// Create adds and starts a pipeline. Its ID is its name.
+func (r *Registry) Create(p *models.Pipeline) (*models.Pipeline, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !namePattern.MatchString(p.Name) {
+		return nil, fmt.Errorf("%w: invalid name %q", models.ErrInvalidPipeline, p.Name)
+	}
+	if r.exists(p.Name) {
+		return nil, ErrExists
+	}
+
+	now := time.Now()
+	created := *p
+	created.ID = p.Name
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	return r.save(&created)
+}
+
+// Update replaces the definition of a stored pipeline, keeping whether it
+// is enabled
+func (r *Registry) Update(id string, p *models.Pipeline) (*models.Pipeline, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.stored(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Name != "" && p.Name != id {
+		return nil, fmt.Errorf("%w: pipelines cannot be renamed", models.ErrInvalidPipeline)
+	}
+
+	updated := *p
+	updated.ID = id
+	updated.Name = id
+	updated.Enabled = current.Enabled
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now()
+	return r.save(&updated)
+}
+
+// SetEnabled starts or stops a stored pipeline
+func (r *Registry) SetEnabled(id string, enabled bool) (*models.Pipeline, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.stored(id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Enabled == enabled {
+		return r.withStatus(current), nil
+	}
+
+	updated := *current
+	updated.Enabled = enabled
+	updated.UpdatedAt = time.Now()
+	return r.save(&updated)
+}
+
+// Delete stops and removes a stored pipeline
+func (r *Registry) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.stored(id); err != nil {
+		return err
+	}
+
+	next := r.without(id)
+	if _, err := r.apply(r.static, r.routing, next); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidPipeline, err)
+	}
+	r.managed = next
+
+	if err := os.Remove(r.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete pipeline: %w", err)
+	}
+	return nil
+}
+
+// save applies a changed pipeline and writes it to disk. The caller holds
+// the lock.
+func (r *Registry) save(p *models.Pipeline) (*models.Pipeline, error) {
+	def, err := pipeline.FromModel(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidPipeline, err)
+	}
+	if err := r.CheckFiles(def); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidPipeline, err)
+	}
+
+	if p.Output.Type == "" {
+		p.Output.Type = pipeline.OutputStorage
+	}
+
+	// Status and metrics are live values and are not stored
+	p.Status = ""
+	p.Metrics = models.PipelineMetrics{}
+
+	next := r.without(p.ID)
+	next[p.ID] = p
+	if _, err := r.apply(r.static, r.routing, next); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidPipeline, err)
+	}
+	r.managed = next
+
+	if err := r.write(p); err != nil {
+		return nil, err
+	}
+	return r.withStatus(p), nil
+}
+
+// CheckFiles rejects a definition naming a file outside the files
+// directory. API clients must not make the server read or write
+// arbitrary paths, whether a definition is stored or only simulated.
+func (r *Registry) CheckFiles(def *config.PipelineConfig) error {
+	if def.SpillDir != "" && !r.inFiles(def.SpillDir) {
+		return fmt.Errorf("spill_dir must be in %s", r.files)
+	}
+	for i, proc := range def.Processors {
+		for _, option := range processor.FileOptions[proc.Type] {
+			path, ok := proc.Config[option].(string)
+			if ok && path != "" && !r.inFiles(path) {
+				return fmt.Errorf("processor[%d] %s %s must be in %s", i, proc.Type, option, r.files)
+			}
+		}
+	}
+	return nil
+}
+
+// inFiles reports whether path is in the files directory
+func (r *Registry) inFiles(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(r.files, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// write stores a pipeline atomically
+func (r *Registry) write(p *models.Pipeline) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pipeline: %w", err)
+	}
+
+	tmp := r.path(p.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pipeline: %w", err)
+	}
+	if err := os.Rename(tmp, r.path(p.ID)); err != nil {
+		return fmt.Errorf("failed to write pipeline: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a stored pipeline
+func (r *Registry) path(id string) string {
+	return filepath.Join(r.dir, id+".json")
+}
+
+// without returns the stored pipelines except one
+func (r *Registry) without(id string) map[string]*models.Pipeline {
+	next := make(map[string]*models.Pipeline, len(r.managed))
+	for key, p := range r.managed {
+		if key != id {
+			next[key] = p
+		}
+	}
+	return next
+}
+
+// exists reports whether a pipeline name is taken
+func (r *Registry) exists(name string) bool {
+	if _, ok := r.managed[name]; ok {
+		return true
+	}
+	for _, def := range r.static {
+		if def.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stored returns a stored pipeline, distinguishing pipelines of the
+// configuration file
+func (r *Registry) stored(id string) (*models.Pipeline, error) {
+	if p, ok := r.managed[id]; ok {
+		return p, nil
+	}
+	for _, def := range r.static {
+		if def.Name == id {
+			return nil, ErrReadOnly
+		}
+	}
+	return nil, models.ErrPipelineNotFound
+}
+
+// staticModel returns a pipeline of the configuration file
+func (r *Registry) staticModel(def *config.PipelineConfig) *models.Pipeline {
+	p := pipeline.ToModel(def)
+	p.CreatedAt = r.loadedAt
+	p.UpdatedAt = r.loadedAt
+	return r.withStatus(p)
+}
+
+// withStatus returns a copy of a pipeline with its live status and metrics
+func (r *Registry) withStatus(p *models.Pipeline) *models.Pipeline {
+	result := *p
+	result.Metrics = models.PipelineMetrics{}
+
+	running, ok := r.manager.Pipeline(p.ID)
+	switch {
+	case ok:
+		result.Status = models.PipelineStatusRunning
+		stats := running.GetStats()
+		result.Metrics = pipeline.ToMetrics(&stats)
+	case !p.Enabled:
+		result.Status = models.PipelineStatusStopped
+	default:
+		result.Status = models.PipelineStatusError
+	}
+	return &result
+}
@@ -0,0 +1,208 @@
+package registry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func newRegistry(t *testing.T, dir string) (*Registry, *pipeline.Manager, chan *models.LogEntry, chan *models.LogEntry) {
+	t.Helper()
+	input := make(chan *models.LogEntry, 10)
+	output := make(chan *models.LogEntry, 10)
+	manager := pipeline.NewManager(input, output, nil)
+	t.Cleanup(manager.Stop)
+
+	r, err := Open(dir, filepath.Join(dir, "files"), manager)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	static := []config.PipelineConfig{{Name: "default"}}
+	if _, err := r.Load(static, config.RoutingConfig{}); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return r, manager, input, output
+}
+
+func apiPipeline(name string) *models.Pipeline {
+	p := models.NewPipeline(name)
+	p.Input = models.InputConfig{Type: "json", Filter: "source:api"}
+	p.Processors = []models.ProcessorConfig{
+		{Type: "add_fields", Config: map[string]interface{}{"team": "payments"}},
+	}
+	return p
+}
+
+func receive(t *testing.T, output chan *models.LogEntry) *models.LogEntry {
+	t.Helper()
+	select {
+	case entry := <-output:
+		return entry
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for entry")
+		return nil
+	}
+}
+
+func TestCreateRunsPipeline(t *testing.T) {
+	r, _, input, output := newRegistry(t, t.TempDir())
+
+	created, err := r.Create(apiPipeline("payments"))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.ID != "payments" || created.Status != models.PipelineStatusRunning {
+		t.Errorf("Unexpected pipeline: %+v", created)
+	}
+
+	input <- &models.LogEntry{Source: "api", Raw: `{"amount": 5}`}
+	entry := receive(t, output)
+	if entry.Fields["team"] != "payments" || entry.Fields["amount"] != float64(5) {
+		t.Errorf("Expected entry processed by the new pipeline, got %v", entry.Fields)
+	}
+
+	got, err := r.Get("payments")
+	if err != nil || got.Metrics.LogsProcessed != 1 {
+		t.Errorf("Expected live metrics, got %+v (%v)", got, err)
+	}
+
+	if _, err := r.Create(apiPipeline("payments")); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	if _, err := r.Create(apiPipeline("default")); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists for a config file pipeline, got %v", err)
+	}
+}
+
+func TestInvalidPipelineIsRejected(t *testing.T) {
+	r, _, _, _ := newRegistry(t, t.TempDir())
+
+	invalid := apiPipeline("broken")
+	invalid.Input.Type = "nope"
+	if _, err := r.Create(invalid); !errors.Is(err, models.ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline, got %v", err)
+	}
+
+	invalid = apiPipeline("../escape")
+	if _, err := r.Create(invalid); !errors.Is(err, models.ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline for name, got %v", err)
+	}
+
+	if len(r.List()) != 1 {
+		t.Errorf("Expected only the config file pipeline, got %d", len(r.List()))
+	}
+}
+
+func TestFilesOutsideDirectoryAreRejected(t *testing.T) {
+	dir := t.TempDir()
+	r, _, _, _ := newRegistry(t, dir)
+	files := filepath.Join(dir, "files")
+
+	spill := apiPipeline("spill")
+	spill.Output.Config = map[string]interface{}{"overflow": "spill_to_disk", "spill_dir": filepath.Join(files, "..", "elsewhere")}
+	if _, err := r.Create(spill); !errors.Is(err, models.ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline for spill_dir, got %v", err)
+	}
+
+	// A readable lookup file next to the files directory
+	lookup := filepath.Join(dir, "owners.csv")
+	if err := os.WriteFile(lookup, []byte("service,team\napi,payments\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lookup file: %v", err)
+	}
+	enrich := apiPipeline("enrich")
+	enrich.Processors = []models.ProcessorConfig{
+		{Type: "enrich", Config: map[string]interface{}{"file": lookup, "fields": []interface{}{"service"}, "watch": false}},
+	}
+	if _, err := r.Create(enrich); !errors.Is(err, models.ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline for enrich file, got %v", err)
+	}
+
+	geoip := apiPipeline("geoip")
+	geoip.Processors = []models.ProcessorConfig{
+		{Type: "geoip", Config: map[string]interface{}{"field": "ip", "asn_database": "/var/lib/GeoLite2-ASN.mmdb"}},
+	}
+	if _, err := r.Create(geoip); !errors.Is(err, models.ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline for geoip database, got %v", err)
+	}
+
+	// A valid regexes file next to the files directory
+	regexes := filepath.Join(dir, "regexes.yaml")
+	if err := os.WriteFile(regexes, []byte("user_agent_parsers:\n  - regex: '(curl)/(\\d+)'\n"), 0644); err != nil {
+		t.Fatalf("Failed to write regexes file: %v", err)
+	}
+	userAgent := apiPipeline("user-agent")
+	userAgent.Processors = []models.ProcessorConfig{
+		{Type: "user_agent", Config: map[string]interface{}{"regexes_file": regexes}},
+	}
+	if _, err := r.Create(userAgent); !errors.Is(err, models.ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline for user agent regexes file, got %v", err)
+	}
+
+	spill.Output.Config["spill_dir"] = filepath.Join(files, "spill")
+	if _, err := r.Create(spill); err != nil {
+		t.Errorf("Create with spill_dir in the files directory failed: %v", err)
+	}
+}
+
+func TestUpdateEnableDelete(t *testing.T) {
+	r, manager, _, _ := newRegistry(t, t.TempDir())
+	if _, err := r.Create(apiPipeline("payments")); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	update := apiPipeline("")
+	update.Processors = nil
+	updated, err := r.Update("payments", update)
+	if err != nil || len(updated.Processors) != 0 || !updated.Enabled {
+		t.Errorf("Unexpected update: %+v (%v)", updated, err)
+	}
+
+	disabled, err := r.SetEnabled("payments", false)
+	if err != nil || disabled.Status != models.PipelineStatusStopped {
+		t.Errorf("Expected stopped pipeline, got %+v (%v)", disabled, err)
+	}
+	if _, ok := manager.Pipeline("payments"); ok {
+		t.Error("Expected disabled pipeline not to run")
+	}
+
+	if _, err := r.Update("default", update); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+
+	if err := r.Delete("payments"); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if _, err := r.Get("payments"); !errors.Is(err, models.ErrPipelineNotFound) {
+		t.Errorf("Expected ErrPipelineNotFound, got %v", err)
+	}
+}
+
+func TestPipelinesArePersisted(t *testing.T) {
+	dir := t.TempDir()
+	r, _, _, _ := newRegistry(t, dir)
+	if _, err := r.Create(apiPipeline("payments")); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	disabled := apiPipeline("audit")
+	disabled.Enabled = false
+	if _, err := r.Create(disabled); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	reopened, manager, _, _ := newRegistry(t, dir)
+	if len(reopened.List()) != 3 {
+		t.Fatalf("Expected 3 pipelines after reopen, got %d", len(reopened.List()))
+	}
+	if _, ok := manager.Pipeline("payments"); !ok {
+		t.Error("Expected stored pipeline to run after reopen")
+	}
+	if p, _ := reopened.Get("audit"); p.Status != models.PipelineStatusStopped {
+		t.Errorf("Expected disabled pipeline to stay stopped, got %s", p.Status)
+	}
+}
@@ -0,0 +1,410 @@
+// Package router distributes entries from the receiver to pipelines
+// according to each pipeline's filter.
+package router
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/UmangDiyora/logpipeline/pkg/expr"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Routing modes
+const (
+	// ModeFirst sends an entry to the first pipeline whose filter matches
+	ModeFirst = "first"
+
+	// ModeAll sends an entry to every pipeline whose filter matches
+	ModeAll = "all"
+)
+
+// Route connects a pipeline's filter to its input
+type Route struct {
+	// Name is the pipeline name
+	Name string
+
+	// Filter selects the entries for the pipeline. It is either an
+	// expression or the shorthand field:value. Only the default route may
+	// omit it; it then receives the entries no filter matches.
+	Filter string
+
+	// Output is the pipeline input
+	Output chan<- *models.LogEntry
+}
+
+// Config holds router configuration
+type Config struct {
+	// Mode is first or all (default first)
+	Mode string
+
+	// Default names the route for entries no filter matches. When empty,
+	// the first route without a filter is used.
+	Default string
+}
+
+// Stats holds router statistics
+type Stats struct {
+	// Routed counts entries sent to each route
+	Routed map[string]uint64
+
+	// Unmatched counts entries no filter matched and no default route took
+	Unmatched uint64
+
+	// Errors counts filters that failed to evaluate
+	Errors uint64
+}
+
+// compiledRoute is a route with its compiled filter
+type compiledRoute struct {
+	Route
+	filter *expr.Expression
+	routed uint64
+}
+
+// table is a compiled set of routes. done is closed when the table is
+// replaced, which aborts sends still waiting on its routes.
+type table struct {
+	mode     string
+	routes   []*compiledRoute
+	fallback *compiledRoute
+	done     chan struct{}
+}
+
+// Router reads entries from an input and sends them to pipelines
+type Router struct {
+	// mu guards the route table. Route holds it for reading while it
+	// sends, so that Update returns only once no entry is still on its
+	// way to a replaced route. Update aborts sends waiting on a full
+	// output before it takes the lock.
+	mu    sync.RWMutex
+	table *table
+
+	// updateMu serializes Update
+	updateMu sync.Mutex
+
+	input  <-chan *models.LogEntry
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// draining is closed by Drain; the router then stops once its input
+	// is empty
+	draining  chan struct{}
+	drainOnce sync.Once
+
+	unmatched uint64
+	errors    uint64
+}
+
+// New creates a router over a set of routes
+func New(config *Config, input <-chan *models.LogEntry, routes []Route) (*Router, error) {
+	t, err := compile(config, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Router{
+		table:    t,
+		input:    input,
+		ctx:      ctx,
+		cancel:   cancel,
+		draining: make(chan struct{}),
+	}, nil
+}
+
+// compile validates and compiles a set of routes
+func compile(config *Config, routes []Route) (*table, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	mode := config.Mode
+	if mode == "" {
+		mode = ModeFirst
+	}
+	if mode != ModeFirst && mode != ModeAll {
+		return nil, fmt.Errorf("invalid routing mode: %s", mode)
+	}
+
+	t := &table{mode: mode, done: make(chan struct{})}
+	names := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if names[route.Name] {
+			return nil, fmt.Errorf("duplicate route %q", route.Name)
+		}
+		names[route.Name] = true
+
+		compiled := &compiledRoute{Route: route}
+		if strings.TrimSpace(route.Filter) != "" {
+			filter, err := ParseFilter(route.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter for pipeline %s: %w", route.Name, err)
+			}
+			compiled.filter = filter
+		}
+		t.routes = append(t.routes, compiled)
+
+		if config.Default == "" && compiled.filter == nil && t.fallback == nil {
+			t.fallback = compiled
+		}
+		if config.Default != "" && route.Name == config.Default {
+			t.fallback = compiled
+		}
+	}
+
+	if config.Default != "" && t.fallback == nil {
+		return nil, fmt.Errorf("default pipeline %q does not exist", config.Default)
+	}
+
+	// Any other route without a filter would never receive an entry
+	for _, route := range t.routes {
+		if route.filter == nil && route != t.fallback {
+			return nil, fmt.Errorf("pipeline %s has no filter; only the default pipeline may omit it", route.Name)
+		}
+	}
+
+	return t, nil
+}
+
+// Validate checks a routing configuration without creating a router
+func Validate(config *Config, routes []Route) error {
+	_, err := compile(config, routes)
+	return err
+}
+
+// Update replaces the routes. Once Update returns, nothing more is sent
+// to the old routes' outputs. An entry waiting for a full output when
+// the routes change is routed again with the new routes, skipping the
+// routes with the same name it already went to. Counters carry over for
+// routes that keep their name.
+func (r *Router) Update(config *Config, routes []Route) error {
+	t, err := compile(config, routes)
+	if err != nil {
+		return err
+	}
+
+	// Only Update replaces the table, so it can be read without mu here
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+	close(r.table.done)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := make(map[string]uint64, len(r.table.routes))
+	for _, route := range r.table.routes {
+		previous[route.Name] = atomic.LoadUint64(&route.routed)
+	}
+	for _, route := range t.routes {
+		route.routed = previous[route.Name]
+	}
+
+	r.table = t
+	return nil
+}
+
+// shorthandPattern matches the field:value filter shorthand
+var shorthandPattern = regexp.MustCompile(`^\s*([A-Za-z_][\w.]*)\s*:\s*(.*?)\s*$`)
+
+// ParseFilter compiles a pipeline filter. Besides expressions it accepts
+// field:value, which matches a field equal to value, and field:pattern
+// with * wildcards, e.g. source:nginx or message:*refused*.
+func ParseFilter(filter string) (*expr.Expression, error) {
+	matches := shorthandPattern.FindStringSubmatch(filter)
+	if matches == nil {
+		return expr.Compile(filter)
+	}
+
+	field, value := matches[1], matches[2]
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	if value == "" {
+		return nil, fmt.Errorf("filter %q has no value", filter)
+	}
+
+	src := fmt.Sprintf("%s == %s", field, strconv.Quote(value))
+	if strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		src = fmt.Sprintf("%s =~ %s", field, strconv.Quote("^"+strings.Join(parts, ".*")+"$"))
+	}
+	return expr.Compile(src)
+}
+
+// Start starts routing entries
+func (r *Router) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+// run routes entries until the input closes, the router stops or it
+// drained its input
+func (r *Router) run() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case entry, ok := <-r.input:
+			if !ok {
+				return
+			}
+			r.Route(entry)
+		case <-r.draining:
+			select {
+			case entry, ok := <-r.input:
+				if !ok {
+					return
+				}
+				r.Route(entry)
+			default:
+				return
+			}
+		}
+	}
+}
+
+// Route sends an entry to the matching pipelines. It blocks while a
+// pipeline input is full, so that the receiver slows down instead of
+// entries being lost.
+func (r *Router) Route(entry *models.LogEntry) {
+	var delivered map[string]bool
+	for {
+		done, ok := r.route(entry, delivered)
+		if ok {
+			return
+		}
+		// The routes changed while a send waited
+		if delivered == nil {
+			delivered = make(map[string]bool)
+		}
+		for _, name := range done {
+			delivered[name] = true
+		}
+	}
+}
+
+// route sends an entry with the current routes, skipping the delivered
+// ones. It returns false along with the routes it sent to when the routes
+// were replaced before it finished.
+func (r *Router) route(entry *models.LogEntry, delivered map[string]bool) ([]string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t := r.table
+	targets := r.match(t, entry)
+	if len(targets) == 0 {
+		if len(delivered) == 0 {
+			atomic.AddUint64(&r.unmatched, 1)
+		}
+		return nil, true
+	}
+
+	pending := targets[:0:0]
+	for _, target := range targets {
+		if !delivered[target.Name] {
+			pending = append(pending, target)
+		}
+	}
+
+	var sent []string
+	for i, target := range pending {
+		// Pipelines modify entries in place, so each extra target gets a
+		// copy. The entry itself goes last, so it is never sent twice.
+		current := entry
+		if i < len(pending)-1 {
+			current = entry.Clone()
+		}
+
+		select {
+		case target.Output <- current:
+			atomic.AddUint64(&target.routed, 1)
+			sent = append(sent, target.Name)
+		case <-t.done:
+			return sent, false
+		case <-r.ctx.Done():
+			return sent, true
+		}
+	}
+	return sent, true
+}
+
+// match returns the routes of a table an entry goes to. The caller holds
+// the lock.
+func (r *Router) match(t *table, entry *models.LogEntry) []*compiledRoute {
+	var targets []*compiledRoute
+	for _, route := range t.routes {
+		if route.filter == nil {
+			continue
+		}
+		matched, err := route.filter.Match(entry)
+		if err != nil {
+			atomic.AddUint64(&r.errors, 1)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		targets = append(targets, route)
+		if t.mode == ModeFirst {
+			break
+		}
+	}
+
+	if len(targets) == 0 && t.fallback != nil {
+		targets = append(targets, t.fallback)
+	}
+	return targets
+}
+
+// Drain routes the entries left in the input and stops. Entries that
+// arrive meanwhile are routed too, so the sender should have stopped.
+// Routing stops outright when ctx is done.
+func (r *Router) Drain(ctx context.Context) {
+	r.drainOnce.Do(func() { close(r.draining) })
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	r.Stop()
+}
+
+// Stop stops routing
+func (r *Router) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// GetStats returns router statistics
+func (r *Router) GetStats() Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := Stats{
+		Routed:    make(map[string]uint64, len(r.table.routes)),
+		Unmatched: atomic.LoadUint64(&r.unmatched),
+		Errors:    atomic.LoadUint64(&r.errors),
+	}
+	for _, route := range r.table.routes {
+		stats.Routed[route.Name] = atomic.LoadUint64(&route.routed)
+	}
+	return stats
+}
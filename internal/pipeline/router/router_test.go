@@ -0,0 +1,298 @@
+package router
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func newEntry(source string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Source = source
+	entry.Message = "connection refused"
+	entry.Level = models.LogLevelError
+	return entry
+}
+
+func newRouter(t *testing.T, config *Config, routes []Route) *Router {
+	t.Helper()
+
+	r, err := New(config, nil, routes)
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+	return r
+}
+
+// received returns the number of entries waiting in each output
+func received(outputs map[string]chan *models.LogEntry) map[string]int {
+	counts := make(map[string]int, len(outputs))
+	for name, output := range outputs {
+		counts[name] = len(output)
+	}
+	return counts
+}
+
+func TestRouteModes(t *testing.T) {
+	for _, mode := range []string{ModeFirst, ModeAll} {
+		outputs := map[string]chan *models.LogEntry{
+			"nginx":  make(chan *models.LogEntry, 10),
+			"errors": make(chan *models.LogEntry, 10),
+			"rest":   make(chan *models.LogEntry, 10),
+		}
+		r := newRouter(t, &Config{Mode: mode}, []Route{
+			{Name: "nginx", Filter: "source:nginx", Output: outputs["nginx"]},
+			{Name: "errors", Filter: `level == "ERROR"`, Output: outputs["errors"]},
+			{Name: "rest", Output: outputs["rest"]},
+		})
+
+		r.Route(newEntry("nginx"))
+		r.Route(newEntry("app"))
+		info := newEntry("app")
+		info.Level = models.LogLevelInfo
+		r.Route(info)
+
+		expected := map[string]int{"nginx": 1, "errors": 1, "rest": 1}
+		if mode == ModeAll {
+			expected["errors"] = 2
+		}
+		counts := received(outputs)
+		for name, n := range expected {
+			if counts[name] != n {
+				t.Errorf("%s: expected %d entries for %s, got %d", mode, n, name, counts[name])
+			}
+		}
+
+		// Each target of an entry gets its own copy
+		if mode == ModeAll && <-outputs["nginx"] == <-outputs["errors"] {
+			t.Error("Expected targets to receive separate entries")
+		}
+
+		stats := r.GetStats()
+		if stats.Routed["rest"] != 1 || stats.Unmatched != 0 {
+			t.Errorf("%s: unexpected stats %+v", mode, stats)
+		}
+	}
+}
+
+func TestRouteDefault(t *testing.T) {
+	nginx := make(chan *models.LogEntry, 10)
+	errors := make(chan *models.LogEntry, 10)
+	r := newRouter(t, &Config{Default: "errors"}, []Route{
+		{Name: "nginx", Filter: "source:nginx", Output: nginx},
+		{Name: "errors", Filter: "source:never", Output: errors},
+	})
+
+	r.Route(newEntry("app"))
+	if len(errors) != 1 || len(nginx) != 0 {
+		t.Errorf("Expected unmatched entry to go to the named default, got %d and %d", len(errors), len(nginx))
+	}
+
+	// Without a default, unmatched entries are counted and dropped
+	r = newRouter(t, nil, []Route{{Name: "nginx", Filter: "source:nginx", Output: nginx}})
+	r.Route(newEntry("app"))
+	if stats := r.GetStats(); stats.Unmatched != 1 || len(nginx) != 0 {
+		t.Errorf("Expected 1 unmatched entry, got %+v", stats)
+	}
+}
+
+func TestCompileRejectsInvalidRoutes(t *testing.T) {
+	invalid := map[string]struct {
+		config *Config
+		routes []Route
+	}{
+		"mode":            {&Config{Mode: "random"}, []Route{{Name: "a"}}},
+		"duplicate":       {nil, []Route{{Name: "a"}, {Name: "a", Filter: "source:x"}}},
+		"filter":          {nil, []Route{{Name: "a", Filter: "level =="}}},
+		"missing default": {&Config{Default: "b"}, []Route{{Name: "a"}}},
+		"two unfiltered":  {nil, []Route{{Name: "a"}, {Name: "b"}}},
+		"unfiltered with named default": {&Config{Default: "b"}, []Route{
+			{Name: "a"}, {Name: "b", Filter: "source:x"},
+		}},
+	}
+
+	for name, tt := range invalid {
+		if err := Validate(tt.config, tt.routes); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	if err := Validate(&Config{Default: "b"}, []Route{{Name: "a", Filter: "source:x"}, {Name: "b"}}); err != nil {
+		t.Errorf("Expected unfiltered default to be valid, got %v", err)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		source  string
+		message string
+		matched bool
+	}{
+		{filter: "source:nginx", source: "nginx", matched: true},
+		{filter: "source:nginx", source: "nginx-ingress"},
+		{filter: "source:nginx*", source: "nginx-ingress", matched: true},
+		{filter: "message:*refused*", message: "connection refused by peer", matched: true},
+		{filter: "message:*refused*", message: "connection reset"},
+		{filter: `message:"a.b"`, message: "a.b", matched: true},
+		{filter: `message:"a.b"`, message: "axb"},
+		{filter: `source == "nginx" && message =~ "^GET"`, source: "nginx", message: "GET /", matched: true},
+	}
+
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("%q: failed to parse: %v", tt.filter, err)
+		}
+
+		entry := models.NewLogEntry()
+		entry.Source = tt.source
+		entry.Message = tt.message
+		matched, err := filter.Match(entry)
+		if err != nil {
+			t.Fatalf("%q: match failed: %v", tt.filter, err)
+		}
+		if matched != tt.matched {
+			t.Errorf("%q on %q/%q: expected %v, got %v", tt.filter, tt.source, tt.message, tt.matched, matched)
+		}
+	}
+
+	for _, filter := range []string{"source:", `source:""`} {
+		if _, err := ParseFilter(filter); err == nil {
+			t.Errorf("Expected error for %q", filter)
+		}
+	}
+}
+
+func TestUpdateWhileRouting(t *testing.T) {
+	oldOutput := make(chan *models.LogEntry)
+	newOutput := make(chan *models.LogEntry)
+	input := make(chan *models.LogEntry)
+
+	r := newRouter(t, nil, []Route{{Name: "old", Output: oldOutput}})
+	r.input = input
+	r.Start()
+	defer r.Stop()
+
+	// Each consumer records the sequence numbers it received
+	const total = 2000
+	var consumers sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	for name, output := range map[string]chan *models.LogEntry{"old": oldOutput, "new": newOutput} {
+		consumers.Add(1)
+		go func(name string, output chan *models.LogEntry) {
+			defer consumers.Done()
+			var seqs []int
+			for entry := range output {
+				seq, _ := strconv.Atoi(entry.ID)
+				seqs = append(seqs, seq)
+			}
+			mu.Lock()
+			seen[name] = seqs
+			mu.Unlock()
+		}(name, output)
+	}
+
+	go func() {
+		for i := 0; i < total; i++ {
+			entry := newEntry("app")
+			entry.ID = strconv.Itoa(i)
+			input <- entry
+		}
+		close(input)
+	}()
+
+	time.Sleep(time.Millisecond)
+	if err := r.Update(nil, []Route{{Name: "new", Output: newOutput}}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Nothing is sent to the old output once Update returned
+	close(oldOutput)
+	r.wg.Wait()
+	close(newOutput)
+	consumers.Wait()
+
+	old, updated := seen["old"], seen["new"]
+	if len(old)+len(updated) != total {
+		t.Fatalf("Expected %d entries, got %d and %d", total, len(old), len(updated))
+	}
+	for i, seq := range append(old, updated...) {
+		if seq != i {
+			t.Fatalf("Expected entries in order across the update, got %d at %d", seq, i)
+		}
+	}
+}
+
+func TestUpdateAbortsBlockedSend(t *testing.T) {
+	full := make(chan *models.LogEntry)
+	nginx := make(chan *models.LogEntry, 1)
+	next := make(chan *models.LogEntry, 1)
+	r := newRouter(t, &Config{Mode: ModeAll}, []Route{
+		{Name: "nginx", Filter: "source:nginx", Output: nginx},
+		{Name: "old", Filter: "source:nginx", Output: full},
+	})
+
+	routed := make(chan struct{})
+	go func() {
+		r.Route(newEntry("nginx"))
+		close(routed)
+	}()
+
+	// The send to the full output holds the route table
+	select {
+	case <-nginx:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the first target")
+	}
+
+	updated := make(chan error)
+	go func() {
+		updated <- r.Update(&Config{Mode: ModeAll}, []Route{
+			{Name: "nginx", Filter: "source:nginx", Output: nginx},
+			{Name: "new", Filter: "source:nginx", Output: next},
+		})
+	}()
+
+	select {
+	case err := <-updated:
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Update blocked behind a full output")
+	}
+	<-routed
+
+	// The entry continues to the new route, but not again to the one it
+	// reached before the update
+	if len(next) != 1 || len(nginx) != 0 {
+		t.Errorf("Expected the entry to be routed to the new route only, got %d and %d", len(next), len(nginx))
+	}
+}
+
+func TestDrainRoutesQueuedEntries(t *testing.T) {
+	input := make(chan *models.LogEntry, 100)
+	output := make(chan *models.LogEntry, 100)
+	r, err := New(&Config{}, input, []Route{{Name: "all", Output: output}})
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		input <- newEntry("app")
+	}
+	r.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.Drain(ctx)
+	if len(output) != 100 {
+		t.Errorf("Expected 100 entries routed before Drain returned, got %d", len(output))
+	}
+}
@@ -0,0 +1,191 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/internal/pipeline/processor"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"github.com/UmangDiyora/logpipeline/pkg/parser"
+)
+
+// StageResult is what one stage of a simulated pipeline did
+type StageResult struct {
+	// Stage names the parser or processor, e.g. "parser:json" or
+	// "processor[2]:geoip"
+	Stage string `json:"stage"`
+
+	// Entries are the entries after the stage. Processors such as split
+	// produce several; filters produce none.
+	Entries []*models.LogEntry `json:"entries"`
+
+	// Error is set when the stage failed
+	Error string `json:"error,omitempty"`
+
+	// Partial is set when the parser held the entry to combine it with
+	// the following samples
+	Partial bool `json:"partial,omitempty"`
+
+	// Took is the time spent in the stage
+	Took time.Duration `json:"took_ns"`
+}
+
+// SampleResult is the simulation of one entry through a pipeline
+type SampleResult struct {
+	// Input is the entry as given
+	Input *models.LogEntry `json:"input"`
+
+	// Stages lists the stages the entry went through, up to the first
+	// failing stage
+	Stages []StageResult `json:"stages"`
+
+	// Output holds the entries the pipeline would store
+	Output []*models.LogEntry `json:"output"`
+
+	// Error is the error of the failing stage
+	Error string `json:"error,omitempty"`
+}
+
+// Simulator runs entries through the parser and processors of a pipeline
+// definition one stage at a time, without queues or output
+type Simulator struct {
+	name       string
+	parser     parser.Parser
+	processors []processor.Processor
+}
+
+// NewSimulator creates the parser and processors of a definition. The
+// simulator keeps processor state, such as dedupe windows, across Run
+// calls, and must be closed.
+func NewSimulator(def *config.PipelineConfig) (*Simulator, error) {
+	cfg, err := NewConfig(def)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Simulator{name: def.Name}
+
+	// Metrics processors register with a private registry so that
+	// simulated entries do not show up on the metrics endpoint
+	registry := metrics.NewRegistry()
+	if cfg.Parser != nil {
+		s.parser, err = parser.New(cfg.Parser)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: failed to create parser: %w", def.Name, err)
+		}
+	}
+
+	for i, procConfig := range cfg.Processors {
+		procConfig.Metrics = registry
+		proc, err := processor.New(&procConfig)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("pipeline %s: failed to create processor %d (%s): %w", def.Name, i, procConfig.Type, err)
+		}
+		s.processors = append(s.processors, proc)
+	}
+	return s, nil
+}
+
+// Run simulates an entry. The entry is not modified.
+func (s *Simulator) Run(entry *models.LogEntry) *SampleResult {
+	result := &SampleResult{
+		Input:  entry,
+		Stages: make([]StageResult, 0, len(s.processors)+1),
+		Output: make([]*models.LogEntry, 0, 1),
+	}
+
+	current := []*models.LogEntry{entry.Clone()}
+
+	if s.parser != nil {
+		stage := StageResult{Stage: parserStage(s.parser)}
+		start := time.Now()
+		err := s.parser.Parse(current[0])
+		stage.Took = time.Since(start)
+
+		if errors.Is(err, parser.ErrPartialEntry) {
+			stage.Partial = true
+			stage.Entries = make([]*models.LogEntry, 0)
+			result.Stages = append(result.Stages, stage)
+			return result
+		}
+		if err != nil {
+			stage.Error = err.Error()
+			result.Error = stage.Error
+			result.Stages = append(result.Stages, stage)
+			return result
+		}
+		stage.Entries = snapshot(current)
+		result.Stages = append(result.Stages, stage)
+	}
+
+	for i, proc := range s.processors {
+		stage := StageResult{Stage: processorStage(i, proc)}
+		next := make([]*models.LogEntry, 0, len(current))
+		start := time.Now()
+		for _, e := range current {
+			results, err := proc.Process(e)
+			if err != nil {
+				stage.Error = err.Error()
+				break
+			}
+			next = append(next, results...)
+		}
+		stage.Took = time.Since(start)
+
+		if stage.Error != "" {
+			result.Error = stage.Error
+			result.Stages = append(result.Stages, stage)
+			return result
+		}
+
+		current = next
+		stage.Entries = snapshot(current)
+		result.Stages = append(result.Stages, stage)
+		if len(current) == 0 {
+			break
+		}
+	}
+
+	result.Output = current
+	return result
+}
+
+// SampleEntry creates the entry the agent would ship for a raw line
+func SampleEntry(line, source, host string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Raw = line
+	entry.Message = line
+	entry.Source = source
+	entry.Host = host
+	entry.Level = models.LogLevelInfo
+	return entry
+}
+
+// snapshot copies entries so that later stages do not change them
+func snapshot(entries []*models.LogEntry) []*models.LogEntry {
+	copies := make([]*models.LogEntry, len(entries))
+	for i, entry := range entries {
+		copies[i] = entry.Clone()
+	}
+	return copies
+}
+
+// Close releases resources held by processors
+func (s *Simulator) Close() error {
+	p := &Pipeline{name: s.name, processors: s.processors}
+	return p.closeProcessors()
+}
+
+// parserStage names the parser stage
+func parserStage(p parser.Parser) string {
+	return "parser:" + p.Name()
+}
+
+// processorStage names a processor stage
+func processorStage(index int, proc processor.Processor) string {
+	return fmt.Sprintf("processor[%d]:%s", index, proc.Name())
+}
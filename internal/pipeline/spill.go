@@ -0,0 +1,255 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// spillSegmentEntries is the number of entries per spill file
+const spillSegmentEntries = 10000
+
+// spillQueue is a FIFO of entries on disk, used by the spill_to_disk
+// overflow policy. Entries are appended as JSON lines to numbered segment
+// files; segments are deleted once read. Segments left by a previous run
+// are read first, so spilled entries survive a restart.
+type spillQueue struct {
+	mu  sync.Mutex
+	dir string
+
+	writer     *os.File
+	writeSeq   int
+	writeCount int
+
+	reader     *os.File
+	scanner    *bufio.Scanner
+	readSeq    int
+	pending    int
+	readerOpen bool
+
+	// head is the oldest entry once Peek read it; it stays in the queue
+	// until Remove
+	head *models.LogEntry
+}
+
+// openSpillQueue opens a spill queue in dir, recovering existing segments
+func openSpillQueue(dir string) (*spillQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+
+	q := &spillQueue{dir: dir}
+
+	files, err := filepath.Glob(filepath.Join(dir, "spill-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	seqs := make([]int, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "spill-"), ".jsonl")
+		seq, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+
+		count, err := countLines(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		q.pending += count
+	}
+	sort.Ints(seqs)
+
+	if len(seqs) > 0 {
+		q.readSeq = seqs[0]
+		q.writeSeq = seqs[len(seqs)-1] + 1
+	}
+	return q, nil
+}
+
+// countLines counts the lines in a file
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// segmentPath returns the path of a segment
+func (q *spillQueue) segmentPath(seq int) string {
+	return filepath.Join(q.dir, fmt.Sprintf("spill-%08d.jsonl", seq))
+}
+
+// Push appends an entry
+func (q *spillQueue) Push(entry *models.LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.writer == nil {
+		f, err := os.OpenFile(q.segmentPath(q.writeSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %w", err)
+		}
+		q.writer = f
+		q.writeCount = 0
+	}
+
+	if _, err := q.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	q.pending++
+	q.writeCount++
+
+	if q.writeCount >= spillSegmentEntries {
+		q.rotate()
+	}
+	return nil
+}
+
+// rotate closes the segment being written so that it can be read
+func (q *spillQueue) rotate() {
+	if q.writer != nil {
+		q.writer.Close()
+		q.writer = nil
+	}
+	q.writeSeq++
+}
+
+// Pop removes and returns the oldest entry. It returns false when the
+// queue is empty.
+func (q *spillQueue) Pop() (*models.LogEntry, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok, err := q.peek()
+	if ok {
+		q.remove()
+	}
+	return entry, ok, err
+}
+
+// Peek returns the oldest entry without removing it, so that it stays
+// first until Remove. It returns false when the queue is empty.
+func (q *spillQueue) Peek() (*models.LogEntry, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.peek()
+}
+
+// Remove removes the entry returned by Peek
+func (q *spillQueue) Remove() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head != nil {
+		q.remove()
+	}
+}
+
+// peek reads the oldest entry into head. The caller holds the lock.
+func (q *spillQueue) peek() (*models.LogEntry, bool, error) {
+	if q.head != nil {
+		return q.head, true, nil
+	}
+
+	for q.pending > 0 {
+		if !q.readerOpen {
+			// The segment being written is only read once it is closed
+			if q.readSeq == q.writeSeq {
+				q.rotate()
+			}
+			f, err := os.Open(q.segmentPath(q.readSeq))
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to open spill file: %w", err)
+			}
+			q.reader = f
+			q.scanner = bufio.NewScanner(f)
+			q.scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+			q.readerOpen = true
+		}
+
+		if !q.scanner.Scan() {
+			err := q.scanner.Err()
+			q.reader.Close()
+			q.readerOpen = false
+			if err != nil && err != io.EOF {
+				return nil, false, fmt.Errorf("failed to read spill file: %w", err)
+			}
+			os.Remove(q.segmentPath(q.readSeq))
+			q.readSeq++
+			continue
+		}
+
+		var entry models.LogEntry
+		if err := json.Unmarshal(q.scanner.Bytes(), &entry); err != nil {
+			q.remove()
+			return nil, false, fmt.Errorf("failed to decode spilled entry: %w", err)
+		}
+		q.head = &entry
+		return q.head, true, nil
+	}
+	return nil, false, nil
+}
+
+// remove removes the entry last read. The caller holds the lock.
+func (q *spillQueue) remove() {
+	q.head = nil
+	q.pending--
+
+	// The last entry has been read; remove its segment so that it is not
+	// read again after a restart
+	if q.pending == 0 {
+		q.reader.Close()
+		q.readerOpen = false
+		os.Remove(q.segmentPath(q.readSeq))
+		q.readSeq = q.writeSeq
+	}
+}
+
+// Len returns the number of entries on disk
+func (q *spillQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending
+}
+
+// Close closes the open files. Unread entries, including one returned by
+// Peek but not removed, remain on disk; entries already read from a
+// partly consumed segment are read again on reopen.
+func (q *spillQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.readerOpen {
+		q.reader.Close()
+		q.readerOpen = false
+	}
+	if q.writer != nil {
+		return q.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func openSpill(t *testing.T, dir string) *spillQueue {
+	t.Helper()
+
+	q, err := openSpillQueue(dir)
+	if err != nil {
+		t.Fatalf("Failed to open spill queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func pushSeq(t *testing.T, q *spillQueue, from, to int) {
+	t.Helper()
+
+	for i := from; i < to; i++ {
+		entry := models.NewLogEntry()
+		entry.ID = strconv.Itoa(i)
+		if err := q.Push(entry); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+	}
+}
+
+func popSeq(t *testing.T, q *spillQueue, from, to int) {
+	t.Helper()
+
+	for i := from; i < to; i++ {
+		entry, ok, err := q.Pop()
+		if err != nil || !ok {
+			t.Fatalf("Pop %d failed: %v %v", i, ok, err)
+		}
+		if entry.ID != strconv.Itoa(i) {
+			t.Fatalf("Expected entry %d, got %s", i, entry.ID)
+		}
+	}
+}
+
+func segments(t *testing.T, dir string) int {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "spill-*.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to list segments: %v", err)
+	}
+	return len(files)
+}
+
+func TestSpillQueueOrder(t *testing.T) {
+	q := openSpill(t, t.TempDir())
+
+	if _, ok, err := q.Pop(); ok || err != nil {
+		t.Fatalf("Expected empty queue, got %v %v", ok, err)
+	}
+
+	// Reading and writing interleave within one segment
+	pushSeq(t, q, 0, 3)
+	popSeq(t, q, 0, 1)
+	pushSeq(t, q, 3, 5)
+	popSeq(t, q, 1, 5)
+
+	if q.Len() != 0 {
+		t.Errorf("Expected empty queue, got %d", q.Len())
+	}
+}
+
+func TestSpillQueueRotates(t *testing.T) {
+	dir := t.TempDir()
+	q := openSpill(t, dir)
+
+	total := spillSegmentEntries + 5
+	pushSeq(t, q, 0, total)
+	if n := segments(t, dir); n != 2 {
+		t.Errorf("Expected 2 segments, got %d", n)
+	}
+	if q.Len() != total {
+		t.Errorf("Expected %d entries, got %d", total, q.Len())
+	}
+
+	popSeq(t, q, 0, spillSegmentEntries+1)
+	if n := segments(t, dir); n != 1 {
+		t.Errorf("Expected the read segment to be removed, got %d segments", n)
+	}
+	popSeq(t, q, spillSegmentEntries+1, total)
+	if n := segments(t, dir); n != 0 {
+		t.Errorf("Expected no segments once empty, got %d", n)
+	}
+}
+
+func TestSpillQueueRecovers(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := openSpillQueue(dir)
+	if err != nil {
+		t.Fatalf("Failed to open spill queue: %v", err)
+	}
+	pushSeq(t, q, 0, 5)
+	q.Close()
+
+	// Entries survive a restart
+	q = openSpill(t, dir)
+	if q.Len() != 5 {
+		t.Fatalf("Expected 5 recovered entries, got %d", q.Len())
+	}
+	pushSeq(t, q, 5, 8)
+	popSeq(t, q, 0, 8)
+	q.Close()
+
+	q = openSpill(t, dir)
+	if q.Len() != 0 {
+		t.Errorf("Expected consumed entries not to be recovered, got %d", q.Len())
+	}
+}
+
+func TestSpillQueuePeek(t *testing.T) {
+	dir := t.TempDir()
+	q, err := openSpillQueue(dir)
+	if err != nil {
+		t.Fatalf("Failed to open spill queue: %v", err)
+	}
+	pushSeq(t, q, 0, 3)
+
+	// A peeked entry stays first while newer entries arrive
+	for i := 0; i < 2; i++ {
+		entry, ok, err := q.Peek()
+		if err != nil || !ok || entry.ID != "0" {
+			t.Fatalf("Expected entry 0, got %v %v %v", entry, ok, err)
+		}
+	}
+	pushSeq(t, q, 3, 5)
+	if q.Len() != 5 {
+		t.Errorf("Expected the peeked entry to be counted, got %d", q.Len())
+	}
+	q.Remove()
+	popSeq(t, q, 1, 4)
+
+	// The last entry is kept on disk until it is removed
+	if entry, ok, _ := q.Peek(); !ok || entry.ID != "4" {
+		t.Fatalf("Expected entry 4, got %v", entry)
+	}
+	q.Close()
+
+	// Entries read from the same segment before it are read again
+	q = openSpill(t, dir)
+	var last *models.LogEntry
+	for {
+		entry, ok, err := q.Pop()
+		if err != nil {
+			t.Fatalf("Pop failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		last = entry
+	}
+	if last == nil || last.ID != "4" {
+		t.Errorf("Expected entry 4 after reopening, got %v", last)
+	}
+}
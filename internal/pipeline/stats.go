@@ -0,0 +1,418 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/metrics"
+	"github.com/UmangDiyora/logpipeline/pkg/parser"
+)
+
+// Error types counted in the error breakdowns
+const (
+	ErrorTypeNoMatch   = "no_match"
+	ErrorTypeJSON      = "invalid_json"
+	ErrorTypeTimestamp = "invalid_timestamp"
+	ErrorTypeNumber    = "invalid_number"
+	ErrorTypeTimeout   = "timeout"
+	ErrorTypeSpill     = "spill"
+	ErrorTypeOther     = "other"
+)
+
+// errorTypes lists every error type in the order stages count them
+var errorTypes = []string{
+	ErrorTypeNoMatch, ErrorTypeJSON, ErrorTypeTimestamp, ErrorTypeNumber,
+	ErrorTypeTimeout, ErrorTypeSpill, ErrorTypeOther,
+}
+
+// errorIndex returns the position of an error type in errorTypes
+func errorIndex(errType string) int {
+	for i, t := range errorTypes {
+		if t == errType {
+			return i
+		}
+	}
+	return len(errorTypes) - 1
+}
+
+// errorType classifies an error for the error breakdowns
+func errorType(err error) string {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		timeErr   *time.ParseError
+		numErr    *strconv.NumError
+	)
+	switch {
+	case errors.Is(err, parser.ErrNoMatch):
+		return ErrorTypeNoMatch
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrorTypeJSON
+	case errors.Is(err, parser.ErrInvalidTimestamp), errors.As(err, &timeErr):
+		return ErrorTypeTimestamp
+	case errors.As(err, &numErr):
+		return ErrorTypeNumber
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTypeTimeout
+	}
+	return ErrorTypeOther
+}
+
+// latencyBuckets are the upper bounds of latency histograms in seconds.
+// Stages usually take microseconds; the pipeline latency includes time
+// spent waiting for the output.
+var latencyBuckets = []float64{
+	0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005,
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05,
+	0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// LatencyStats summarizes a latency histogram. Percentiles are estimated
+// from the histogram buckets.
+type LatencyStats struct {
+	Count   uint64
+	Average time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+}
+
+// latencyHistogram counts latencies per bucket
+type latencyHistogram struct {
+	sum   int64
+	count uint64
+
+	// counts holds observations per bucket, not cumulative; the last
+	// element counts observations above every bucket
+	counts []uint64
+}
+
+// newLatencyHistogram creates an empty histogram
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBuckets)+1)}
+}
+
+// observe records a latency
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := sort.SearchFloat64s(latencyBuckets, d.Seconds())
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+	atomic.AddUint64(&h.count, 1)
+}
+
+// stats summarizes the histogram
+func (h *latencyHistogram) stats() LatencyStats {
+	counts := make([]uint64, len(h.counts))
+	var total uint64
+	for i := range h.counts {
+		counts[i] = atomic.LoadUint64(&h.counts[i])
+		total += counts[i]
+	}
+
+	stats := LatencyStats{Count: atomic.LoadUint64(&h.count)}
+	if stats.Count == 0 || total == 0 {
+		return stats
+	}
+	stats.Average = time.Duration(atomic.LoadInt64(&h.sum) / int64(stats.Count))
+	stats.P50 = quantile(counts, total, 0.5)
+	stats.P95 = quantile(counts, total, 0.95)
+	stats.P99 = quantile(counts, total, 0.99)
+	return stats
+}
+
+// quantile estimates a quantile by interpolating within its bucket.
+// Observations above every bucket are reported at the largest bound.
+func quantile(counts []uint64, total uint64, q float64) time.Duration {
+	rank := q * float64(total)
+
+	var cumulative uint64
+	for i, n := range counts {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(latencyBuckets) {
+			break
+		}
+
+		lower := 0.0
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		upper := latencyBuckets[i]
+		seconds := lower + (upper-lower)*(rank-float64(cumulative))/float64(n)
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Duration(latencyBuckets[len(latencyBuckets)-1] * float64(time.Second))
+}
+
+// StageStats holds the statistics of one parser or processor stage
+type StageStats struct {
+	// Stage names the stage, e.g. "parser:json" or "processor[2]:geoip"
+	Stage string
+
+	// In counts entries given to the stage and Out the entries it
+	// produced, which can be more when it splits entries. Dropped counts
+	// entries the stage removed and Errors entries it failed on.
+	// Entries held by the parser until they are complete are only
+	// counted in In.
+	In      uint64
+	Out     uint64
+	Dropped uint64
+	Errors  uint64
+
+	// ErrorTypes breaks Errors down by error type
+	ErrorTypes map[string]uint64
+
+	// Latency is the time spent in the stage per entry
+	Latency LatencyStats
+}
+
+// stageStats counts the entries through one stage. Its exported series
+// are resolved when the stage is created, so that counting an entry takes
+// no lock.
+type stageStats struct {
+	in      uint64
+	out     uint64
+	dropped uint64
+	errors  uint64
+	latency *latencyHistogram
+
+	// errorCounts counts errors by position in errorTypes
+	errorCounts []uint64
+
+	pipeline string
+	name     string
+	exported *exportedMetrics
+
+	inSeries      *metrics.Series
+	outSeries     *metrics.Series
+	droppedSeries *metrics.Series
+	latencySeries *metrics.Series
+
+	// errorSeries are resolved on the first error of each type, so that
+	// stages without errors export no error series
+	errorSeries []atomic.Pointer[metrics.Series]
+}
+
+// newStageStats creates the statistics of a stage
+func newStageStats(pipeline, name string, exported *exportedMetrics) *stageStats {
+	s := &stageStats{
+		pipeline:    pipeline,
+		name:        name,
+		exported:    exported,
+		latency:     newLatencyHistogram(),
+		errorCounts: make([]uint64, len(errorTypes)),
+		errorSeries: make([]atomic.Pointer[metrics.Series], len(errorTypes)),
+	}
+
+	// Series over the series limit stay nil and are not exported
+	s.inSeries, _ = exported.stageIn.With(pipeline, name)
+	s.outSeries, _ = exported.stageOut.With(pipeline, name)
+	s.droppedSeries, _ = exported.stageDropped.With(pipeline, name)
+	s.latencySeries, _ = exported.stageLatency.With(pipeline, name)
+	return s
+}
+
+// done records an entry that produced out entries
+func (s *stageStats) done(out int, took time.Duration) {
+	s.observe(took)
+	atomic.AddUint64(&s.out, uint64(out))
+	s.outSeries.Add(float64(out))
+	if out == 0 {
+		atomic.AddUint64(&s.dropped, 1)
+		s.droppedSeries.Add(1)
+	}
+}
+
+// held records an entry the parser holds until it is complete
+func (s *stageStats) held(took time.Duration) {
+	s.observe(took)
+}
+
+// failed records an entry the stage failed on
+func (s *stageStats) failed(err error, took time.Duration) {
+	s.observe(took)
+	atomic.AddUint64(&s.errors, 1)
+
+	i := errorIndex(errorType(err))
+	atomic.AddUint64(&s.errorCounts[i], 1)
+	series := s.errorSeries[i].Load()
+	if series == nil {
+		series, _ = s.exported.stageErrors.With(s.pipeline, s.name, errorTypes[i])
+		s.errorSeries[i].Store(series)
+	}
+	series.Add(1)
+}
+
+// observe counts an entry in and its latency
+func (s *stageStats) observe(took time.Duration) {
+	atomic.AddUint64(&s.in, 1)
+	s.latency.observe(took)
+	s.inSeries.Add(1)
+	s.latencySeries.Observe(took.Seconds())
+}
+
+// stats returns a copy of the statistics
+func (s *stageStats) stats() StageStats {
+	stats := StageStats{
+		Stage:   s.name,
+		In:      atomic.LoadUint64(&s.in),
+		Out:     atomic.LoadUint64(&s.out),
+		Dropped: atomic.LoadUint64(&s.dropped),
+		Errors:  atomic.LoadUint64(&s.errors),
+		Latency: s.latency.stats(),
+	}
+
+	stats.ErrorTypes = make(map[string]uint64)
+	for i := range s.errorCounts {
+		if n := atomic.LoadUint64(&s.errorCounts[i]); n > 0 {
+			stats.ErrorTypes[errorTypes[i]] = n
+		}
+	}
+	return stats
+}
+
+// exportedMetrics are the Prometheus metrics of pipelines. They are shared
+// by every pipeline of a registry, so that counters continue when a
+// pipeline is replaced on reload.
+type exportedMetrics struct {
+	entries      *metrics.Vec
+	errors       *metrics.Vec
+	latency      *metrics.Vec
+	queueDepth   *metrics.Vec
+	stageIn      *metrics.Vec
+	stageOut     *metrics.Vec
+	stageDropped *metrics.Vec
+	stageErrors  *metrics.Vec
+	stageLatency *metrics.Vec
+}
+
+// maxPipelineSeries bounds the series of each pipeline metric
+const maxPipelineSeries = 10000
+
+// registerMetrics registers the pipeline metrics with a registry
+func registerMetrics(registry *metrics.Registry) (*exportedMetrics, error) {
+	m := &exportedMetrics{}
+	descs := []struct {
+		vec  **metrics.Vec
+		desc metrics.Desc
+	}{
+		{&m.entries, metrics.Desc{
+			Name:   "logpipeline_pipeline_entries_total",
+			Help:   "Entries handled by pipelines by result: processed, failed, filtered or dropped",
+			Type:   metrics.TypeCounter,
+			Labels: []string{"pipeline", "result"},
+		}},
+		{&m.errors, metrics.Desc{
+			Name:   "logpipeline_pipeline_errors_total",
+			Help:   "Entries that failed in pipelines by error type",
+			Type:   metrics.TypeCounter,
+			Labels: []string{"pipeline", "type"},
+		}},
+		{&m.latency, metrics.Desc{
+			Name:    "logpipeline_pipeline_latency_seconds",
+			Help:    "Time from an entry entering a pipeline to reaching the output",
+			Type:    metrics.TypeHistogram,
+			Labels:  []string{"pipeline"},
+			Buckets: latencyBuckets,
+		}},
+		{&m.queueDepth, metrics.Desc{
+			Name:   "logpipeline_pipeline_queue_depth",
+			Help:   "Entries waiting in a pipeline's input, output or spill queue",
+			Type:   metrics.TypeGauge,
+			Labels: []string{"pipeline", "queue"},
+		}},
+		{&m.stageIn, metrics.Desc{
+			Name:   "logpipeline_stage_in_total",
+			Help:   "Entries given to a pipeline stage",
+			Type:   metrics.TypeCounter,
+			Labels: []string{"pipeline", "stage"},
+		}},
+		{&m.stageOut, metrics.Desc{
+			Name:   "logpipeline_stage_out_total",
+			Help:   "Entries produced by a pipeline stage",
+			Type:   metrics.TypeCounter,
+			Labels: []string{"pipeline", "stage"},
+		}},
+		{&m.stageDropped, metrics.Desc{
+			Name:   "logpipeline_stage_dropped_total",
+			Help:   "Entries removed by a pipeline stage",
+			Type:   metrics.TypeCounter,
+			Labels: []string{"pipeline", "stage"},
+		}},
+		{&m.stageErrors, metrics.Desc{
+			Name:   "logpipeline_stage_errors_total",
+			Help:   "Entries a pipeline stage failed on by error type",
+			Type:   metrics.TypeCounter,
+			Labels: []string{"pipeline", "stage", "type"},
+		}},
+		{&m.stageLatency, metrics.Desc{
+			Name:    "logpipeline_stage_latency_seconds",
+			Help:    "Time spent in a pipeline stage per entry",
+			Type:    metrics.TypeHistogram,
+			Labels:  []string{"pipeline", "stage"},
+			Buckets: latencyBuckets,
+		}},
+	}
+
+	for _, d := range descs {
+		d.desc.MaxSeries = maxPipelineSeries
+		vec, err := registry.Register(d.desc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register pipeline metrics: %w", err)
+		}
+		*d.vec = vec
+	}
+	return m, nil
+}
+
+// pipelineSeries are the exported series a pipeline updates per entry
+type pipelineSeries struct {
+	processed *metrics.Series
+	failed    *metrics.Series
+	filtered  *metrics.Series
+	dropped   *metrics.Series
+	latency   *metrics.Series
+}
+
+// series resolves the per-entry series of a pipeline. Series over the
+// series limit stay nil and are not exported.
+func (m *exportedMetrics) series(pipeline string) pipelineSeries {
+	var ps pipelineSeries
+	ps.processed, _ = m.entries.With(pipeline, "processed")
+	ps.failed, _ = m.entries.With(pipeline, "failed")
+	ps.filtered, _ = m.entries.With(pipeline, "filtered")
+	ps.dropped, _ = m.entries.With(pipeline, "dropped")
+	ps.latency, _ = m.latency.With(pipeline)
+	return ps
+}
+
+// deleteStages removes the stage series of a pipeline except those of
+// the given stages
+func (m *exportedMetrics) deleteStages(pipeline string, keep map[string]bool) {
+	stale := func(labelValues []string) bool {
+		return labelValues[0] == pipeline && !keep[labelValues[1]]
+	}
+	for _, vec := range []*metrics.Vec{
+		m.stageIn, m.stageOut, m.stageDropped, m.stageErrors, m.stageLatency,
+	} {
+		vec.DeleteFunc(stale)
+	}
+}
+
+// delete removes the series of a pipeline
+func (m *exportedMetrics) delete(pipeline string) {
+	for _, vec := range []*metrics.Vec{
+		m.entries, m.errors, m.latency, m.queueDepth,
+		m.stageIn, m.stageOut, m.stageDropped, m.stageErrors, m.stageLatency,
+	} {
+		vec.Delete("pipeline", pipeline)
+	}
+}
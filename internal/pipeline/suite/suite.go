@@ -0,0 +1,330 @@
+// Package suite runs declarative test suites against pipeline
+// definitions, so that parsers and processors can be tested like code.
+//
+// A suite is a YAML file naming a pipeline of the server configuration
+// and listing input lines with the entries expected from them:
+//
+//	pipeline: nginx-logs
+//	config: ../server.yaml
+//	tests:
+//	  - name: access line
+//	    source: nginx
+//	    input: '127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"'
+//	    expect:
+//	      - level: INFO
+//	        fields:
+//	          status: "200"
+//	          request_method: GET
+package suite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/internal/pipeline"
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of test cases for one pipeline
+type Suite struct {
+	// Pipeline names the pipeline in the server configuration
+	Pipeline string `yaml:"pipeline"`
+
+	// Config is the server configuration file, relative to the suite
+	Config string `yaml:"config,omitempty"`
+
+	// Tests are the test cases
+	Tests []Case `yaml:"tests"`
+
+	// Path is the file the suite was loaded from
+	Path string `yaml:"-"`
+}
+
+// Case is input for a pipeline and the entries expected from it
+type Case struct {
+	// Name describes the case
+	Name string `yaml:"name"`
+
+	// Input holds one or more lines, each shipped as a separate entry
+	Input string `yaml:"input"`
+
+	// Source and Host are set on the input entries
+	Source string `yaml:"source,omitempty"`
+	Host   string `yaml:"host,omitempty"`
+
+	// Expect lists the entries expected in order. Without expectations
+	// the input must produce at least one entry.
+	Expect []Expected `yaml:"expect,omitempty"`
+
+	// Dropped expects the input to produce no entries
+	Dropped bool `yaml:"dropped,omitempty"`
+
+	// Error expects a stage to fail with an error containing the text
+	Error string `yaml:"error,omitempty"`
+}
+
+// Expected describes an output entry. Only the given values are checked.
+type Expected struct {
+	Level     string `yaml:"level,omitempty"`
+	Message   string `yaml:"message,omitempty"`
+	Service   string `yaml:"service,omitempty"`
+	Timestamp string `yaml:"timestamp,omitempty"`
+
+	// Fields must have the given values; numbers compare equal whatever
+	// their type
+	Fields map[string]interface{} `yaml:"fields,omitempty"`
+
+	// AbsentFields must not be set
+	AbsentFields []string `yaml:"absent_fields,omitempty"`
+
+	// Tags must be present; other tags are allowed
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// Mismatch is a difference between the expected and the actual output
+type Mismatch struct {
+	// Path locates the value, e.g. "entries[0].fields.status"
+	Path string `json:"path"`
+
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Result is the outcome of a case
+type Result struct {
+	Name       string     `json:"name"`
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+
+	// Output holds the entries the pipeline produced
+	Output []*models.LogEntry `json:"output"`
+}
+
+// Passed reports whether the output matched the expectations
+func (r *Result) Passed() bool {
+	return len(r.Mismatches) == 0
+}
+
+// Load reads and validates a suite
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+
+	var s Suite
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse suite %s: %w", path, err)
+	}
+	s.Path = path
+
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Validate checks that the cases are well formed
+func (s *Suite) Validate() error {
+	if s.Pipeline == "" {
+		return fmt.Errorf("pipeline is required")
+	}
+	if len(s.Tests) == 0 {
+		return fmt.Errorf("at least one test is required")
+	}
+
+	for i, c := range s.Tests {
+		if c.Name == "" {
+			return fmt.Errorf("test %d: name is required", i)
+		}
+		if strings.TrimSpace(c.Input) == "" {
+			return fmt.Errorf("test %q: input is required", c.Name)
+		}
+		if c.Dropped && len(c.Expect) > 0 {
+			return fmt.Errorf("test %q: dropped cannot be combined with expect", c.Name)
+		}
+		if c.Error != "" && (c.Dropped || len(c.Expect) > 0) {
+			return fmt.Errorf("test %q: error cannot be combined with expect or dropped", c.Name)
+		}
+		for j, e := range c.Expect {
+			if e.Timestamp == "" {
+				continue
+			}
+			if _, err := time.Parse(time.RFC3339Nano, e.Timestamp); err != nil {
+				return fmt.Errorf("test %q: entry %d: invalid timestamp: %w", c.Name, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ConfigPath returns the server configuration of the suite, resolved
+// against the directory of the suite file
+func (s *Suite) ConfigPath() string {
+	if s.Config == "" || filepath.IsAbs(s.Config) {
+		return s.Config
+	}
+	return filepath.Join(filepath.Dir(s.Path), s.Config)
+}
+
+// Run runs the cases through the parser and processors of a pipeline.
+// Each case starts with fresh processors, so stateful processors such as
+// dedupe do not carry state between cases.
+func Run(def *config.PipelineConfig, cases []Case) ([]*Result, error) {
+	results := make([]*Result, 0, len(cases))
+	for _, c := range cases {
+		result, err := runCase(def, c)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runCase runs a single case
+func runCase(def *config.PipelineConfig, c Case) (*Result, error) {
+	simulator, err := pipeline.NewSimulator(def)
+	if err != nil {
+		return nil, err
+	}
+	defer simulator.Close()
+
+	result := &Result{
+		Name:   c.Name,
+		Output: make([]*models.LogEntry, 0, 1),
+	}
+
+	var failure string
+	for _, line := range strings.Split(strings.TrimRight(c.Input, "\n"), "\n") {
+		sample := simulator.Run(pipeline.SampleEntry(strings.TrimRight(line, "\r"), c.Source, c.Host))
+		if sample.Error != "" && failure == "" {
+			stage := sample.Stages[len(sample.Stages)-1].Stage
+			failure = stage + ": " + sample.Error
+		}
+		result.Output = append(result.Output, sample.Output...)
+	}
+
+	switch {
+	case c.Error != "":
+		if !strings.Contains(failure, c.Error) {
+			result.mismatch("error", c.Error, orNone(failure))
+		}
+		return result, nil
+	case failure != "":
+		result.mismatch("error", "none", failure)
+		return result, nil
+	}
+
+	switch {
+	case c.Dropped:
+		if len(result.Output) > 0 {
+			result.mismatch("entries", "dropped", countEntries(len(result.Output)))
+		}
+	case len(c.Expect) == 0:
+		if len(result.Output) == 0 {
+			result.mismatch("entries", "at least 1 entry", "dropped")
+		}
+	default:
+		if len(result.Output) != len(c.Expect) {
+			result.mismatch("entries", countEntries(len(c.Expect)), countEntries(len(result.Output)))
+		}
+		for i := 0; i < len(c.Expect) && i < len(result.Output); i++ {
+			result.compare(fmt.Sprintf("entries[%d]", i), &c.Expect[i], result.Output[i])
+		}
+	}
+	return result, nil
+}
+
+// compare records the differences between an expected and an actual entry
+func (r *Result) compare(path string, expected *Expected, actual *models.LogEntry) {
+	if expected.Level != "" && !strings.EqualFold(expected.Level, string(actual.Level)) {
+		r.mismatch(path+".level", strings.ToUpper(expected.Level), string(actual.Level))
+	}
+	if expected.Message != "" && expected.Message != actual.Message {
+		r.mismatch(path+".message", quote(expected.Message), quote(actual.Message))
+	}
+	if expected.Service != "" && expected.Service != actual.Service {
+		r.mismatch(path+".service", quote(expected.Service), quote(actual.Service))
+	}
+	if expected.Timestamp != "" {
+		// Validate checked the format
+		ts, _ := time.Parse(time.RFC3339Nano, expected.Timestamp)
+		if !ts.Equal(actual.Timestamp) {
+			r.mismatch(path+".timestamp", expected.Timestamp, actual.Timestamp.Format(time.RFC3339Nano))
+		}
+	}
+
+	keys := make([]string, 0, len(expected.Fields))
+	for key := range expected.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		want := encode(expected.Fields[key])
+		value, ok := actual.GetField(key)
+		if !ok {
+			r.mismatch(path+".fields."+key, want, "<missing>")
+			continue
+		}
+		if got := encode(value); got != want {
+			r.mismatch(path+".fields."+key, want, got)
+		}
+	}
+	for _, key := range expected.AbsentFields {
+		if value, ok := actual.GetField(key); ok {
+			r.mismatch(path+".fields."+key, "<absent>", encode(value))
+		}
+	}
+
+	for _, tag := range expected.Tags {
+		if !actual.HasTag(tag) {
+			r.mismatch(path+".tags", "contains "+quote(tag), "["+strings.Join(actual.Tags, ", ")+"]")
+		}
+	}
+}
+
+// mismatch records a difference
+func (r *Result) mismatch(path, expected, actual string) {
+	r.Mismatches = append(r.Mismatches, Mismatch{Path: path, Expected: expected, Actual: actual})
+}
+
+// encode formats a value as JSON, so that numbers compare equal whether
+// they were decoded as integers or floats
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// quote formats a string value
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+// orNone describes an empty error
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// countEntries describes a number of entries
+func countEntries(n int) string {
+	if n == 1 {
+		return "1 entry"
+	}
+	return fmt.Sprintf("%d entries", n)
+}
@@ -0,0 +1,138 @@
+package suite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/config"
+)
+
+func jsonPipeline() *config.PipelineConfig {
+	return &config.PipelineConfig{
+		Name:   "json-logs",
+		Parser: "json",
+		Processors: []config.ProcessorConfig{
+			{Type: "add_fields", Config: map[string]interface{}{"team": "payments"}},
+			{Type: "drop", Condition: `fields.debug == true`},
+		},
+	}
+}
+
+func writeSuite(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "suite.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write suite: %v", err)
+	}
+	return path
+}
+
+func TestLoadValidates(t *testing.T) {
+	path := writeSuite(t, `
+pipeline: json-logs
+config: server.yaml
+tests:
+  - name: parsed
+    input: '{"amount": 5}'
+    expect:
+      - fields:
+          amount: 5
+`)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.ConfigPath() != filepath.Join(filepath.Dir(path), "server.yaml") {
+		t.Errorf("Expected config relative to the suite, got %s", s.ConfigPath())
+	}
+
+	invalid := map[string]string{
+		"unknown key":     "pipeline: p\ntests:\n  - name: a\n    input: x\n    expected: []\n",
+		"no pipeline":     "tests:\n  - name: a\n    input: x\n",
+		"no input":        "pipeline: p\ntests:\n  - name: a\n",
+		"dropped, expect": "pipeline: p\ntests:\n  - name: a\n    input: x\n    dropped: true\n    expect: [{level: INFO}]\n",
+		"bad timestamp":   "pipeline: p\ntests:\n  - name: a\n    input: x\n    expect: [{timestamp: yesterday}]\n",
+	}
+	for name, content := range invalid {
+		if _, err := Load(writeSuite(t, content)); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestRunPasses(t *testing.T) {
+	cases := []Case{
+		{
+			Name:  "parsed",
+			Input: `{"amount": 5, "level": "error", "message": "declined"}`,
+			Expect: []Expected{{
+				Level:        "error",
+				Message:      "declined",
+				Fields:       map[string]interface{}{"amount": 5, "team": "payments"},
+				AbsentFields: []string{"debug"},
+			}},
+		},
+		{Name: "dropped", Input: `{"debug": true}`, Dropped: true},
+		{Name: "two lines", Input: "{\"n\": 1}\n{\"n\": 2}\n", Expect: []Expected{
+			{Fields: map[string]interface{}{"n": 1}},
+			{Fields: map[string]interface{}{"n": 2}},
+		}},
+		{Name: "invalid", Input: "not json", Error: "parser:json"},
+	}
+
+	results, err := Run(jsonPipeline(), cases)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("Expected %s to pass, got %+v", r.Name, r.Mismatches)
+		}
+	}
+}
+
+func TestRunReportsMismatches(t *testing.T) {
+	cases := []Case{{
+		Name:  "wrong",
+		Input: `{"amount": 5, "password": "secret"}`,
+		Expect: []Expected{{
+			Level:        "WARN",
+			Fields:       map[string]interface{}{"amount": "5", "region": "eu"},
+			AbsentFields: []string{"password"},
+			Tags:         []string{"billing"},
+		}},
+	}}
+
+	results, err := Run(jsonPipeline(), cases)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	paths := make([]string, 0)
+	for _, m := range results[0].Mismatches {
+		paths = append(paths, m.Path)
+	}
+	want := []string{
+		"entries[0].level",
+		"entries[0].fields.amount",
+		"entries[0].fields.region",
+		"entries[0].fields.password",
+		"entries[0].tags",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected mismatches %v, got %v", want, paths)
+	}
+	if m := results[0].Mismatches[1]; m.Expected != `"5"` || m.Actual != "5" {
+		t.Errorf("Expected typed values in diff, got %+v", m)
+	}
+}
+
+func TestRunRejectsInvalidPipeline(t *testing.T) {
+	def := jsonPipeline()
+	def.Parser = "nope"
+	if _, err := Run(def, []Case{{Name: "a", Input: "x"}}); err == nil {
+		t.Error("Expected error for invalid pipeline")
+	}
+}
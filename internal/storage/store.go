@@ -11,6 +11,12 @@ import (
 	"github.com/UmangDiyora/logpipeline/pkg/models"
 )
 
+func init() {
+	// Entry fields may hold decoded JSON containers
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
 // Store is the interface for log storage
 type Store interface {
 	// Write writes a log entry
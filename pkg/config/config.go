@@ -1,8 +1,11 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -84,8 +87,11 @@ type InputConfig struct {
 
 // MultilineConfig represents multiline log configuration
 type MultilineConfig struct {
+	// Preset selects built-in rules instead of a pattern (stacktrace)
+	Preset string `yaml:"preset,omitempty"`
+
 	// Pattern is the regex pattern to match
-	Pattern string `yaml:"pattern"`
+	Pattern string `yaml:"pattern,omitempty"`
 
 	// Negate inverts the pattern matching
 	Negate bool `yaml:"negate"`
@@ -189,9 +195,24 @@ type ServerConfig struct {
 	// Pipelines configuration
 	Pipelines []PipelineConfig `yaml:"pipelines,omitempty"`
 
+	// Routing of entries to pipelines
+	Routing RoutingConfig `yaml:"routing,omitempty"`
+
+	// PipelineStore is the directory of pipelines created through the API
+	// (default: pipelines under the storage path)
+	PipelineStore string `yaml:"pipeline_store,omitempty"`
+
+	// PipelineFiles is the directory that spill directories and lookup
+	// files of pipelines created through the API must be in (default:
+	// files under the pipeline store)
+	PipelineFiles string `yaml:"pipeline_files,omitempty"`
+
 	// Metrics settings
 	Metrics MetricsConfig `yaml:"metrics,omitempty"`
 
+	// DeadLetter keeps entries that failed in a pipeline
+	DeadLetter DeadLetterConfig `yaml:"dead_letter,omitempty"`
+
 	// Alerts configuration
 	Alerts []AlertConfig `yaml:"alerts,omitempty"`
 }
@@ -240,14 +261,104 @@ type PipelineConfig struct {
 	// Name is the pipeline name
 	Name string `yaml:"name"`
 
-	// Filter determines which logs enter this pipeline
+	// Filter determines which logs enter this pipeline. Only the default
+	// pipeline of the routing config may omit it.
 	Filter string `yaml:"filter,omitempty"`
 
 	// Parser to use
 	Parser string `yaml:"parser,omitempty"`
 
+	// ParserOptions configure the parser
+	ParserOptions *ParserOptions `yaml:"parser_options,omitempty"`
+
 	// Processors to apply
 	Processors []ProcessorConfig `yaml:"processors,omitempty"`
+
+	// Workers is the number of concurrent workers (default 4)
+	Workers int `yaml:"workers,omitempty"`
+
+	// QueueSize is the capacity of the pipeline input queue (default 1000)
+	QueueSize int `yaml:"queue_size,omitempty"`
+
+	// PartitionBy keeps entries with the same key in order by giving each
+	// key to a single worker: "source_host" or "field:<name>". Entries are
+	// partitioned before the parser runs, so the field must be set when
+	// the entry arrives, e.g. by the agent; a field the parser extracts is
+	// still missing and every entry would go to the same worker. By
+	// default workers share one queue and may reorder entries. Pipelines
+	// with the docker or cri parser are always partitioned by source_host
+	// so that split lines are reassembled in order.
+	PartitionBy string `yaml:"partition_by,omitempty"`
+
+	// Overflow is the policy when the output is full (block, drop_oldest,
+	// drop_newest, spill_to_disk; default block)
+	Overflow string `yaml:"overflow,omitempty"`
+
+	// SpillDir holds entries spilled by the spill_to_disk policy. Each
+	// pipeline needs a directory of its own.
+	SpillDir string `yaml:"spill_dir,omitempty"`
+}
+
+// ParserOptions configures a pipeline parser
+type ParserOptions struct {
+	// Fields maps destination field names to source paths
+	Fields map[string]string `yaml:"fields,omitempty"`
+
+	// Patterns for regex parsing; the regex parser reads "pattern"
+	Patterns map[string]string `yaml:"patterns,omitempty"`
+
+	// TimeFormat is the timestamp layout
+	TimeFormat string `yaml:"time_format,omitempty"`
+
+	// TimeFormats are additional layouts tried after TimeFormat
+	TimeFormats []string `yaml:"time_formats,omitempty"`
+
+	// Timezone is applied to timestamps without zone information
+	Timezone string `yaml:"timezone,omitempty"`
+
+	// TimeField is the field containing the timestamp
+	TimeField string `yaml:"time_field,omitempty"`
+
+	// Flatten expands nested JSON objects into dotted field names
+	Flatten bool `yaml:"flatten,omitempty"`
+
+	// MaxDepth limits flattening depth
+	MaxDepth int `yaml:"max_depth,omitempty"`
+
+	// Separator joins flattened keys
+	Separator string `yaml:"separator,omitempty"`
+
+	// ArrayMode controls flattened arrays (json, index)
+	ArrayMode string `yaml:"array_mode,omitempty"`
+
+	// KeepJSON lists paths whose subtrees are stored as JSON strings
+	KeepJSON []string `yaml:"keep_json,omitempty"`
+
+	// AppPackages lists function or path prefixes of application frames
+	// for the stacktrace parser
+	AppPackages []string `yaml:"app_packages,omitempty"`
+
+	// Inner is the parser applied to messages unwrapped by docker or cri
+	Inner *InnerParserConfig `yaml:"inner,omitempty"`
+}
+
+// InnerParserConfig names a parser nested in an envelope parser
+type InnerParserConfig struct {
+	// Type is the parser type
+	Type string `yaml:"type"`
+
+	// Options for the parser
+	ParserOptions `yaml:",inline"`
+}
+
+// RoutingConfig controls how entries are routed to pipelines
+type RoutingConfig struct {
+	// Mode sends each entry to the first or to all matching pipelines (first, all)
+	Mode string `yaml:"mode,omitempty"`
+
+	// Default is the pipeline for entries no filter matches; defaults to
+	// the first pipeline without a filter
+	Default string `yaml:"default,omitempty"`
 }
 
 // MetricsConfig represents metrics configuration
@@ -262,6 +373,18 @@ type MetricsConfig struct {
 	Path string `yaml:"path,omitempty"`
 }
 
+// DeadLetterConfig represents dead-letter queue configuration
+type DeadLetterConfig struct {
+	// Enabled indicates if failed entries are kept
+	Enabled bool `yaml:"enabled"`
+
+	// Path is the dead-letter directory (default: dlq under the storage path)
+	Path string `yaml:"path,omitempty"`
+
+	// MaxRecords is the number of records kept, oldest first out
+	MaxRecords int `yaml:"max_records,omitempty"`
+}
+
 // AlertConfig represents an alert rule
 type AlertConfig struct {
 	// Name is the alert name
@@ -319,8 +442,11 @@ func LoadServerConfig(path string) (*ServerConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Unknown keys are rejected so that misspelled options fail at startup
 	var config ServerConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
@@ -337,5 +463,21 @@ func LoadServerConfig(path string) (*ServerConfig, error) {
 		config.Metrics.Port = 2112
 	}
 
+	if config.Metrics.Enabled && config.Metrics.Path == "" {
+		config.Metrics.Path = "/metrics"
+	}
+
+	if config.PipelineStore == "" {
+		config.PipelineStore = filepath.Join(config.Storage.Path, "pipelines")
+	}
+
+	if config.PipelineFiles == "" {
+		config.PipelineFiles = filepath.Join(config.PipelineStore, "files")
+	}
+
+	if config.DeadLetter.Enabled && config.DeadLetter.Path == "" {
+		config.DeadLetter.Path = filepath.Join(config.Storage.Path, "dlq")
+	}
+
 	return &config, nil
 }
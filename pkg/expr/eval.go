@@ -0,0 +1,562 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/lru"
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// node is an evaluable expression tree node
+type node interface {
+	eval(entry *models.LogEntry) (interface{}, error)
+}
+
+// literalNode is a constant value
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(entry *models.LogEntry) (interface{}, error) {
+	return n.value, nil
+}
+
+// fieldNode references a core entry field or a value in Fields
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(entry *models.LogEntry) (interface{}, error) {
+	value, _ := Lookup(entry, n.name)
+	return value, nil
+}
+
+// listNode is a list literal
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(entry *models.LogEntry) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(entry)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// logicalNode implements short-circuit && and ||
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(entry *models.LogEntry) (interface{}, error) {
+	left, err := n.left.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "&&" && !Truthy(left) {
+		return false, nil
+	}
+	if n.op == "||" && Truthy(left) {
+		return true, nil
+	}
+
+	right, err := n.right.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+	return Truthy(right), nil
+}
+
+// notNode negates its operand
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(entry *models.LogEntry) (interface{}, error) {
+	v, err := n.operand.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+	return !Truthy(v), nil
+}
+
+// compareNode implements ==, !=, <, <=, > and >=
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(entry *models.LogEntry) (interface{}, error) {
+	left, err := n.left.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false, nil
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+// arithNode implements +, -, *, / and %. The + operator adds numbers and
+// concatenates anything else.
+type arithNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithNode) eval(entry *models.LogEntry) (interface{}, error) {
+	left, err := n.left.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Arithmetic on a missing field yields a missing value
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	a, okA := ToNumber(left)
+	b, okB := ToNumber(right)
+	if !okA || !okB {
+		if n.op == "+" {
+			return ToString(left) + ToString(right), nil
+		}
+		return nil, fmt.Errorf("operator %s requires numbers, got %s and %s", n.op, typeName(left), typeName(right))
+	}
+
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	case "%":
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(a, b), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+// negateNode implements unary minus
+type negateNode struct {
+	operand node
+}
+
+func (n *negateNode) eval(entry *models.LogEntry) (interface{}, error) {
+	v, err := n.operand.eval(entry)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	f, ok := ToNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("unary minus requires a number, got %s", typeName(v))
+	}
+	return -f, nil
+}
+
+// typeName describes the type of a value for error messages
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	case time.Time:
+		return "timestamp"
+	}
+	if isNumeric(v) {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// matchNode implements =~ and !~
+type matchNode struct {
+	left, right node
+	re          *regexp.Regexp
+	negate      bool
+}
+
+// dynamicPatternCacheSize bounds the regexes cached for non-literal
+// operands, whose patterns come from entries
+const dynamicPatternCacheSize = 1000
+
+// dynamicPatterns caches regexes built from non-literal operands
+var dynamicPatterns = lru.New[string, *regexp.Regexp](dynamicPatternCacheSize)
+
+func (n *matchNode) eval(entry *models.LogEntry) (interface{}, error) {
+	left, err := n.left.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil {
+		return n.negate, nil
+	}
+
+	re := n.re
+	if re == nil {
+		right, err := n.right.eval(entry)
+		if err != nil {
+			return nil, err
+		}
+		pattern := ToString(right)
+		if cached, ok := dynamicPatterns.Get(pattern); ok {
+			re = cached
+		} else {
+			re, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+			}
+			dynamicPatterns.Add(pattern, re)
+		}
+	}
+
+	return re.MatchString(ToString(left)) != n.negate, nil
+}
+
+// inNode implements membership tests against lists and substrings
+type inNode struct {
+	left, right node
+	negate      bool
+}
+
+func (n *inNode) eval(entry *models.LogEntry) (interface{}, error) {
+	left, err := n.left.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	switch r := right.(type) {
+	case []interface{}:
+		for _, item := range r {
+			if equal(left, item) {
+				found = true
+				break
+			}
+		}
+	case []string:
+		for _, item := range r {
+			if equal(left, item) {
+				found = true
+				break
+			}
+		}
+	case map[string]interface{}:
+		_, found = r[ToString(left)]
+	case string:
+		found = left != nil && strings.Contains(r, ToString(left))
+	}
+
+	return found != n.negate, nil
+}
+
+// callNode invokes a built-in function
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+}
+
+func (n *callNode) eval(entry *models.LogEntry) (interface{}, error) {
+	v, err := n.fn.call(entry, n.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// Lookup resolves a name against an entry. Core fields (level, message,
+// source, host, service, id, raw, timestamp, tags) take precedence;
+// other names are looked up in Fields, either as a literal key or as a
+// dotted path into nested maps. A "fields." prefix forces a Fields lookup.
+func Lookup(entry *models.LogEntry, name string) (interface{}, bool) {
+	if entry == nil {
+		return nil, false
+	}
+
+	if rest, ok := strings.CutPrefix(name, "fields."); ok {
+		return lookupField(entry.Fields, rest)
+	}
+
+	switch name {
+	case "level":
+		return string(entry.Level), entry.Level != ""
+	case "message":
+		return entry.Message, true
+	case "source":
+		return entry.Source, entry.Source != ""
+	case "host":
+		return entry.Host, entry.Host != ""
+	case "service":
+		return entry.Service, entry.Service != ""
+	case "id":
+		return entry.ID, entry.ID != ""
+	case "raw":
+		return entry.Raw, true
+	case "timestamp":
+		return entry.Timestamp, !entry.Timestamp.IsZero()
+	case "tags":
+		tags := make([]interface{}, len(entry.Tags))
+		for i, tag := range entry.Tags {
+			tags[i] = tag
+		}
+		return tags, true
+	}
+
+	return lookupField(entry.Fields, name)
+}
+
+// lookupField finds a literal key or walks a dotted path into nested maps
+func lookupField(fields map[string]interface{}, name string) (interface{}, bool) {
+	if fields == nil {
+		return nil, false
+	}
+	if v, ok := fields[name]; ok {
+		return v, true
+	}
+
+	head, rest, found := strings.Cut(name, ".")
+	if !found {
+		return nil, false
+	}
+	child, ok := fields[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupField(child, rest)
+}
+
+// Truthy reports whether a value counts as true in a boolean context
+func Truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != "" && !strings.EqualFold(t, "false") && t != "0"
+	case []interface{}:
+		return len(t) > 0
+	case time.Time:
+		return !t.IsZero()
+	}
+	if f, ok := ToNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// ToNumber coerces a value to float64
+func ToNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int8:
+		return float64(t), true
+	case int16:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint8:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case time.Duration:
+		return float64(t), true
+	case bool:
+		if t {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ToString formats a value as a string
+func ToString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case models.LogLevel:
+		return string(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return t.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// isNumeric reports whether v is a native numeric type
+func isNumeric(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	}
+	return false
+}
+
+// equal compares two values with type coercion. Numbers compare
+// numerically when either side is numeric, booleans accept "true" and
+// "false" strings, and timestamps accept RFC3339 strings.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if isNumeric(a) || isNumeric(b) {
+		fa, okA := ToNumber(a)
+		fb, okB := ToNumber(b)
+		if okA && okB {
+			return fa == fb
+		}
+	}
+
+	if ba, ok := a.(bool); ok {
+		return ba == Truthy(b)
+	}
+	if bb, ok := b.(bool); ok {
+		return bb == Truthy(a)
+	}
+
+	if ta, tb, ok := asTimes(a, b); ok {
+		return ta.Equal(tb)
+	}
+
+	return ToString(a) == ToString(b)
+}
+
+// compare orders two values, returning false if they are not comparable
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	if ta, tb, ok := asTimes(a, b); ok {
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	fa, okA := ToNumber(a)
+	fb, okB := ToNumber(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if isNumeric(a) || isNumeric(b) {
+		return 0, false
+	}
+
+	return strings.Compare(ToString(a), ToString(b)), true
+}
+
+// asTimes converts a pair of values to times when at least one is a time
+func asTimes(a, b interface{}) (time.Time, time.Time, bool) {
+	ta, okA := a.(time.Time)
+	tb, okB := b.(time.Time)
+	if !okA && !okB {
+		return time.Time{}, time.Time{}, false
+	}
+
+	var err error
+	if !okA {
+		if ta, err = time.Parse(time.RFC3339Nano, ToString(a)); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if !okB {
+		if tb, err = time.Parse(time.RFC3339Nano, ToString(b)); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	return ta, tb, true
+}
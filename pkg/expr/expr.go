@@ -0,0 +1,76 @@
+// Package expr implements a small expression language for conditions over
+// log entries.
+//
+// Expressions reference core entry fields (level, message, source, host,
+// service, id, raw, timestamp, tags) and values in Fields by name, with
+// dotted paths reaching into nested maps. Supported operators are the
+// arithmetic operators +, -, *, / and %, comparisons ==, !=, <, <=, >, >=,
+// =~ and !~ (regex), in and not in, and the boolean operators &&, || and !
+// (also spelled and, or, not). + concatenates when either side is not a
+// number. Arithmetic on a missing field yields null.
+//
+// Built-in functions are exists(field), field("name with spaces"), the
+// string functions lower, upper, trim, len, concat, substr, replace, split,
+// contains, starts_with, ends_with and coalesce, and the numeric functions
+// abs, ceil, floor, round, min, max, int, float and string.
+//
+// Example:
+//
+//	level == "ERROR" && service =~ "^api-" && status >= 500
+//	round(bytes / 1024, 1)
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Expression is a compiled expression
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses an expression
+func Compile(src string) (*Expression, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	root, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile expression %q: %w", src, err)
+	}
+
+	return &Expression{src: src, root: root}, nil
+}
+
+// MustCompile is like Compile but panics on error
+func MustCompile(src string) *Expression {
+	e, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Eval evaluates the expression against an entry and returns its value
+func (e *Expression) Eval(entry *models.LogEntry) (interface{}, error) {
+	return e.root.eval(entry)
+}
+
+// Match evaluates the expression as a condition
+func (e *Expression) Match(entry *models.LogEntry) (bool, error) {
+	v, err := e.root.eval(entry)
+	if err != nil {
+		return false, err
+	}
+	return Truthy(v), nil
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.src
+}
@@ -0,0 +1,261 @@
+package expr
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func newTestEntry() *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Level = models.LogLevelError
+	entry.Message = "connection refused"
+	entry.Service = "api-gateway"
+	entry.Host = "web-01"
+	entry.Timestamp = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry.AddTag("prod")
+	entry.AddField("status", 503)
+	entry.AddField("duration", "1.5")
+	entry.AddField("user agent", "curl")
+	entry.AddField("http", map[string]interface{}{
+		"method": "POST",
+	})
+	return entry
+}
+
+func TestMatch(t *testing.T) {
+	entry := newTestEntry()
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`level == "ERROR" && service =~ "^api-"`, true},
+		{`level == "ERROR" and service =~ "^web-"`, false},
+		{`level != "ERROR" || host == "web-01"`, true},
+		{`!(level == "ERROR")`, false},
+		{`not level == "INFO"`, true},
+		{`status >= 500`, true},
+		{`status == "503"`, true},
+		{`duration > 1`, true},
+		{`duration < 1.5`, false},
+		{`status in [500, 502, 503]`, true},
+		{`level not in ["DEBUG", "INFO"]`, true},
+		{`"prod" in tags`, true},
+		{`"refused" in message`, true},
+		{`http.method == "POST"`, true},
+		{`fields.http.method == "POST"`, true},
+		{`exists(status)`, true},
+		{`exists(missing)`, false},
+		{`!exists("user agent")`, false},
+		{`field("user agent") == "curl"`, true},
+		{`missing == "x"`, false},
+		{`missing > 1`, false},
+		{`missing < 1`, false},
+		{`missing == null`, true},
+		{`message !~ "timeout"`, true},
+		{`message =~ '\brefused$'`, true},
+		{`timestamp > "2023-12-31T00:00:00Z"`, true},
+		{`timestamp == "2024-01-01T12:00:00Z"`, true},
+		{`status > -1`, true},
+		{`status`, true},
+		{`missing`, false},
+	}
+
+	for _, tt := range tests {
+		e, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		got, err := e.Match(entry)
+		if err != nil {
+			t.Errorf("Match(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Match(%q) = %v, expected %v", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	entry := newTestEntry()
+
+	// && binds tighter than ||
+	e := MustCompile(`host == "x" && level == "DEBUG" || service == "api-gateway"`)
+	if ok, _ := e.Match(entry); !ok {
+		t.Error("Expected && to bind tighter than ||")
+	}
+
+	e = MustCompile(`host == "x" && (level == "DEBUG" || service == "api-gateway")`)
+	if ok, _ := e.Match(entry); ok {
+		t.Error("Expected parentheses to group the || expression")
+	}
+}
+
+func TestDynamicRegex(t *testing.T) {
+	entry := newTestEntry()
+	entry.AddField("pattern", "^conn")
+
+	e := MustCompile(`message =~ pattern`)
+	if ok, err := e.Match(entry); err != nil || !ok {
+		t.Errorf("Expected dynamic pattern to match, got %v (%v)", ok, err)
+	}
+
+	entry.AddField("pattern", "(")
+	if _, err := e.Match(entry); err == nil {
+		t.Error("Expected error for invalid dynamic pattern")
+	}
+
+	// Patterns taken from entries must not grow the cache without bound
+	for i := 0; i < 2*dynamicPatternCacheSize; i++ {
+		entry.AddField("pattern", fmt.Sprintf("^conn%d", i))
+		e.Match(entry)
+	}
+	if n := dynamicPatterns.Len(); n > dynamicPatternCacheSize {
+		t.Errorf("Expected at most %d cached patterns, got %d", dynamicPatternCacheSize, n)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`level ==`,
+		`level == "ERROR" &&`,
+		`(level == "ERROR"`,
+		`message =~ "("`,
+		`unknown(level)`,
+		`exists()`,
+		`exists("a", "b")`,
+		`exists(1)`,
+		`status in [1, 2`,
+		`"unterminated`,
+		`level # "x"`,
+		`status *`,
+		`lower()`,
+		`substr(message)`,
+	}
+
+	for _, src := range tests {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Expected Compile(%q) to fail", src)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	entry := newTestEntry()
+
+	v, err := MustCompile(`http.method`).Eval(entry)
+	if err != nil || v != "POST" {
+		t.Errorf("Expected POST, got %v (%v)", v, err)
+	}
+
+	v, err = MustCompile(`level`).Eval(entry)
+	if err != nil || v != "ERROR" {
+		t.Errorf("Expected ERROR, got %v (%v)", v, err)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	entry := newTestEntry()
+	entry.AddField("bytes", 2048)
+	entry.AddField("count", 4)
+
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`1 + 2 * 3`, float64(7)},
+		{`(1 + 2) * 3`, float64(9)},
+		{`bytes / 1024`, float64(2)},
+		{`bytes % 1000`, float64(48)},
+		{`duration * 1000`, float64(1500)},
+		{`-count + 1`, float64(-3)},
+		{`10 - 4 - 3`, float64(3)},
+		{`service + "/" + host`, "api-gateway/web-01"},
+		{`"v" + count`, "v4"},
+		{`missing + 1`, nil},
+	}
+
+	for _, tt := range tests {
+		v, err := MustCompile(tt.expr).Eval(entry)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if v != tt.expected {
+			t.Errorf("Eval(%q) = %v, expected %v", tt.expr, v, tt.expected)
+		}
+	}
+
+	if ok, _ := MustCompile(`bytes / count > 500`).Match(entry); !ok {
+		t.Error("Expected arithmetic to bind tighter than comparison")
+	}
+
+	for _, src := range []string{`bytes / 0`, `bytes % 0`, `service * 2`, `-service`} {
+		if _, err := MustCompile(src).Eval(entry); err == nil {
+			t.Errorf("Expected Eval(%q) to fail", src)
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	entry := newTestEntry()
+	entry.AddField("path", "/api/v1/users")
+
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`upper(service)`, "API-GATEWAY"},
+		{`lower(level)`, "error"},
+		{`trim("  x ")`, "x"},
+		{`len(message)`, float64(18)},
+		{`len(tags)`, float64(1)},
+		{`concat(service, ":", status)`, "api-gateway:503"},
+		{`substr(service, 0, 3)`, "api"},
+		{`substr(service, -7)`, "gateway"},
+		{`substr(service, 4, 100)`, "gateway"},
+		{`replace(path, "/", ".")`, ".api.v1.users"},
+		{`contains(path, "/v1/")`, true},
+		{`starts_with(path, "/api")`, true},
+		{`ends_with(path, "/orders")`, false},
+		{`coalesce(missing, "", host)`, "web-01"},
+		{`round(duration * 1.234, 2)`, 1.85},
+		{`round(2.5)`, float64(3)},
+		{`floor(duration)`, float64(1)},
+		{`ceil(duration)`, float64(2)},
+		{`abs(-3)`, float64(3)},
+		{`min(status, 100, missing)`, float64(100)},
+		{`max(status, 100)`, float64(503)},
+		{`int(duration)`, float64(1)},
+		{`float("2.5")`, 2.5},
+		{`string(status)`, "503"},
+		{`upper(missing)`, nil},
+	}
+
+	for _, tt := range tests {
+		v, err := MustCompile(tt.expr).Eval(entry)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if v != tt.expected {
+			t.Errorf("Eval(%q) = %v (%T), expected %v", tt.expr, v, v, tt.expected)
+		}
+	}
+
+	v, err := MustCompile(`split(path, "/")`).Eval(entry)
+	parts, ok := v.([]interface{})
+	if err != nil || !ok || len(parts) != 4 || parts[1] != "api" {
+		t.Errorf("Unexpected split result %v (%v)", v, err)
+	}
+
+	if _, err := MustCompile(`float(service)`).Eval(entry); err == nil {
+		t.Error("Expected float of a non-number to fail")
+	}
+}
@@ -0,0 +1,255 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// function describes a built-in function. Functions with fieldArg take a
+// field reference as their first argument and receive the unevaluated
+// nodes; other functions receive evaluated values through call.
+type function struct {
+	minArgs  int
+	maxArgs  int
+	fieldArg bool
+	call     func(entry *models.LogEntry, args []node) (interface{}, error)
+}
+
+// valueFunction adapts a function over evaluated arguments
+func valueFunction(minArgs, maxArgs int, fn func(args []interface{}) (interface{}, error)) *function {
+	return &function{
+		minArgs: minArgs,
+		maxArgs: maxArgs,
+		call: func(entry *models.LogEntry, args []node) (interface{}, error) {
+			values := make([]interface{}, len(args))
+			for i, arg := range args {
+				v, err := arg.eval(entry)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return fn(values)
+		},
+	}
+}
+
+// stringFunction adapts a function of one string; missing values stay missing
+func stringFunction(fn func(s string) interface{}) *function {
+	return valueFunction(1, 1, func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return fn(ToString(args[0])), nil
+	})
+}
+
+// numberFunction adapts a function of one number; missing values stay missing
+func numberFunction(fn func(f float64) float64) *function {
+	return valueFunction(1, 1, func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		f, ok := ToNumber(args[0])
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %q", ToString(args[0]))
+		}
+		return fn(f), nil
+	})
+}
+
+// functions lists the built-in functions by name
+var functions map[string]*function
+
+func init() {
+	functions = map[string]*function{
+		"exists": {
+			minArgs:  1,
+			maxArgs:  1,
+			fieldArg: true,
+			call: func(entry *models.LogEntry, args []node) (interface{}, error) {
+				_, ok := Lookup(entry, args[0].(*fieldNode).name)
+				return ok, nil
+			},
+		},
+		"field": {
+			minArgs:  1,
+			maxArgs:  1,
+			fieldArg: true,
+			call: func(entry *models.LogEntry, args []node) (interface{}, error) {
+				return args[0].eval(entry)
+			},
+		},
+
+		// String functions
+		"lower": stringFunction(func(s string) interface{} { return strings.ToLower(s) }),
+		"upper": stringFunction(func(s string) interface{} { return strings.ToUpper(s) }),
+		"trim":  stringFunction(func(s string) interface{} { return strings.TrimSpace(s) }),
+		"len": valueFunction(1, 1, func(args []interface{}) (interface{}, error) {
+			switch v := args[0].(type) {
+			case nil:
+				return float64(0), nil
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			}
+			return float64(len([]rune(ToString(args[0])))), nil
+		}),
+		"concat": valueFunction(1, -1, func(args []interface{}) (interface{}, error) {
+			var b strings.Builder
+			for _, arg := range args {
+				b.WriteString(ToString(arg))
+			}
+			return b.String(), nil
+		}),
+		"substr": valueFunction(2, 3, func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			runes := []rune(ToString(args[0]))
+			start, err := intArg(args[1], "start")
+			if err != nil {
+				return nil, err
+			}
+			if start < 0 {
+				start += len(runes)
+			}
+			start = clamp(start, 0, len(runes))
+			end := len(runes)
+			if len(args) == 3 {
+				length, err := intArg(args[2], "length")
+				if err != nil {
+					return nil, err
+				}
+				end = clamp(start+length, start, len(runes))
+			}
+			return string(runes[start:end]), nil
+		}),
+		"replace": valueFunction(3, 3, func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			return strings.ReplaceAll(ToString(args[0]), ToString(args[1]), ToString(args[2])), nil
+		}),
+		"split": valueFunction(2, 2, func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			parts := strings.Split(ToString(args[0]), ToString(args[1]))
+			items := make([]interface{}, len(parts))
+			for i, part := range parts {
+				items[i] = part
+			}
+			return items, nil
+		}),
+		"contains": valueFunction(2, 2, func(args []interface{}) (interface{}, error) {
+			return args[0] != nil && strings.Contains(ToString(args[0]), ToString(args[1])), nil
+		}),
+		"starts_with": valueFunction(2, 2, func(args []interface{}) (interface{}, error) {
+			return args[0] != nil && strings.HasPrefix(ToString(args[0]), ToString(args[1])), nil
+		}),
+		"ends_with": valueFunction(2, 2, func(args []interface{}) (interface{}, error) {
+			return args[0] != nil && strings.HasSuffix(ToString(args[0]), ToString(args[1])), nil
+		}),
+		"coalesce": valueFunction(1, -1, func(args []interface{}) (interface{}, error) {
+			for _, arg := range args {
+				if arg != nil && arg != "" {
+					return arg, nil
+				}
+			}
+			return nil, nil
+		}),
+
+		// Numeric functions
+		"abs":   numberFunction(math.Abs),
+		"ceil":  numberFunction(math.Ceil),
+		"floor": numberFunction(math.Floor),
+		"round": valueFunction(1, 2, func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			f, ok := ToNumber(args[0])
+			if !ok {
+				return nil, fmt.Errorf("expected a number, got %q", ToString(args[0]))
+			}
+			digits := 0
+			if len(args) == 2 {
+				var err error
+				if digits, err = intArg(args[1], "digits"); err != nil {
+					return nil, err
+				}
+			}
+			scale := math.Pow(10, float64(digits))
+			return math.Round(f*scale) / scale, nil
+		}),
+		"min": valueFunction(1, -1, func(args []interface{}) (interface{}, error) {
+			return extreme(args, func(a, b float64) bool { return a < b })
+		}),
+		"max": valueFunction(1, -1, func(args []interface{}) (interface{}, error) {
+			return extreme(args, func(a, b float64) bool { return a > b })
+		}),
+
+		// Conversions
+		"int": numberFunction(math.Trunc),
+		"float": valueFunction(1, 1, func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			f, ok := ToNumber(args[0])
+			if !ok {
+				return nil, fmt.Errorf("expected a number, got %q", ToString(args[0]))
+			}
+			return f, nil
+		}),
+		"string": valueFunction(1, 1, func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			return ToString(args[0]), nil
+		}),
+	}
+}
+
+// intArg converts an argument to an int
+func intArg(v interface{}, name string) (int, error) {
+	f, ok := ToNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number, got %q", name, ToString(v))
+	}
+	return int(f), nil
+}
+
+// clamp limits n to the range [lo, hi]
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+
+// extreme returns the argument preferred by better, ignoring missing values
+func extreme(args []interface{}, better func(a, b float64) bool) (interface{}, error) {
+	var result interface{}
+	best := 0.0
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		f, ok := ToNumber(arg)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %q", ToString(arg))
+		}
+		if result == nil || better(f, best) {
+			best = f
+			result = f
+		}
+	}
+	return result, nil
+}
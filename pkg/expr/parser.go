@@ -0,0 +1,353 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parser builds an expression tree from tokens using recursive descent.
+//
+// Grammar, lowest precedence first:
+//
+//	or             = and { ("||" | "or") and }
+//	and            = not { ("&&" | "and") not }
+//	not            = ("!" | "not") not | comparison
+//	comparison     = additive [ compOp additive | ["not"] "in" additive ]
+//	additive       = multiplicative { ("+" | "-") multiplicative }
+//	multiplicative = unary { ("*" | "/" | "%") unary }
+//	unary          = "-" unary | primary
+//	primary        = literal | field | call | list | "(" or ")"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse compiles source into an expression tree
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOp consumes the next token if it is one of the given operators or
+// keywords and returns its canonical form
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.value == op || (tok.kind == tokenIdent && strings.EqualFold(tok.value, op)) {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, fmt.Errorf("expected %s at position %d, got %s", what, tok.pos, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.acceptOp("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	// "not in" needs two tokens of lookahead
+	if tok := p.peek(); tok.kind == tokenIdent && strings.EqualFold(tok.value, "not") {
+		if p.pos+1 < len(p.tokens) && strings.EqualFold(p.tokens[p.pos+1].value, "in") {
+			p.pos += 2
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &inNode{left: left, right: right, negate: true}, nil
+		}
+	}
+
+	op, ok := p.acceptOp("==", "!=", "<=", ">=", "<", ">", "=~", "!~", "in")
+	if !ok {
+		return left, nil
+	}
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "in":
+		return &inNode{left: left, right: right}, nil
+	case "=~", "!~":
+		return newMatchNode(left, right, op == "!~")
+	default:
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// Fold negative literals so that they stay constant
+		if lit, ok := operand.(*literalNode); ok {
+			if f, ok := lit.value.(float64); ok {
+				return &literalNode{value: -f}, nil
+			}
+		}
+		return &negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.value}, nil
+
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.value, tok.pos)
+		}
+		return &literalNode{value: f}, nil
+
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case tokenLBracket:
+		return p.parseList()
+
+	case tokenIdent:
+		switch strings.ToLower(tok.value) {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		}
+
+		if p.peek().kind == tokenLParen {
+			p.next()
+			return p.parseCall(tok)
+		}
+		return &fieldNode{name: tok.value}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+}
+
+func (p *parser) parseList() (node, error) {
+	items := make([]node, 0)
+	if p.peek().kind == tokenRBracket {
+		p.next()
+		return &listNode{items: items}, nil
+	}
+
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		tok := p.next()
+		switch tok.kind {
+		case tokenComma:
+			continue
+		case tokenRBracket:
+			return &listNode{items: items}, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' at position %d, got %s", tok.pos, tok)
+		}
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[strings.ToLower(name.value)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.value, name.pos)
+	}
+
+	args := make([]node, 0)
+	if p.peek().kind == tokenRParen {
+		p.next()
+	} else {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			tok := p.next()
+			if tok.kind == tokenRParen {
+				break
+			}
+			if tok.kind != tokenComma {
+				return nil, fmt.Errorf("expected ',' or ')' at position %d, got %s", tok.pos, tok)
+			}
+		}
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s: got %d", name.value, len(args))
+	}
+
+	if fn.fieldArg {
+		// Functions like exists() take a field reference; allow quoting
+		// names that are not valid identifiers
+		if lit, ok := args[0].(*literalNode); ok {
+			if s, ok := lit.value.(string); ok {
+				args[0] = &fieldNode{name: s}
+			}
+		}
+		if _, ok := args[0].(*fieldNode); !ok {
+			return nil, fmt.Errorf("%s expects a field name", name.value)
+		}
+	}
+
+	return &callNode{name: strings.ToLower(name.value), fn: fn, args: args}, nil
+}
+
+// newMatchNode creates a regex match node, compiling literal patterns once
+func newMatchNode(left, right node, negate bool) (node, error) {
+	n := &matchNode{left: left, right: right, negate: negate}
+	if lit, ok := right.(*literalNode); ok {
+		pattern, ok := lit.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("regex pattern must be a string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		n.re = re
+	}
+	return n, nil
+}
@@ -0,0 +1,73 @@
+// Package lru provides a fixed size least recently used cache.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a fixed size, concurrency safe least recently used cache
+type Cache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[K]*list.Element
+}
+
+// item is a cached key and value
+type item[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New creates a cache holding up to capacity items
+func New[K comparable, V any](capacity int) *Cache[K, V] {
+	return &Cache[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// Get returns a cached value and marks it as recently used
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*item[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Add stores a value, evicting the least recently used item when full
+func (c *Cache[K, V]) Add(key K, value V) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*item[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*item[K, V]).key)
+	}
+	c.items[key] = c.order.PushFront(&item[K, V]{key: key, value: value})
+}
+
+// Len returns the number of cached items
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,44 @@
+package lru
+
+import "testing"
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// Reading a makes b the least recently used
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Expected a=1, got %v %v", v, ok)
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected a to stay cached, got %v %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 items, got %d", c.Len())
+	}
+}
+
+func TestCacheUpdatesExistingKey(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("a", 2)
+
+	if v, _ := c.Get("a"); v != 2 || c.Len() != 1 {
+		t.Errorf("Expected a=2 in 1 item, got %v in %d", v, c.Len())
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := New[string, int](0)
+	c.Add("a", 1)
+
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("Expected a cache without capacity to store nothing")
+	}
+}
@@ -33,6 +33,16 @@ type Pipeline struct {
 	// Output defines where processed logs should be sent
 	Output OutputConfig `json:"output" yaml:"output"`
 
+	// Workers is the number of concurrent workers
+	Workers int `json:"workers,omitempty" yaml:"workers,omitempty"`
+
+	// QueueSize is the capacity of the pipeline input queue
+	QueueSize int `json:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+
+	// PartitionBy keeps logs with the same key in order: "source_host" or
+	// "field:<name>". The docker and cri parsers require source_host.
+	PartitionBy string `json:"partition_by,omitempty" yaml:"partition_by,omitempty"`
+
 	// Status is the current operational status
 	Status PipelineStatus `json:"status"`
 
@@ -93,13 +103,63 @@ type PipelineMetrics struct {
 	// BytesProcessed is the total bytes processed
 	BytesProcessed uint64 `json:"bytes_processed"`
 
+	// LogsFiltered is the number of logs removed by processors such as
+	// filter, drop and sample
+	LogsFiltered uint64 `json:"logs_filtered"`
+
+	// LogsDropped is the number of logs discarded by the overflow policy
+	LogsDropped uint64 `json:"logs_dropped"`
+
 	// AverageLatency is the average processing latency in milliseconds
 	AverageLatency float64 `json:"average_latency_ms"`
 
+	// P50Latency, P95Latency and P99Latency are latency percentiles in
+	// milliseconds, estimated from a histogram
+	P50Latency float64 `json:"p50_latency_ms"`
+	P95Latency float64 `json:"p95_latency_ms"`
+	P99Latency float64 `json:"p99_latency_ms"`
+
+	// QueueDepth is the number of logs waiting in the input queue, which
+	// holds up to QueueCapacity logs
+	QueueDepth    int `json:"queue_depth"`
+	QueueCapacity int `json:"queue_capacity"`
+
+	// Errors breaks LogsFailed down by error type
+	Errors map[string]uint64 `json:"errors,omitempty"`
+
+	// Stages holds the metrics of the parser and each processor, in order
+	Stages []StageMetrics `json:"stages,omitempty"`
+
 	// LastProcessedAt is when the last log was processed
 	LastProcessedAt time.Time `json:"last_processed_at,omitempty"`
 }
 
+// StageMetrics contains runtime statistics for one parser or processor
+type StageMetrics struct {
+	// Stage names the stage, e.g. "parser:json" or "processor[2]:geoip"
+	Stage string `json:"stage"`
+
+	// In is the number of logs given to the stage
+	In uint64 `json:"in"`
+
+	// Out is the number of logs the stage produced
+	Out uint64 `json:"out"`
+
+	// Dropped is the number of logs the stage removed
+	Dropped uint64 `json:"dropped"`
+
+	// Errors is the number of logs the stage failed on
+	Errors uint64 `json:"errors"`
+
+	// ErrorTypes breaks Errors down by error type
+	ErrorTypes map[string]uint64 `json:"error_types,omitempty"`
+
+	// AverageLatency and P99Latency are the time spent in the stage per
+	// log in milliseconds
+	AverageLatency float64 `json:"average_latency_ms"`
+	P99Latency     float64 `json:"p99_latency_ms"`
+}
+
 // NewPipeline creates a new pipeline with default values
 func NewPipeline(name string) *Pipeline {
 	now := time.Now()
@@ -135,17 +195,17 @@ func (p *Pipeline) AddProcessor(processor ProcessorConfig) {
 	p.UpdatedAt = time.Now()
 }
 
-// UpdateMetrics updates the pipeline metrics
+// UpdateMetrics updates the pipeline metrics with a batch of processed
+// logs whose average latency is latency
 func (p *Pipeline) UpdateMetrics(processed, failed uint64, bytes uint64, latency float64) {
 	p.Metrics.LogsProcessed += processed
 	p.Metrics.LogsFailed += failed
 	p.Metrics.BytesProcessed += bytes
 
-	// Calculate moving average for latency
-	if p.Metrics.AverageLatency == 0 {
-		p.Metrics.AverageLatency = latency
-	} else {
-		p.Metrics.AverageLatency = (p.Metrics.AverageLatency + latency) / 2
+	// Average over every processed log, weighting the batch by its size
+	if p.Metrics.LogsProcessed > 0 {
+		weight := float64(processed) / float64(p.Metrics.LogsProcessed)
+		p.Metrics.AverageLatency += (latency - p.Metrics.AverageLatency) * weight
 	}
 
 	p.Metrics.LastProcessedAt = time.Now()
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdateMetricsAveragesLatency(t *testing.T) {
+	p := NewPipeline("test")
+
+	p.UpdateMetrics(1, 0, 100, 10)
+	p.UpdateMetrics(1, 0, 100, 20)
+	p.UpdateMetrics(2, 1, 200, 40)
+
+	if p.Metrics.LogsProcessed != 4 || p.Metrics.LogsFailed != 1 || p.Metrics.BytesProcessed != 400 {
+		t.Errorf("Unexpected counters: %+v", p.Metrics)
+	}
+
+	// (10 + 20 + 2*40) / 4
+	if math.Abs(p.Metrics.AverageLatency-27.5) > 1e-9 {
+		t.Errorf("Expected average latency 27.5, got %v", p.Metrics.AverageLatency)
+	}
+
+	p.UpdateMetrics(0, 1, 0, 0)
+	if math.Abs(p.Metrics.AverageLatency-27.5) > 1e-9 {
+		t.Errorf("Expected a batch without processed logs to keep the average, got %v", p.Metrics.AverageLatency)
+	}
+}
@@ -0,0 +1,299 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// ErrPartialEntry is returned when a parser buffered a partial line and is
+// waiting for the rest of the message. The entry should be discarded.
+var ErrPartialEntry = errors.New("partial entry buffered")
+
+// InnerParseFailureTag is added when the inner parser rejects an unwrapped
+// message; the entry is kept with the plain message
+const InnerParseFailureTag = "_inner_parse_failure"
+
+// maxPartialBytes caps reassembled messages so a missing final fragment
+// cannot grow a buffer without bound
+const maxPartialBytes = 1024 * 1024
+
+// partialIdleTimeout is how long a stream may wait for its next fragment.
+// Fragments of a stream that stays idle longer, e.g. because the container
+// exited mid-message, are discarded and counted as evicted in the parser
+// stats.
+const partialIdleTimeout = 30 * time.Second
+
+// ReassemblesLines reports whether a parser type joins lines split by the
+// container runtime. Such parsers must see the lines of a source and host
+// one at a time and in order.
+func ReassemblesLines(parserType string) bool {
+	return parserType == "docker" || parserType == "cri"
+}
+
+// partial is a message being reassembled
+type partial struct {
+	buf     strings.Builder
+	updated time.Time
+}
+
+// partialBuffer reassembles messages split across several lines
+type partialBuffer struct {
+	mu      sync.Mutex
+	pending map[string]*partial
+	swept   time.Time
+	evicted uint64
+
+	// now returns the current time; tests replace it
+	now func() time.Time
+}
+
+// newPartialBuffer creates a new partial buffer
+func newPartialBuffer() *partialBuffer {
+	return &partialBuffer{
+		pending: make(map[string]*partial),
+		now:     time.Now,
+	}
+}
+
+// add appends a fragment. It returns the full message and true once the
+// final fragment arrives or the size limit is hit.
+func (b *partialBuffer) add(key, fragment string, final bool) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.evict(now)
+
+	pending, exists := b.pending[key]
+	if exists && now.Sub(pending.updated) >= partialIdleTimeout {
+		delete(b.pending, key)
+		b.evicted++
+		exists = false
+	}
+	if !exists {
+		if final {
+			return fragment, true
+		}
+		pending = &partial{}
+		b.pending[key] = pending
+	}
+
+	pending.updated = now
+	pending.buf.WriteString(fragment)
+	if !final && pending.buf.Len() < maxPartialBytes {
+		return "", false
+	}
+
+	delete(b.pending, key)
+	return pending.buf.String(), true
+}
+
+// evict discards the fragments of idle streams. It scans the buffer at
+// most once per idle timeout so that adding stays cheap.
+func (b *partialBuffer) evict(now time.Time) {
+	if now.Sub(b.swept) < partialIdleTimeout {
+		return
+	}
+	b.swept = now
+
+	for key, pending := range b.pending {
+		if now.Sub(pending.updated) >= partialIdleTimeout {
+			delete(b.pending, key)
+			b.evicted++
+		}
+	}
+}
+
+// size returns the number of streams waiting for fragments
+func (b *partialBuffer) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// evictions returns the number of partial messages discarded as idle
+func (b *partialBuffer) evictions() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.evicted
+}
+
+// partialKey identifies the stream a fragment belongs to
+func partialKey(entry *models.LogEntry, stream string) string {
+	return entry.Source + "|" + entry.Host + "|" + stream
+}
+
+// containerParser holds the behavior shared by container runtime parsers
+type containerParser struct {
+	inner    Parser
+	partials *partialBuffer
+}
+
+// newContainerParser creates the optional inner parser
+func newContainerParser(config *Config) (containerParser, error) {
+	cp := containerParser{partials: newPartialBuffer()}
+	if config != nil && config.Inner != nil {
+		inner, err := New(config.Inner)
+		if err != nil {
+			return cp, fmt.Errorf("failed to create inner parser: %w", err)
+		}
+		cp.inner = inner
+	}
+	return cp, nil
+}
+
+// emit replaces the entry payload with the unwrapped message and applies
+// the inner parser if one is configured. Containers often mix structured
+// and plain output, so inner parse failures tag the entry instead of
+// failing it.
+func (cp *containerParser) emit(entry *models.LogEntry, message, stream string, ts time.Time) error {
+	entry.Raw = message
+	entry.Message = message
+	entry.AddField("stream", stream)
+	if !ts.IsZero() {
+		entry.Timestamp = ts
+	}
+
+	if cp.inner != nil {
+		if err := cp.inner.Parse(entry); err != nil {
+			entry.AddTag(InnerParseFailureTag)
+		}
+	}
+	return nil
+}
+
+// Stats returns the number of partial messages discarded because their
+// stream went idle before the final fragment arrived
+func (cp *containerParser) Stats() map[string]uint64 {
+	return map[string]uint64{"partials_evicted": cp.partials.evictions()}
+}
+
+// DockerParser parses Docker json-file log lines. The unwrapped message
+// replaces entry.Raw so that an inner parser can process it.
+type DockerParser struct {
+	containerParser
+}
+
+// dockerLine is a single json-file record
+type dockerLine struct {
+	Log    string            `json:"log"`
+	Stream string            `json:"stream"`
+	Time   string            `json:"time"`
+	Attrs  map[string]string `json:"attrs,omitempty"`
+}
+
+// NewDockerParser creates a new Docker json-file parser
+func NewDockerParser(config *Config) (*DockerParser, error) {
+	cp, err := newContainerParser(config)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerParser{containerParser: cp}, nil
+}
+
+// Parse parses a Docker json-file log entry. Chunks of a long line are
+// buffered per source, host and stream until the last one arrives, so the
+// lines of a stream must be parsed in order.
+func (p *DockerParser) Parse(entry *models.LogEntry) error {
+	var line dockerLine
+	if err := json.Unmarshal([]byte(entry.Raw), &line); err != nil {
+		return fmt.Errorf("failed to parse docker log: %w", err)
+	}
+
+	// Docker splits long lines into 16KB chunks; only the last one ends
+	// with a newline
+	final := strings.HasSuffix(line.Log, "\n")
+	message, complete := p.partials.add(partialKey(entry, line.Stream), line.Log, final)
+	if !complete {
+		return ErrPartialEntry
+	}
+
+	var ts time.Time
+	if line.Time != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, line.Time)
+		if err != nil {
+			return fmt.Errorf("invalid docker timestamp: %w", err)
+		}
+		ts = parsed
+	}
+
+	for key, value := range line.Attrs {
+		entry.AddField("attrs."+key, value)
+	}
+
+	return p.emit(entry, strings.TrimRight(message, "\r\n"), line.Stream, ts)
+}
+
+// Name returns the parser name
+func (p *DockerParser) Name() string {
+	return "docker"
+}
+
+// CRIParser parses CRI (containerd, CRI-O) log lines of the form
+// "<timestamp> <stream> <P|F> <message>"
+type CRIParser struct {
+	containerParser
+}
+
+// NewCRIParser creates a new CRI log parser
+func NewCRIParser(config *Config) (*CRIParser, error) {
+	cp, err := newContainerParser(config)
+	if err != nil {
+		return nil, err
+	}
+	return &CRIParser{containerParser: cp}, nil
+}
+
+// Parse parses a CRI log entry. Partial (P) lines are buffered per source,
+// host and stream until the final (F) line arrives, so the lines of a
+// stream must be parsed in order.
+func (p *CRIParser) Parse(entry *models.LogEntry) error {
+	raw := strings.TrimRight(entry.Raw, "\r\n")
+
+	parts := strings.SplitN(raw, " ", 4)
+	if len(parts) < 3 {
+		return fmt.Errorf("cri %w", ErrNoMatch)
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid cri timestamp: %w", err)
+	}
+
+	stream := parts[1]
+	if stream != "stdout" && stream != "stderr" {
+		return fmt.Errorf("invalid cri stream: %s", stream)
+	}
+
+	// The tag may carry further flags after a colon
+	tag := parts[2]
+	if idx := strings.Index(tag, ":"); idx >= 0 {
+		tag = tag[:idx]
+	}
+	if tag != "P" && tag != "F" {
+		return fmt.Errorf("invalid cri tag: %s", parts[2])
+	}
+
+	fragment := ""
+	if len(parts) == 4 {
+		fragment = parts[3]
+	}
+
+	message, complete := p.partials.add(partialKey(entry, stream), fragment, tag == "F")
+	if !complete {
+		return ErrPartialEntry
+	}
+
+	return p.emit(entry, message, stream, ts)
+}
+
+// Name returns the parser name
+func (p *CRIParser) Name() string {
+	return "cri"
+}
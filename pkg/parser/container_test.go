@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestDockerParserWithInnerJSON(t *testing.T) {
+	parser, err := NewDockerParser(&Config{Inner: &Config{Type: "json"}})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Raw = `{"log":"{\"level\":\"error\",\"message\":\"db down\"}\n","stream":"stderr","time":"2024-01-01T12:00:00.123456789Z"}`
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if entry.Message != "db down" {
+		t.Errorf("Expected message 'db down', got '%s'", entry.Message)
+	}
+
+	if entry.Level != models.LogLevelError {
+		t.Errorf("Expected level ERROR, got %s", entry.Level)
+	}
+
+	if val, ok := entry.GetField("stream"); !ok || val != "stderr" {
+		t.Errorf("Expected stream stderr, got %v", val)
+	}
+
+	if entry.Timestamp.Nanosecond() != 123456789 {
+		t.Errorf("Expected nanosecond timestamp, got %v", entry.Timestamp)
+	}
+}
+
+func TestDockerParserPartialLines(t *testing.T) {
+	parser, _ := NewDockerParser(nil)
+
+	first := models.NewLogEntry()
+	first.Source = "container-1"
+	first.Raw = `{"log":"hello ","stream":"stdout","time":"2024-01-01T12:00:00Z"}`
+
+	if err := parser.Parse(first); !errors.Is(err, ErrPartialEntry) {
+		t.Fatalf("Expected ErrPartialEntry, got %v", err)
+	}
+
+	second := models.NewLogEntry()
+	second.Source = "container-1"
+	second.Raw = `{"log":"world\n","stream":"stdout","time":"2024-01-01T12:00:01Z"}`
+
+	if err := parser.Parse(second); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if second.Message != "hello world" {
+		t.Errorf("Expected reassembled message, got '%s'", second.Message)
+	}
+}
+
+func TestCRIParser(t *testing.T) {
+	parser, err := NewCRIParser(&Config{Inner: &Config{Type: "json"}})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	partial := models.NewLogEntry()
+	partial.Raw = `2024-01-01T12:00:00.000000001Z stdout P {"message":"split `
+	if err := parser.Parse(partial); !errors.Is(err, ErrPartialEntry) {
+		t.Fatalf("Expected ErrPartialEntry, got %v", err)
+	}
+
+	final := models.NewLogEntry()
+	final.Raw = `2024-01-01T12:00:00.000000002Z stdout F line"}`
+	if err := parser.Parse(final); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if final.Message != "split line" {
+		t.Errorf("Expected message 'split line', got '%s'", final.Message)
+	}
+
+	if final.Raw != `{"message":"split line"}` {
+		t.Errorf("Expected unwrapped raw, got '%s'", final.Raw)
+	}
+}
+
+func TestCRIParserPlainInnerFailure(t *testing.T) {
+	parser, _ := NewCRIParser(&Config{Inner: &Config{Type: "json"}})
+
+	entry := models.NewLogEntry()
+	entry.Raw = "2024-01-01T12:00:00Z stderr F plain text"
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if entry.Message != "plain text" || !entry.HasTag(InnerParseFailureTag) {
+		t.Errorf("Expected plain message with failure tag, got '%s' %v", entry.Message, entry.Tags)
+	}
+}
+
+func TestCRIParserInvalid(t *testing.T) {
+	parser, _ := NewCRIParser(nil)
+
+	entry := models.NewLogEntry()
+	entry.Raw = "not a cri line"
+
+	if err := parser.Parse(entry); err == nil {
+		t.Error("Expected error for invalid CRI line")
+	}
+}
+
+func TestCRIParserEvictsIdleStreams(t *testing.T) {
+	parser, _ := NewCRIParser(nil)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	parser.partials.now = func() time.Time { return now }
+
+	for _, source := range []string{"pod-a", "pod-b"} {
+		entry := models.NewLogEntry()
+		entry.Source = source
+		entry.Raw = "2024-01-01T12:00:00Z stdout P stale "
+		if err := parser.Parse(entry); !errors.Is(err, ErrPartialEntry) {
+			t.Fatalf("Expected ErrPartialEntry, got %v", err)
+		}
+	}
+
+	// The final line of pod-a arrives after its fragment expired
+	now = now.Add(partialIdleTimeout)
+	entry := models.NewLogEntry()
+	entry.Source = "pod-a"
+	entry.Raw = "2024-01-01T12:00:31Z stdout F fresh"
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if entry.Message != "fresh" {
+		t.Errorf("Expected stale fragment to be discarded, got '%s'", entry.Message)
+	}
+
+	// pod-b never sent its final line
+	if n := parser.partials.size(); n != 0 {
+		t.Errorf("Expected idle streams to be evicted, got %d pending", n)
+	}
+	if stats := parser.Stats(); stats["partials_evicted"] != 2 {
+		t.Errorf("Expected 2 evicted partials, got %v", stats)
+	}
+}
+
+func TestDockerParserCountsEvictedPartials(t *testing.T) {
+	parser, _ := NewDockerParser(nil)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	parser.partials.now = func() time.Time { return now }
+
+	parse := func(source, log string) error {
+		entry := models.NewLogEntry()
+		entry.Source = source
+		entry.Raw = `{"log":"` + log + `","stream":"stdout"}`
+		return parser.Parse(entry)
+	}
+
+	if err := parse("container-1", "lost "); !errors.Is(err, ErrPartialEntry) {
+		t.Fatalf("Expected ErrPartialEntry, got %v", err)
+	}
+
+	// Still within the idle timeout, nothing is evicted
+	now = now.Add(partialIdleTimeout - time.Second)
+	if err := parse("container-2", `kept\n`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if stats := parser.Stats(); stats["partials_evicted"] != 0 {
+		t.Errorf("Expected no evicted partials, got %v", stats)
+	}
+
+	// The sweep run by another stream discards the idle fragment
+	now = now.Add(time.Second)
+	if err := parse("container-2", `next\n`); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if stats := parser.Stats(); stats["partials_evicted"] != 1 {
+		t.Errorf("Expected 1 evicted partial, got %v", stats)
+	}
+}
+
+func TestCRIParserConcurrentStreams(t *testing.T) {
+	parser, _ := NewCRIParser(nil)
+
+	// Each stream is parsed in order, as a partitioned pipeline does, while
+	// streams run concurrently
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(source string) {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				for _, line := range []string{"P part1-", "P part2-", "F end"} {
+					entry := models.NewLogEntry()
+					entry.Source = source
+					entry.Raw = "2024-01-01T12:00:00Z stdout " + line
+					err := parser.Parse(entry)
+					if line[0] == 'P' {
+						if !errors.Is(err, ErrPartialEntry) {
+							errs <- fmt.Errorf("%s: expected ErrPartialEntry, got %v", source, err)
+							return
+						}
+						continue
+					}
+					if err != nil || entry.Message != "part1-part2-end" {
+						errs <- fmt.Errorf("%s: expected reassembled message, got '%s' (%v)", source, entry.Message, err)
+						return
+					}
+				}
+			}
+		}(fmt.Sprintf("pod-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Array handling modes for flattened JSON
+const (
+	// ArrayModeJSON stores arrays as JSON strings
+	ArrayModeJSON = "json"
+
+	// ArrayModeIndex flattens array items into indexed keys (items.0, items.1)
+	ArrayModeIndex = "index"
+)
+
+// lookupPath returns the value at a dotted path such as "http.request.method".
+// Numeric segments index into arrays. Keys that themselves contain dots are
+// matched literally before descending.
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if value, ok := v[path]; ok {
+			return value, true
+		}
+		head, rest, found := strings.Cut(path, ".")
+		if !found {
+			return nil, false
+		}
+		child, ok := v[head]
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(child, rest)
+
+	case []interface{}:
+		head, rest, _ := strings.Cut(path, ".")
+		idx, err := strconv.Atoi(head)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return nil, false
+		}
+		return lookupPath(v[idx], rest)
+
+	default:
+		return nil, false
+	}
+}
+
+// replacePath replaces the value at a dotted path if it exists
+func replacePath(data map[string]interface{}, path string, value interface{}) bool {
+	if _, ok := data[path]; ok {
+		data[path] = value
+		return true
+	}
+
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return false
+	}
+
+	switch child := data[head].(type) {
+	case map[string]interface{}:
+		return replacePath(child, rest, value)
+	case []interface{}:
+		idxStr, tail, nested := strings.Cut(rest, ".")
+		idx, err := strconv.Atoi(idxStr)
+		if err != nil || idx < 0 || idx >= len(child) {
+			return false
+		}
+		if !nested {
+			child[idx] = value
+			return true
+		}
+		if m, ok := child[idx].(map[string]interface{}); ok {
+			return replacePath(m, tail, value)
+		}
+	}
+
+	return false
+}
+
+// flattenOptions controls how nested JSON is flattened
+type flattenOptions struct {
+	separator string
+	maxDepth  int
+	arrayMode string
+}
+
+// flatten writes value into out using separator-joined keys. Objects nested
+// deeper than maxDepth (when positive) are kept as JSON strings.
+func flatten(out map[string]interface{}, prefix string, value interface{}, depth int, opts flattenOptions) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if prefix != "" && opts.maxDepth > 0 && depth >= opts.maxDepth {
+			out[prefix] = toJSONString(v)
+			return
+		}
+		if prefix != "" && len(v) == 0 {
+			out[prefix] = toJSONString(v)
+			return
+		}
+		for key, child := range v {
+			flatten(out, joinKey(prefix, key, opts.separator), child, depth+1, opts)
+		}
+
+	case []interface{}:
+		if opts.arrayMode != ArrayModeIndex || (opts.maxDepth > 0 && depth >= opts.maxDepth) || len(v) == 0 {
+			out[prefix] = toJSONString(v)
+			return
+		}
+		for i, child := range v {
+			flatten(out, joinKey(prefix, strconv.Itoa(i), opts.separator), child, depth+1, opts)
+		}
+
+	default:
+		out[prefix] = v
+	}
+}
+
+// scalarValue converts nested values to JSON strings so that they can be
+// stored without registering container types with encoders
+func scalarValue(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return toJSONString(value)
+	default:
+		return value
+	}
+}
+
+// joinKey joins a key prefix and a child key
+func joinKey(prefix, key, separator string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + separator + key
+}
+
+// toJSONString encodes a value as compact JSON
+func toJSONString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -10,6 +11,9 @@ import (
 	"github.com/UmangDiyora/logpipeline/pkg/models"
 )
 
+// ErrNoMatch is returned when a line does not match the parser's pattern
+var ErrNoMatch = errors.New("pattern did not match")
+
 // Parser is the interface for log parsers
 type Parser interface {
 	// Parse parses a log entry
@@ -24,7 +28,8 @@ type Config struct {
 	// Type is the parser type
 	Type string
 
-	// Fields to extract
+	// Fields maps destination field names to source paths. JSON paths are
+	// dotted (e.g. "http.request.method") with numeric array indexes
 	Fields map[string]string
 
 	// Patterns for regex/grok parsing
@@ -33,18 +38,81 @@ type Config struct {
 	// TimeFormat for timestamp parsing
 	TimeFormat string
 
+	// TimeFormats are additional layouts tried after TimeFormat
+	TimeFormats []string
+
+	// Timezone is applied to timestamps without zone information
+	Timezone string
+
 	// TimeField is the field containing the timestamp
 	TimeField string
+
+	// Flatten expands nested JSON objects into dotted field names
+	Flatten bool
+
+	// MaxDepth limits flattening depth; deeper objects are kept as JSON
+	// strings. Zero means unlimited
+	MaxDepth int
+
+	// Separator joins flattened keys (default ".")
+	Separator string
+
+	// ArrayMode controls flattened arrays: "json" (default) or "index"
+	ArrayMode string
+
+	// KeepJSON lists paths whose subtrees are stored as JSON strings
+	KeepJSON []string
+
+	// AppPackages lists function or path prefixes identifying application
+	// frames for the stacktrace parser, e.g. "com.example." or "myapp/"
+	AppPackages []string
+
+	// Inner configures a parser applied to the message unwrapped by an
+	// envelope parser such as docker or cri
+	Inner *Config
+}
+
+// timestampConfig builds the timestamp resolver configuration
+func (c *Config) timestampConfig() *TimestampConfig {
+	if c == nil {
+		return nil
+	}
+
+	formats := make([]string, 0, len(c.TimeFormats)+1)
+	if c.TimeFormat != "" {
+		formats = append(formats, c.TimeFormat)
+	}
+	formats = append(formats, c.TimeFormats...)
+
+	return &TimestampConfig{
+		Formats:  formats,
+		Timezone: c.Timezone,
+	}
+}
+
+// newTimestampResolver creates the resolver for a parser, falling back to
+// the defaults when the configuration is invalid. New validates the
+// configuration up front so that only direct constructor calls hit the fallback.
+func newTimestampResolver(config *Config) *TimestampResolver {
+	resolver, err := NewTimestampResolver(config.timestampConfig())
+	if err != nil {
+		resolver, _ = NewTimestampResolver(nil)
+	}
+	return resolver
 }
 
 // JSONParser parses JSON logs
 type JSONParser struct {
-	config *Config
+	config     *Config
+	timestamps *TimestampResolver
 }
 
 // NewJSONParser creates a new JSON parser
 func NewJSONParser(config *Config) *JSONParser {
-	return &JSONParser{config: config}
+	return &JSONParser{
+		config:     config,
+		timestamps: newTimestampResolver(config),
+	}
 }
 
 // Parse parses a JSON log entry
@@ -54,9 +122,48 @@ func (p *JSONParser) Parse(entry *models.LogEntry) error {
 		return fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
+	config := p.config
+	if config == nil {
+		config = &Config{}
+	}
+
+	// Collapse subtrees that should stay opaque
+	for _, path := range config.KeepJSON {
+		if value, ok := lookupPath(data, path); ok {
+			replacePath(data, path, scalarValue(value))
+		}
+	}
+
 	// Extract fields
-	for key, value := range data {
-		entry.AddField(key, value)
+	if config.Flatten {
+		separator := config.Separator
+		if separator == "" {
+			separator = "."
+		}
+		arrayMode := config.ArrayMode
+		if arrayMode == "" {
+			arrayMode = ArrayModeJSON
+		}
+		flat := make(map[string]interface{}, len(data))
+		flatten(flat, "", data, 0, flattenOptions{
+			separator: separator,
+			maxDepth:  config.MaxDepth,
+			arrayMode: arrayMode,
+		})
+		for key, value := range flat {
+			entry.AddField(key, value)
+		}
+	} else {
+		for key, value := range data {
+			entry.AddField(key, value)
+		}
+	}
+
+	// Map paths to named fields
+	for field, path := range config.Fields {
+		if value, ok := lookupPath(data, path); ok {
+			entry.AddField(field, scalarValue(value))
+		}
 	}
 
 	// Extract message
@@ -72,9 +179,9 @@ func (p *JSONParser) Parse(entry *models.LogEntry) error {
 	}
 
 	// Extract timestamp
-	if p.config != nil && p.config.TimeField != "" {
-		if ts, ok := data[p.config.TimeField]; ok {
-			if timestamp, err := p.parseTimestamp(ts); err == nil {
+	if config.TimeField != "" {
+		if ts, ok := lookupPath(data, config.TimeField); ok {
+			if timestamp, err := p.timestamps.Resolve(ts); err == nil {
 				entry.Timestamp = timestamp
 			}
 		}
@@ -107,38 +214,11 @@ func (p *JSONParser) parseLevel(level string) models.LogLevel {
 	}
 }
 
-// parseTimestamp parses a timestamp value
-func (p *JSONParser) parseTimestamp(value interface{}) (time.Time, error) {
-	switch v := value.(type) {
-	case string:
-		if p.config != nil && p.config.TimeFormat != "" {
-			return time.Parse(p.config.TimeFormat, v)
-		}
-		// Try common formats
-		formats := []string{
-			time.RFC3339,
-			time.RFC3339Nano,
-			"2006-01-02T15:04:05.000Z07:00",
-			"2006-01-02 15:04:05",
-		}
-		for _, format := range formats {
-			if t, err := time.Parse(format, v); err == nil {
-				return t, nil
-			}
-		}
-		return time.Time{}, fmt.Errorf("failed to parse timestamp")
-	case float64:
-		// Unix timestamp
-		return time.Unix(int64(v), 0), nil
-	default:
-		return time.Time{}, fmt.Errorf("unsupported timestamp type")
-	}
-}
-
 // RegexParser parses logs using regular expressions
 type RegexParser struct {
-	config  *Config
-	pattern *regexp.Regexp
+	config     *Config
+	pattern    *regexp.Regexp
+	timestamps *TimestampResolver
 }
 
 // NewRegexParser creates a new regex parser
@@ -158,8 +238,9 @@ func NewRegexParser(config *Config) (*RegexParser, error) {
 	}
 
 	return &RegexParser{
-		config:  config,
-		pattern: pattern,
+		config:     config,
+		pattern:    pattern,
+		timestamps: newTimestampResolver(config),
 	}, nil
 }
 
@@ -167,7 +248,7 @@ func NewRegexParser(config *Config) (*RegexParser, error) {
 func (p *RegexParser) Parse(entry *models.LogEntry) error {
 	matches := p.pattern.FindStringSubmatch(entry.Raw)
 	if matches == nil {
-		return fmt.Errorf("pattern did not match")
+		return ErrNoMatch
 	}
 
 	names := p.pattern.SubexpNames()
@@ -182,7 +263,7 @@ func (p *RegexParser) Parse(entry *models.LogEntry) error {
 			case "level":
 				entry.Level = p.parseLevel(matches[i])
 			case "timestamp":
-				if ts, err := p.parseTimestamp(matches[i]); err == nil {
+				if ts, err := p.timestamps.Resolve(matches[i]); err == nil {
 					entry.Timestamp = ts
 				}
 			}
@@ -216,29 +297,6 @@ func (p *RegexParser) parseLevel(level string) models.LogLevel {
 	}
 }
 
-// parseTimestamp parses a timestamp string
-func (p *RegexParser) parseTimestamp(value string) (time.Time, error) {
-	if p.config != nil && p.config.TimeFormat != "" {
-		return time.Parse(p.config.TimeFormat, value)
-	}
-
-	// Try common formats
-	formats := []string{
-		time.RFC3339,
-		"2006-01-02 15:04:05",
-		"2006/01/02 15:04:05",
-		"Jan 02 15:04:05",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, value); err == nil {
-			return t, nil
-		}
-	}
-
-	return time.Time{}, fmt.Errorf("failed to parse timestamp")
-}
-
 // NginxParser parses Nginx access logs
 type NginxParser struct{}
 
@@ -254,7 +312,7 @@ func (p *NginxParser) Parse(entry *models.LogEntry) error {
 
 	matches := pattern.FindStringSubmatch(entry.Raw)
 	if matches == nil || len(matches) < 11 {
-		return fmt.Errorf("nginx pattern did not match")
+		return fmt.Errorf("nginx %w", ErrNoMatch)
 	}
 
 	entry.AddField("remote_addr", matches[1])
@@ -298,6 +356,25 @@ func New(config *Config) (Parser, error) {
 		return nil, fmt.Errorf("config is required")
 	}
 
+	if _, err := NewTimestampResolver(config.timestampConfig()); err != nil {
+		return nil, fmt.Errorf("invalid timestamp config: %w", err)
+	}
+
+	switch config.ArrayMode {
+	case "", ArrayModeJSON, ArrayModeIndex:
+	default:
+		return nil, fmt.Errorf("unsupported array mode: %s", config.ArrayMode)
+	}
+
+	if len(config.AppPackages) > 0 && config.Type != "stacktrace" {
+		return nil, fmt.Errorf("app_packages is only supported by the stacktrace parser")
+	}
+	for _, prefix := range config.AppPackages {
+		if strings.TrimSpace(prefix) == "" {
+			return nil, fmt.Errorf("app_packages must not contain empty prefixes")
+		}
+	}
+
 	switch config.Type {
 	case "json":
 		return NewJSONParser(config), nil
@@ -305,6 +382,16 @@ func New(config *Config) (Parser, error) {
 		return NewRegexParser(config)
 	case "nginx":
 		return NewNginxParser(), nil
+	case "stacktrace":
+		return NewStackTraceParser(config), nil
+	case "docker":
+		return NewDockerParser(config)
+	case "cri":
+		return NewCRIParser(config)
+	case "cef":
+		return NewCEFParser(config), nil
+	case "leef":
+		return NewLEEFParser(config), nil
 	default:
 		return nil, fmt.Errorf("unsupported parser type: %s", config.Type)
 	}
@@ -40,6 +40,74 @@ func TestJSONParserInvalidJSON(t *testing.T) {
 	}
 }
 
+func TestJSONParserPathMapping(t *testing.T) {
+	parser := NewJSONParser(&Config{
+		Fields: map[string]string{
+			"method": "http.request.method",
+			"first":  "items.0.id",
+			"req":    "http.request",
+		},
+		TimeField: "meta.time",
+	})
+
+	entry := models.NewLogEntry()
+	entry.Raw = `{"message":"ok","http":{"request":{"method":"GET"}},"items":[{"id":"a"},{"id":"b"}],"meta":{"time":"2024-01-01T12:00:00Z"}}`
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if val, ok := entry.GetField("method"); !ok || val != "GET" {
+		t.Errorf("Expected method GET, got %v", val)
+	}
+
+	if val, ok := entry.GetField("first"); !ok || val != "a" {
+		t.Errorf("Expected first item id a, got %v", val)
+	}
+
+	if val, ok := entry.GetField("req"); !ok || val != `{"method":"GET"}` {
+		t.Errorf("Expected mapped subtree as JSON string, got %v", val)
+	}
+
+	if entry.Timestamp.Year() != 2024 {
+		t.Errorf("Expected timestamp from nested path, got %v", entry.Timestamp)
+	}
+}
+
+func TestJSONParserFlatten(t *testing.T) {
+	parser := NewJSONParser(&Config{
+		Flatten:   true,
+		MaxDepth:  2,
+		ArrayMode: ArrayModeIndex,
+		KeepJSON:  []string{"headers"},
+	})
+
+	entry := models.NewLogEntry()
+	entry.Raw = `{"http":{"request":{"method":"GET"},"status":200},"tags":["a","b"],"headers":{"x-id":"1"}}`
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"http.status":  float64(200),
+		"http.request": `{"method":"GET"}`,
+		"tags.0":       "a",
+		"tags.1":       "b",
+		"headers":      `{"x-id":"1"}`,
+	}
+
+	for key, want := range expected {
+		if got, ok := entry.GetField(key); !ok || got != want {
+			t.Errorf("Field %s: expected %v, got %v", key, want, got)
+		}
+	}
+
+	if _, ok := entry.GetField("http"); ok {
+		t.Error("Nested object should not be stored when flattening")
+	}
+}
+
 func TestRegexParser(t *testing.T) {
 	config := &Config{
 		Patterns: map[string]string{
@@ -0,0 +1,387 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+var (
+	// RFC3164 envelope: <PRI>Mmm dd hh:mm:ss host
+	syslog3164Pattern = regexp.MustCompile(`^<(\d{1,3})>([A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}:\d{2}) (\S+)`)
+
+	// RFC5424 envelope: <PRI>1 timestamp host
+	syslog5424Pattern = regexp.MustCompile(`^<(\d{1,3})>\d+ (\S+) (\S+)`)
+)
+
+// securityParser holds the behavior shared by CEF and LEEF parsers
+type securityParser struct {
+	timestamps *TimestampResolver
+}
+
+// unwrapSyslog locates the payload marker and records any syslog envelope
+// in front of it
+func (sp *securityParser) unwrapSyslog(entry *models.LogEntry, marker string) (string, error) {
+	idx := strings.Index(entry.Raw, marker)
+	if idx < 0 {
+		return "", fmt.Errorf("%s header not found", strings.TrimSuffix(marker, ":"))
+	}
+
+	envelope := strings.TrimSpace(entry.Raw[:idx])
+	if envelope != "" {
+		if m := syslog3164Pattern.FindStringSubmatch(envelope); m != nil {
+			sp.applyEnvelope(entry, m[1], m[2], m[3])
+		} else if m := syslog5424Pattern.FindStringSubmatch(envelope); m != nil {
+			sp.applyEnvelope(entry, m[1], m[2], m[3])
+		}
+	}
+
+	return strings.TrimRight(entry.Raw[idx:], "\r\n"), nil
+}
+
+// applyEnvelope stores syslog envelope details on the entry
+func (sp *securityParser) applyEnvelope(entry *models.LogEntry, priority, timestamp, host string) {
+	entry.AddField("syslog.host", host)
+	if pri, err := strconv.Atoi(priority); err == nil {
+		entry.AddField("syslog.priority", pri)
+	}
+	if ts, err := sp.timestamps.Resolve(timestamp); err == nil {
+		entry.Timestamp = ts
+	}
+}
+
+// severityToLevel maps a 0-10 severity to a log level
+func severityToLevel(severity string) models.LogLevel {
+	if n, err := strconv.Atoi(strings.TrimSpace(severity)); err == nil {
+		switch {
+		case n <= 3:
+			return models.LogLevelInfo
+		case n <= 6:
+			return models.LogLevelWarn
+		case n <= 8:
+			return models.LogLevelError
+		default:
+			return models.LogLevelFatal
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "low", "unknown", "":
+		return models.LogLevelInfo
+	case "medium":
+		return models.LogLevelWarn
+	case "high":
+		return models.LogLevelError
+	case "very-high", "very high", "critical":
+		return models.LogLevelFatal
+	default:
+		return models.LogLevelInfo
+	}
+}
+
+// splitHeader splits a pipe-delimited header honoring "\|" and "\\"
+// escapes. It returns at most n fields; the last holds the remainder.
+func splitHeader(s string, n int) []string {
+	fields := make([]string, 0, n)
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if len(fields) == n-1 {
+			// Remainder is returned untouched for the extension parser
+			fields = append(fields, s[i:])
+			return fields
+		}
+		switch {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			b.WriteByte(s[i+1])
+			i++
+		case c == '|':
+			fields = append(fields, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if b.Len() > 0 || len(fields) < n {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// CEFParser parses ArcSight Common Event Format messages
+type CEFParser struct {
+	securityParser
+}
+
+// NewCEFParser creates a new CEF parser
+func NewCEFParser(config *Config) *CEFParser {
+	return &CEFParser{securityParser{timestamps: newTimestampResolver(config)}}
+}
+
+// cefHeaderFields names the CEF header fields that follow the version
+var cefHeaderFields = []string{"device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+// Parse parses a CEF log entry, optionally wrapped in a syslog envelope
+func (p *CEFParser) Parse(entry *models.LogEntry) error {
+	payload, err := p.unwrapSyslog(entry, "CEF:")
+	if err != nil {
+		return err
+	}
+
+	parts := splitHeader(strings.TrimPrefix(payload, "CEF:"), 8)
+	if len(parts) < 7 {
+		return fmt.Errorf("invalid CEF header: expected 7 fields, got %d", len(parts))
+	}
+
+	entry.AddField("cef.version", parts[0])
+	for i, name := range cefHeaderFields {
+		entry.AddField("cef."+name, parts[i+1])
+	}
+
+	entry.Message = parts[5]
+	entry.Level = severityToLevel(parts[6])
+
+	if len(parts) == 8 {
+		for key, value := range parseCEFExtension(parts[7]) {
+			entry.AddField("cef."+key, value)
+		}
+		if rt, ok := entry.GetField("cef.rt"); ok {
+			if ts, err := p.timestamps.Resolve(rt); err == nil {
+				entry.Timestamp = ts
+			}
+		}
+	}
+
+	return nil
+}
+
+// Name returns the parser name
+func (p *CEFParser) Name() string {
+	return "cef"
+}
+
+// parseCEFExtension parses space separated key=value pairs. Values may
+// contain spaces and escaped "\=", "\\", "\n" and "\r"; a value ends where
+// the next unescaped key= begins.
+func parseCEFExtension(ext string) map[string]string {
+	type keyPos struct {
+		key        string
+		start, end int
+	}
+
+	keys := make([]keyPos, 0)
+	for i := 0; i < len(ext); i++ {
+		if ext[i] != '=' || (i > 0 && ext[i-1] == '\\') {
+			continue
+		}
+		start := i
+		for start > 0 && isExtensionKeyChar(ext[start-1]) {
+			start--
+		}
+		if start == i || (start > 0 && ext[start-1] != ' ') {
+			continue
+		}
+		keys = append(keys, keyPos{key: ext[start:i], start: start, end: i + 1})
+	}
+
+	result := make(map[string]string, len(keys))
+	for i, k := range keys {
+		valueEnd := len(ext)
+		if i+1 < len(keys) {
+			valueEnd = keys[i+1].start
+		}
+		result[k.key] = unescapeCEFValue(strings.TrimRight(ext[k.end:valueEnd], " "))
+	}
+	return result
+}
+
+// isExtensionKeyChar reports whether c may appear in an extension key
+func isExtensionKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '[' || c == ']' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// unescapeCEFValue resolves CEF extension escapes
+func unescapeCEFValue(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '=', '\\', '|':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// LEEFParser parses IBM QRadar Log Event Extended Format messages
+type LEEFParser struct {
+	securityParser
+}
+
+// NewLEEFParser creates a new LEEF parser
+func NewLEEFParser(config *Config) *LEEFParser {
+	return &LEEFParser{securityParser{timestamps: newTimestampResolver(config)}}
+}
+
+// Parse parses a LEEF 1.0 or 2.0 log entry, optionally wrapped in a
+// syslog envelope
+func (p *LEEFParser) Parse(entry *models.LogEntry) error {
+	payload, err := p.unwrapSyslog(entry, "LEEF:")
+	if err != nil {
+		return err
+	}
+
+	version := strings.TrimPrefix(payload, "LEEF:")
+	if idx := strings.Index(version, "|"); idx >= 0 {
+		version = version[:idx]
+	}
+
+	// LEEF 2.0 adds a delimiter field to the header
+	headerFields := 6
+	if strings.HasPrefix(version, "2") {
+		headerFields = 7
+	}
+
+	parts := splitHeader(strings.TrimPrefix(payload, "LEEF:"), headerFields)
+	if len(parts) < headerFields-1 {
+		return fmt.Errorf("invalid LEEF header: expected %d fields, got %d", headerFields-1, len(parts))
+	}
+
+	entry.AddField("leef.version", parts[0])
+	entry.AddField("leef.vendor", parts[1])
+	entry.AddField("leef.product", parts[2])
+	entry.AddField("leef.product_version", parts[3])
+	entry.AddField("leef.event_id", parts[4])
+	entry.Message = parts[4]
+
+	delimiter := "\t"
+	attrIdx := 5
+	if headerFields == 7 {
+		if len(parts) > 5 && parts[5] != "" {
+			d, err := parseLEEFDelimiter(parts[5])
+			if err != nil {
+				return err
+			}
+			delimiter = d
+		}
+		attrIdx = 6
+	}
+
+	attrs := make(map[string]string)
+	if len(parts) > attrIdx {
+		for _, pair := range strings.Split(parts[attrIdx], delimiter) {
+			key, value, found := strings.Cut(pair, "=")
+			if !found || key == "" {
+				continue
+			}
+			attrs[strings.TrimSpace(key)] = value
+		}
+	}
+
+	for key, value := range attrs {
+		entry.AddField("leef."+key, value)
+	}
+
+	if sev, ok := attrs["sev"]; ok {
+		entry.Level = severityToLevel(sev)
+	} else {
+		entry.Level = models.LogLevelInfo
+	}
+
+	if devTime, ok := attrs["devTime"]; ok {
+		if ts, err := p.resolveDevTime(devTime, attrs["devTimeFormat"]); err == nil {
+			entry.Timestamp = ts
+		}
+	}
+
+	return nil
+}
+
+// Name returns the parser name
+func (p *LEEFParser) Name() string {
+	return "leef"
+}
+
+// resolveDevTime parses devTime using devTimeFormat when present. LEEF
+// formats use Java SimpleDateFormat patterns.
+func (p *LEEFParser) resolveDevTime(value, format string) (time.Time, error) {
+	if format == "" {
+		return p.timestamps.Resolve(value)
+	}
+	resolver, err := NewTimestampResolver(&TimestampConfig{
+		Formats:  []string{javaDateFormatToLayout(format)},
+		Timezone: p.timestamps.location.String(),
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return resolver.Resolve(value)
+}
+
+// parseLEEFDelimiter decodes a LEEF 2.0 delimiter, given either as a
+// single character or as a hex value such as "x09" or "0x5E"
+func parseLEEFDelimiter(s string) (string, error) {
+	if len(s) == 1 {
+		return s, nil
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0"), "x")
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid LEEF delimiter %q", s)
+	}
+	return string(rune(code)), nil
+}
+
+// javaDatePatterns maps SimpleDateFormat tokens to Go layout elements,
+// longest tokens first
+var javaDatePatterns = []struct{ java, golang string }{
+	{"yyyy", "2006"}, {"yy", "06"},
+	{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"},
+	{"dd", "02"}, {"HH", "15"}, {"hh", "03"},
+	{"mm", "04"}, {"ss", "05"}, {"SSS", "000"},
+	{"EEEE", "Monday"}, {"EEE", "Mon"},
+	{"a", "PM"}, {"zzz", "MST"}, {"Z", "-0700"}, {"XXX", "Z07:00"},
+}
+
+// javaDateFormatToLayout converts a SimpleDateFormat pattern to a Go layout
+func javaDateFormatToLayout(format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		matched := false
+		for _, p := range javaDatePatterns {
+			if strings.HasPrefix(format[i:], p.java) {
+				b.WriteString(p.golang)
+				i += len(p.java)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+	return b.String()
+}
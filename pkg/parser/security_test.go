@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestCEFParser(t *testing.T) {
+	parser := NewCEFParser(nil)
+
+	entry := models.NewLogEntry()
+	entry.Raw = `CEF:0|Security|threat\|manager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat. No action needed act=blocked a \= b rt=1704110400000`
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertField(t, entry, "cef.device_vendor", "Security")
+	assertField(t, entry, "cef.device_product", "threat|manager")
+	assertField(t, entry, "cef.signature_id", "100")
+	assertField(t, entry, "cef.src", "10.0.0.1")
+	assertField(t, entry, "cef.msg", "Detected a threat. No action needed")
+	assertField(t, entry, "cef.act", "blocked a = b")
+
+	if entry.Message != "worm successfully stopped" {
+		t.Errorf("Expected message from name header, got '%s'", entry.Message)
+	}
+
+	if entry.Level != models.LogLevelFatal {
+		t.Errorf("Expected level FATAL for severity 10, got %s", entry.Level)
+	}
+
+	expected := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp from rt, got %v", entry.Timestamp)
+	}
+}
+
+func TestCEFParserSyslogEnvelope(t *testing.T) {
+	parser := NewCEFParser(nil)
+
+	entry := models.NewLogEntry()
+	entry.Raw = `<134>Jan 12 10:00:00 fw01 CEF:0|Palo Alto|PAN-OS|10.1|threat|Port scan|Medium|src=192.168.1.5`
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertField(t, entry, "syslog.host", "fw01")
+	assertField(t, entry, "cef.src", "192.168.1.5")
+
+	if entry.Level != models.LogLevelWarn {
+		t.Errorf("Expected level WARN for Medium severity, got %s", entry.Level)
+	}
+}
+
+func TestLEEFParser(t *testing.T) {
+	parser := NewLEEFParser(nil)
+
+	entry := models.NewLogEntry()
+	entry.Raw = "LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tdevTime=Jan 05 2024 10:00:00\tdevTimeFormat=MMM dd yyyy HH:mm:ss"
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertField(t, entry, "leef.vendor", "Microsoft")
+	assertField(t, entry, "leef.event_id", "15345")
+	assertField(t, entry, "leef.dst", "172.50.123.1")
+
+	if entry.Level != models.LogLevelWarn {
+		t.Errorf("Expected level WARN for sev 5, got %s", entry.Level)
+	}
+
+	expected := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	if !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected devTime timestamp, got %v", entry.Timestamp)
+	}
+}
+
+func TestLEEFParserV2CustomDelimiter(t *testing.T) {
+	parser := NewLEEFParser(nil)
+
+	entry := models.NewLogEntry()
+	entry.Raw = "<13>1 2024-01-01T12:00:00Z ids01 LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=9"
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertField(t, entry, "leef.src", "10.0.1.8")
+	assertField(t, entry, "syslog.host", "ids01")
+
+	if entry.Level != models.LogLevelFatal {
+		t.Errorf("Expected level FATAL for sev 9, got %s", entry.Level)
+	}
+}
+
+func TestSecurityParsersRejectOtherFormats(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.Raw = "plain log line"
+
+	if err := NewCEFParser(nil).Parse(entry); err == nil {
+		t.Error("Expected CEF parser to reject non-CEF entry")
+	}
+
+	if err := NewLEEFParser(nil).Parse(entry); err == nil {
+		t.Error("Expected LEEF parser to reject non-LEEF entry")
+	}
+}
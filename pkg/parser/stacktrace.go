@@ -0,0 +1,333 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+// Stack trace languages
+const (
+	LanguageJava   = "java"
+	LanguageGo     = "go"
+	LanguagePython = "python"
+	LanguageNode   = "node"
+)
+
+var (
+	javaHeaderPattern   = regexp.MustCompile(`^(?:Exception in thread "[^"]*" )?((?:[A-Za-z_$][\w$]*\.)+[A-Za-z_$][\w$]*(?:Exception|Error|Throwable)[\w$]*)(?:: ?(.*))?$`)
+	javaFramePattern    = regexp.MustCompile(`^\s+at ((?:[\w$<>]+\.)*[\w$<>]+)\(([^)]*)\)`)
+	javaCausedByPattern = regexp.MustCompile(`^Caused by: ([\w$.]+)(?:: ?(.*))?$`)
+
+	goPanicPattern     = regexp.MustCompile(`^(panic|fatal error): (.*)$`)
+	goGoroutinePattern = regexp.MustCompile(`^goroutine \d+ \[[^\]]*\]:$`)
+	goFuncPattern      = regexp.MustCompile(`^(?:created by )?(\S+)\([^()]*\)(?: in goroutine \d+)?$`)
+	goFilePattern      = regexp.MustCompile(`^\s+(\S+\.go:\d+)`)
+	goRuntimeError     = regexp.MustCompile(`^runtime error: (.*?)(?: \[recovered\])?$`)
+
+	pythonHeaderPattern    = regexp.MustCompile(`^Traceback \(most recent call last\):$`)
+	pythonFramePattern     = regexp.MustCompile(`^\s+File "([^"]+)", line (\d+), in (\S+)`)
+	pythonExceptionPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?:: (.*))?$`)
+
+	nodeHeaderPattern = regexp.MustCompile(`^(?:Uncaught )?([A-Z]\w*(?:Error|Exception))(?: \[[\w_]+\])?(?:: (.*))?$`)
+	nodeFramePattern  = regexp.MustCompile(`^\s+at (?:(?:async )?(\S+(?: \[as \w+\])?) \()?([^()]+):(\d+):(\d+)\)?$`)
+)
+
+// stackFrame is a single frame of a stack trace
+type stackFrame struct {
+	function string
+	location string
+}
+
+// String formats the frame as "function (location)"
+func (f stackFrame) String() string {
+	switch {
+	case f.function == "":
+		return f.location
+	case f.location == "":
+		return f.function
+	default:
+		return fmt.Sprintf("%s (%s)", f.function, f.location)
+	}
+}
+
+// stackTrace holds the parsed details of an exception
+type stackTrace struct {
+	language  string
+	excType   string
+	message   string
+	frames    []stackFrame
+	causeType string
+	causeMsg  string
+}
+
+// StackTraceParser parses multi-line exceptions and stack traces
+type StackTraceParser struct {
+	config      *Config
+	appPrefixes []string
+}
+
+// NewStackTraceParser creates a new stack trace parser. Config.AppPackages
+// lists the function or path prefixes identifying application frames;
+// without it, frames outside well-known runtime and library packages are
+// treated as application code.
+func NewStackTraceParser(config *Config) *StackTraceParser {
+	p := &StackTraceParser{config: config}
+	if config != nil {
+		for _, prefix := range config.AppPackages {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				p.appPrefixes = append(p.appPrefixes, prefix)
+			}
+		}
+	}
+	return p
+}
+
+// Parse parses a stack trace log entry. Entries without a stack trace
+// pass through unchanged, so that a pipeline can receive ordinary lines
+// next to exceptions.
+func (p *StackTraceParser) Parse(entry *models.LogEntry) error {
+	lines := strings.Split(strings.TrimRight(entry.Raw, "\r\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+
+	trace := p.detect(lines)
+	if trace == nil {
+		return nil
+	}
+
+	entry.AddField("exception.type", trace.excType)
+	entry.AddField("exception.message", trace.message)
+	entry.AddField("exception.language", trace.language)
+	entry.AddField("exception.frames", len(trace.frames))
+
+	if frame, ok := p.topAppFrame(trace); ok {
+		entry.AddField("exception.frame", frame.String())
+	}
+
+	if trace.causeType != "" {
+		entry.AddField("exception.cause.type", trace.causeType)
+		entry.AddField("exception.cause.message", trace.causeMsg)
+	}
+
+	if entry.Message == "" || entry.Message == entry.Raw {
+		entry.Message = lines[0]
+	}
+
+	if trace.language == LanguageGo {
+		entry.Level = models.LogLevelFatal
+	} else if entry.Level == "" || entry.Level == models.LogLevelInfo || entry.Level == models.LogLevelDebug {
+		entry.Level = models.LogLevelError
+	}
+
+	return nil
+}
+
+// Name returns the parser name
+func (p *StackTraceParser) Name() string {
+	return "stacktrace"
+}
+
+// detect finds the first recognizable stack trace in the lines
+func (p *StackTraceParser) detect(lines []string) *stackTrace {
+	for i, line := range lines {
+		switch {
+		case pythonHeaderPattern.MatchString(line):
+			return parsePythonTrace(lines[i+1:])
+
+		case goPanicPattern.MatchString(line), goGoroutinePattern.MatchString(line):
+			return parseGoTrace(lines[i:])
+
+		case javaHeaderPattern.MatchString(line) && hasFrame(lines[i+1:], javaFramePattern):
+			return parseJavaTrace(lines[i:])
+
+		case nodeHeaderPattern.MatchString(line) && hasFrame(lines[i+1:], nodeFramePattern):
+			return parseNodeTrace(lines[i:])
+		}
+	}
+	return nil
+}
+
+// hasFrame reports whether the next line is a frame of the given format
+func hasFrame(lines []string, pattern *regexp.Regexp) bool {
+	return len(lines) > 0 && pattern.MatchString(lines[0])
+}
+
+// parseJavaTrace parses a Java exception with optional cause chain
+func parseJavaTrace(lines []string) *stackTrace {
+	m := javaHeaderPattern.FindStringSubmatch(lines[0])
+	trace := &stackTrace{language: LanguageJava, excType: m[1], message: m[2]}
+
+	inCause := false
+	for _, line := range lines[1:] {
+		if fm := javaFramePattern.FindStringSubmatch(line); fm != nil {
+			// Frames of the outermost exception describe where it surfaced
+			if !inCause {
+				trace.frames = append(trace.frames, stackFrame{function: fm[1], location: fm[2]})
+			}
+			continue
+		}
+		if cm := javaCausedByPattern.FindStringSubmatch(line); cm != nil {
+			// Keep the innermost cause
+			trace.causeType, trace.causeMsg = cm[1], cm[2]
+			inCause = true
+		}
+	}
+
+	return trace
+}
+
+// parseGoTrace parses a Go panic or goroutine dump
+func parseGoTrace(lines []string) *stackTrace {
+	trace := &stackTrace{language: LanguageGo, excType: "goroutine_dump"}
+
+	if m := goPanicPattern.FindStringSubmatch(lines[0]); m != nil {
+		trace.excType = m[1]
+		trace.message = m[2]
+		if rm := goRuntimeError.FindStringSubmatch(m[2]); rm != nil {
+			trace.excType = "runtime.Error"
+			trace.message = rm[1]
+		}
+	}
+
+	seenGoroutine := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if goGoroutinePattern.MatchString(line) {
+			// Only the first goroutine is the one that panicked
+			if seenGoroutine {
+				break
+			}
+			seenGoroutine = true
+			continue
+		}
+		if !seenGoroutine {
+			continue
+		}
+		fm := goFuncPattern.FindStringSubmatch(line)
+		if fm == nil {
+			continue
+		}
+		frame := stackFrame{function: fm[1]}
+		if i+1 < len(lines) {
+			if lm := goFilePattern.FindStringSubmatch(lines[i+1]); lm != nil {
+				frame.location = lm[1]
+				i++
+			}
+		}
+		trace.frames = append(trace.frames, frame)
+	}
+
+	return trace
+}
+
+// parsePythonTrace parses the body of a Python traceback
+func parsePythonTrace(lines []string) *stackTrace {
+	trace := &stackTrace{language: LanguagePython}
+
+	// Python lists the most recent call last; store frames innermost first
+	// to match the other languages
+	frames := make([]stackFrame, 0)
+	for _, line := range lines {
+		if fm := pythonFramePattern.FindStringSubmatch(line); fm != nil {
+			frames = append(frames, stackFrame{function: fm[3], location: fm[1] + ":" + fm[2]})
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || line == "" {
+			continue
+		}
+		if em := pythonExceptionPattern.FindStringSubmatch(line); em != nil {
+			trace.excType = em[1]
+			trace.message = em[2]
+			break
+		}
+	}
+
+	for i := len(frames) - 1; i >= 0; i-- {
+		trace.frames = append(trace.frames, frames[i])
+	}
+
+	if trace.excType == "" {
+		trace.excType = "Traceback"
+	}
+	return trace
+}
+
+// parseNodeTrace parses a Node.js error with stack
+func parseNodeTrace(lines []string) *stackTrace {
+	m := nodeHeaderPattern.FindStringSubmatch(lines[0])
+	trace := &stackTrace{language: LanguageNode, excType: m[1], message: m[2]}
+
+	for _, line := range lines[1:] {
+		fm := nodeFramePattern.FindStringSubmatch(line)
+		if fm == nil {
+			continue
+		}
+		trace.frames = append(trace.frames, stackFrame{
+			function: fm[1],
+			location: fmt.Sprintf("%s:%s:%s", fm[2], fm[3], fm[4]),
+		})
+	}
+
+	return trace
+}
+
+// topAppFrame returns the innermost frame that belongs to application code
+func (p *StackTraceParser) topAppFrame(trace *stackTrace) (stackFrame, bool) {
+	for _, frame := range trace.frames {
+		if p.isAppFrame(trace.language, frame) {
+			return frame, true
+		}
+	}
+	if len(trace.frames) > 0 {
+		return trace.frames[0], true
+	}
+	return stackFrame{}, false
+}
+
+// isAppFrame reports whether a frame belongs to application code
+func (p *StackTraceParser) isAppFrame(language string, frame stackFrame) bool {
+	if len(p.appPrefixes) > 0 {
+		for _, prefix := range p.appPrefixes {
+			if strings.HasPrefix(frame.function, prefix) || strings.HasPrefix(frame.location, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch language {
+	case LanguageJava:
+		for _, prefix := range []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "scala."} {
+			if strings.HasPrefix(frame.function, prefix) {
+				return false
+			}
+		}
+		return true
+
+	case LanguageGo:
+		// Standard library packages have no dot in their first path element
+		pkg := frame.function
+		if idx := strings.Index(pkg, "/"); idx >= 0 {
+			pkg = pkg[:idx]
+		} else if idx := strings.Index(pkg, "."); idx >= 0 {
+			pkg = pkg[:idx]
+		}
+		return pkg == "main" || strings.Contains(pkg, ".")
+
+	case LanguagePython:
+		return !strings.Contains(frame.location, "site-packages") &&
+			!strings.Contains(frame.location, "dist-packages") &&
+			!strings.Contains(frame.location, "/lib/python")
+
+	case LanguageNode:
+		return !strings.Contains(frame.location, "node_modules") &&
+			!strings.HasPrefix(frame.location, "node:") &&
+			!strings.HasPrefix(frame.location, "internal/")
+	}
+
+	return true
+}
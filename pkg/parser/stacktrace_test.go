@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func parseStackTrace(t *testing.T, raw string) *models.LogEntry {
+	t.Helper()
+
+	entry := models.NewLogEntry()
+	entry.Raw = raw
+
+	if err := NewStackTraceParser(nil).Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return entry
+}
+
+func assertField(t *testing.T, entry *models.LogEntry, key string, expected interface{}) {
+	t.Helper()
+
+	if val, ok := entry.GetField(key); !ok || val != expected {
+		t.Errorf("Field %s: expected %v, got %v", key, expected, val)
+	}
+}
+
+func TestStackTraceParserJava(t *testing.T) {
+	entry := parseStackTrace(t, `2024-01-01 12:00:00 ERROR request failed
+java.lang.IllegalStateException: order not found
+	at java.util.Optional.orElseThrow(Optional.java:408)
+	at com.example.orders.OrderService.load(OrderService.java:42)
+	at com.example.orders.OrderController.get(OrderController.java:17)
+Caused by: java.sql.SQLException: connection reset
+	at org.postgresql.Driver.connect(Driver.java:100)
+	... 2 more`)
+
+	assertField(t, entry, "exception.type", "java.lang.IllegalStateException")
+	assertField(t, entry, "exception.message", "order not found")
+	assertField(t, entry, "exception.frame", "com.example.orders.OrderService.load (OrderService.java:42)")
+	assertField(t, entry, "exception.cause.type", "java.sql.SQLException")
+	assertField(t, entry, "exception.language", LanguageJava)
+
+	if entry.Level != models.LogLevelError {
+		t.Errorf("Expected level ERROR, got %s", entry.Level)
+	}
+}
+
+func TestStackTraceParserGoPanic(t *testing.T) {
+	entry := parseStackTrace(t, `panic: runtime error: invalid memory address or nil pointer dereference
+
+goroutine 1 [running]:
+runtime.panicmem()
+	/usr/local/go/src/runtime/panic.go:261 +0x48
+github.com/acme/app/store.(*Store).Get(0x0, {0x1, 0x2})
+	/app/store/store.go:88 +0x1d
+main.main()
+	/app/main.go:12 +0x25
+exit status 2`)
+
+	assertField(t, entry, "exception.type", "runtime.Error")
+	assertField(t, entry, "exception.message", "invalid memory address or nil pointer dereference")
+	assertField(t, entry, "exception.frame", "github.com/acme/app/store.(*Store).Get (/app/store/store.go:88)")
+
+	if entry.Level != models.LogLevelFatal {
+		t.Errorf("Expected level FATAL, got %s", entry.Level)
+	}
+}
+
+func TestStackTraceParserPython(t *testing.T) {
+	entry := parseStackTrace(t, `Traceback (most recent call last):
+  File "/app/worker.py", line 10, in run
+    handle(job)
+  File "/usr/lib/python3.11/json/__init__.py", line 346, in loads
+    return _default_decoder.decode(s)
+ValueError: Expecting value`)
+
+	assertField(t, entry, "exception.type", "ValueError")
+	assertField(t, entry, "exception.message", "Expecting value")
+	assertField(t, entry, "exception.frame", "run (/app/worker.py:10)")
+}
+
+func TestStackTraceParserNode(t *testing.T) {
+	entry := parseStackTrace(t, `TypeError: Cannot read properties of undefined (reading 'id')
+    at Object.<anonymous> (/app/node_modules/lib/index.js:5:10)
+    at handler (/app/src/routes/users.js:21:15)
+    at node:internal/process/task_queues:95:5`)
+
+	assertField(t, entry, "exception.type", "TypeError")
+	assertField(t, entry, "exception.message", "Cannot read properties of undefined (reading 'id')")
+	assertField(t, entry, "exception.frame", "handler (/app/src/routes/users.js:21:15)")
+}
+
+func TestStackTraceParserNoTrace(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.Raw = "plain log line"
+	entry.Message = "plain log line"
+	entry.Level = models.LogLevelInfo
+
+	if err := NewStackTraceParser(nil).Parse(entry); err != nil {
+		t.Fatalf("Expected entry without stack trace to pass, got %v", err)
+	}
+	if _, ok := entry.GetField("exception.type"); ok {
+		t.Error("Expected no exception fields")
+	}
+	if entry.Level != models.LogLevelInfo || entry.Message != "plain log line" {
+		t.Errorf("Expected entry unchanged, got %s %q", entry.Level, entry.Message)
+	}
+}
+
+func TestStackTraceParserAppPackages(t *testing.T) {
+	parser, err := New(&Config{
+		Type:        "stacktrace",
+		AppPackages: []string{" com.example.orders.OrderController"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Raw = `java.lang.IllegalStateException: order not found
+	at java.util.Optional.orElseThrow(Optional.java:408)
+	at com.example.orders.OrderService.load(OrderService.java:42)
+	at com.example.orders.OrderController.get(OrderController.java:17)`
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertField(t, entry, "exception.frame", "com.example.orders.OrderController.get (OrderController.java:17)")
+}
+
+func TestStackTraceParserInvalidAppPackages(t *testing.T) {
+	tests := map[string]*Config{
+		"empty prefix": {Type: "stacktrace", AppPackages: []string{"com.example.", " "}},
+		"other parser": {Type: "json", AppPackages: []string{"com.example."}},
+		"inner parser": {Type: "docker", Inner: &Config{Type: "json", AppPackages: []string{"com.example."}}},
+	}
+
+	for name, config := range tests {
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := New(&Config{Type: "docker", Inner: &Config{Type: "stacktrace", AppPackages: []string{"com.example."}}}); err != nil {
+		t.Errorf("Expected app_packages on an inner stacktrace parser to be valid, got %v", err)
+	}
+}
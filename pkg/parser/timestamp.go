@@ -0,0 +1,353 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTimestamp is returned when a value matches none of the
+// timestamp formats
+var ErrInvalidTimestamp = errors.New("failed to parse timestamp")
+
+// Special timestamp format names
+const (
+	// FormatUnix parses epoch seconds
+	FormatUnix = "UNIX"
+
+	// FormatUnixMs parses epoch milliseconds
+	FormatUnixMs = "UNIX_MS"
+
+	// FormatUnixUs parses epoch microseconds
+	FormatUnixUs = "UNIX_US"
+
+	// FormatUnixNs parses epoch nanoseconds
+	FormatUnixNs = "UNIX_NS"
+
+	// FormatEpoch parses epoch values, detecting the unit from their magnitude
+	FormatEpoch = "EPOCH"
+)
+
+// DefaultTimeFormats are tried when no formats are configured. Bare
+// numbers are only read as epoch values through the final EPOCH entry.
+var DefaultTimeFormats = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05.000000",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.000000",
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RubyDate,
+	time.UnixDate,
+	time.ANSIC,
+	time.StampMicro,
+	time.StampMilli,
+	time.Stamp,
+	"Jan 02 15:04:05",
+	FormatEpoch,
+}
+
+// TimestampConfig holds timestamp resolution configuration
+type TimestampConfig struct {
+	// Formats are the layouts to try in order. Each may be a Go layout, a
+	// strftime pattern (e.g. "%Y-%m-%d %H:%M:%S") or one of the special
+	// names UNIX, UNIX_MS, UNIX_US, UNIX_NS and EPOCH. Numeric strings are
+	// only read as epoch values when one of those names is listed; the
+	// defaults end with EPOCH
+	Formats []string
+
+	// Timezone is the IANA zone applied to layouts without zone information
+	Timezone string
+}
+
+// TimestampResolver converts raw timestamp values to time.Time
+type TimestampResolver struct {
+	formats  []string
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewTimestampResolver creates a new timestamp resolver
+func NewTimestampResolver(config *TimestampConfig) (*TimestampResolver, error) {
+	r := &TimestampResolver{
+		formats:  DefaultTimeFormats,
+		location: time.UTC,
+		now:      time.Now,
+	}
+
+	if config == nil {
+		return r, nil
+	}
+
+	if len(config.Formats) > 0 {
+		r.formats = make([]string, 0, len(config.Formats))
+		for _, format := range config.Formats {
+			if strings.Contains(format, "%") {
+				converted, err := strftimeToLayout(format)
+				if err != nil {
+					return nil, err
+				}
+				format = converted
+			}
+			r.formats = append(r.formats, format)
+		}
+	}
+
+	if config.Timezone != "" {
+		loc, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", config.Timezone, err)
+		}
+		r.location = loc
+	}
+
+	return r, nil
+}
+
+// Resolve converts a string or numeric value to a timestamp
+func (r *TimestampResolver) Resolve(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return r.resolveString(strings.TrimSpace(v))
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return r.resolveInteger(n, "")
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w %q", ErrInvalidTimestamp, v.String())
+		}
+		return r.resolveNumber(f, "")
+	case float64:
+		return r.resolveNumber(v, "")
+	case float32:
+		return r.resolveNumber(float64(v), "")
+	case int:
+		return r.resolveInteger(int64(v), "")
+	case int64:
+		return r.resolveInteger(v, "")
+	case uint64:
+		if v > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("epoch value out of range: %d", v)
+		}
+		return r.resolveInteger(int64(v), "")
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp type %T", value)
+	}
+}
+
+// resolveString tries each configured format against a string value
+func (r *TimestampResolver) resolveString(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+
+	for _, format := range r.formats {
+		if isEpochFormat(format) {
+			if !isNumeric(value) {
+				continue
+			}
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return r.resolveInteger(n, format)
+			}
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return r.resolveNumber(f, format)
+			}
+			continue
+		}
+
+		t, err := time.ParseInLocation(format, value, r.location)
+		if err != nil {
+			continue
+		}
+		return r.inferYear(t), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w %q", ErrInvalidTimestamp, value)
+}
+
+// resolveInteger converts an integral epoch value using the given unit
+// format without going through float64, which cannot hold nanosecond
+// epochs exactly
+func (r *TimestampResolver) resolveInteger(value int64, format string) (time.Time, error) {
+	if format == "" || format == FormatEpoch {
+		format = detectEpochUnit(float64(value))
+	}
+
+	switch format {
+	case FormatUnix:
+		return time.Unix(value, 0).In(r.location), nil
+	case FormatUnixMs:
+		return time.UnixMilli(value).In(r.location), nil
+	case FormatUnixUs:
+		return time.UnixMicro(value).In(r.location), nil
+	case FormatUnixNs:
+		return time.Unix(0, value).In(r.location), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported epoch format: %s", format)
+	}
+}
+
+// resolveNumber converts an epoch value using the given unit format.
+// Integral values are handed to resolveInteger.
+func (r *TimestampResolver) resolveNumber(value float64, format string) (time.Time, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return time.Time{}, fmt.Errorf("invalid epoch value")
+	}
+	if value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64 {
+		return r.resolveInteger(int64(value), format)
+	}
+
+	if format == "" || format == FormatEpoch {
+		format = detectEpochUnit(value)
+	}
+
+	var nanosPerUnit float64
+	switch format {
+	case FormatUnix:
+		nanosPerUnit = 1e9
+	case FormatUnixMs:
+		nanosPerUnit = 1e6
+	case FormatUnixUs:
+		nanosPerUnit = 1e3
+	case FormatUnixNs:
+		nanosPerUnit = 1
+	default:
+		return time.Time{}, fmt.Errorf("unsupported epoch format: %s", format)
+	}
+
+	// Split into whole seconds first to keep float rounding out of the
+	// sub-second part
+	unitsPerSecond := 1e9 / nanosPerUnit
+	sec := math.Floor(value / unitsPerSecond)
+	nsec := math.Round((value - sec*unitsPerSecond) * nanosPerUnit)
+	return time.Unix(int64(sec), int64(nsec)).In(r.location), nil
+}
+
+// inferYear fills in the year for layouts that do not carry one. Timestamps
+// that would land more than a day in the future are assumed to be from the
+// previous year, which handles logs written just before New Year.
+func (r *TimestampResolver) inferYear(t time.Time) time.Time {
+	if t.Year() != 0 {
+		return t
+	}
+
+	now := r.now().In(t.Location())
+	inferred := time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if inferred.After(now.Add(24 * time.Hour)) {
+		inferred = inferred.AddDate(-1, 0, 0)
+	}
+	return inferred
+}
+
+// detectEpochUnit guesses the unit of an epoch value from its magnitude
+func detectEpochUnit(value float64) string {
+	abs := math.Abs(value)
+	switch {
+	case abs < 1e11:
+		return FormatUnix
+	case abs < 1e14:
+		return FormatUnixMs
+	case abs < 1e17:
+		return FormatUnixUs
+	default:
+		return FormatUnixNs
+	}
+}
+
+// isEpochFormat reports whether the format is one of the epoch names
+func isEpochFormat(format string) bool {
+	switch format {
+	case FormatUnix, FormatUnixMs, FormatUnixUs, FormatUnixNs, FormatEpoch:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNumeric reports whether s is a plain decimal number
+func isNumeric(s string) bool {
+	dot := false
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !dot:
+			dot = true
+		case c == '-' && i == 0:
+		default:
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// strftimeDirectives maps strftime directives to Go layout elements
+var strftimeDirectives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'j': "002",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'f': "000000",
+	'L': "000",
+	'N': "000000000",
+	'p': "PM",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'z': "-0700",
+	'Z': "MST",
+	'T': "15:04:05",
+	'F': "2006-01-02",
+	'D': "01/02/06",
+	'R': "15:04",
+	'%': "%",
+}
+
+// strftimeToLayout converts a strftime pattern to a Go time layout
+func strftimeToLayout(format string) (string, error) {
+	if format == "%s" {
+		return FormatUnix, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+
+		if i+1 >= len(format) {
+			return "", fmt.Errorf("invalid strftime format %q: trailing %%", format)
+		}
+		i++
+
+		layout, ok := strftimeDirectives[format[i]]
+		if !ok {
+			return "", fmt.Errorf("unsupported strftime directive %%%c in %q", format[i], format)
+		}
+		b.WriteString(layout)
+	}
+
+	return b.String(), nil
+}
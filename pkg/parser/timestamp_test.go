@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/UmangDiyora/logpipeline/pkg/models"
+)
+
+func TestTimestampResolverDefaultFormats(t *testing.T) {
+	resolver, err := NewTimestampResolver(nil)
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	expected := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	inputs := []string{
+		"2024-01-01T12:00:00Z",
+		"2024-01-01 12:00:00",
+		"2024/01/01 12:00:00",
+		"01/Jan/2024:12:00:00 +0000",
+	}
+
+	for _, input := range inputs {
+		ts, err := resolver.Resolve(input)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", input, err)
+			continue
+		}
+		if !ts.Equal(expected) {
+			t.Errorf("Resolve(%q) = %v, expected %v", input, ts, expected)
+		}
+	}
+}
+
+func TestTimestampResolverEpochDetection(t *testing.T) {
+	resolver, _ := NewTimestampResolver(nil)
+	expected := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	values := []interface{}{
+		float64(1704110400),
+		float64(1704110400000),
+		int64(1704110400000000),
+		int64(1704110400000000000),
+		"1704110400",
+		"1704110400000",
+	}
+
+	for _, value := range values {
+		ts, err := resolver.Resolve(value)
+		if err != nil {
+			t.Errorf("Resolve(%v) failed: %v", value, err)
+			continue
+		}
+		if !ts.Equal(expected) {
+			t.Errorf("Resolve(%v) = %v, expected %v", value, ts, expected)
+		}
+	}
+}
+
+func TestTimestampResolverStrftimeAndTimezone(t *testing.T) {
+	resolver, err := NewTimestampResolver(&TimestampConfig{
+		Formats:  []string{"%d/%m/%Y %H:%M:%S"},
+		Timezone: "America/New_York",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	ts, err := resolver.Resolve("15/07/2024 08:30:00")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	expected := time.Date(2024, 7, 15, 12, 30, 0, 0, time.UTC)
+	if !ts.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, ts.UTC())
+	}
+}
+
+func TestTimestampResolverYearInference(t *testing.T) {
+	resolver, _ := NewTimestampResolver(&TimestampConfig{Formats: []string{time.Stamp}})
+	resolver.now = func() time.Time {
+		return time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	}
+
+	ts, err := resolver.Resolve("Jan  1 10:00:00")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if ts.Year() != 2024 {
+		t.Errorf("Expected year 2024, got %d", ts.Year())
+	}
+
+	// A December timestamp seen in early January belongs to last year
+	ts, err = resolver.Resolve("Dec 31 23:00:00")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if ts.Year() != 2023 {
+		t.Errorf("Expected year 2023, got %d", ts.Year())
+	}
+}
+
+func TestTimestampResolverInvalidConfig(t *testing.T) {
+	if _, err := NewTimestampResolver(&TimestampConfig{Timezone: "Not/AZone"}); err == nil {
+		t.Error("Expected error for invalid timezone")
+	}
+
+	if _, err := NewTimestampResolver(&TimestampConfig{Formats: []string{"%Q"}}); err == nil {
+		t.Error("Expected error for unsupported strftime directive")
+	}
+
+	if _, err := New(&Config{Type: "json", Timezone: "Not/AZone"}); err == nil {
+		t.Error("Expected New to reject invalid timezone")
+	}
+}
+
+func TestJSONParserEpochTimestamp(t *testing.T) {
+	parser := NewJSONParser(&Config{TimeField: "ts"})
+
+	entry := models.NewLogEntry()
+	entry.Raw = `{"message":"hello","ts":1704110400123}`
+
+	if err := parser.Parse(entry); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := time.Date(2024, 1, 1, 12, 0, 0, 123000000, time.UTC)
+	if !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, entry.Timestamp)
+	}
+}
+
+func TestTimestampResolverEpochPrecision(t *testing.T) {
+	resolver, _ := NewTimestampResolver(nil)
+	expected := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+
+	values := []interface{}{
+		int64(1704110400123456789),
+		uint64(1704110400123456789),
+		json.Number("1704110400123456789"),
+		"1704110400123456789",
+	}
+	for _, value := range values {
+		ts, err := resolver.Resolve(value)
+		if err != nil {
+			t.Errorf("Resolve(%v) failed: %v", value, err)
+			continue
+		}
+		if !ts.Equal(expected) {
+			t.Errorf("Resolve(%v) = %v, expected %v", value, ts, expected)
+		}
+	}
+
+	micros, _ := NewTimestampResolver(&TimestampConfig{Formats: []string{FormatUnixUs}})
+	ts, err := micros.Resolve("1704110400123457")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !ts.Equal(time.Date(2024, 1, 1, 12, 0, 0, 123457000, time.UTC)) {
+		t.Errorf("Unexpected microsecond timestamp %v", ts)
+	}
+
+	ts, err = resolver.Resolve(1704110400.5)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !ts.Equal(time.Date(2024, 1, 1, 12, 0, 0, 500000000, time.UTC)) {
+		t.Errorf("Unexpected fractional timestamp %v", ts)
+	}
+}
+
+func TestTimestampResolverNumericStringNeedsEpochFormat(t *testing.T) {
+	resolver, _ := NewTimestampResolver(&TimestampConfig{Formats: []string{"2006-01-02"}})
+	if _, err := resolver.Resolve("2024"); err == nil {
+		t.Error("Expected bare number to be rejected without an epoch format")
+	}
+
+	// Numeric values are epochs whatever the formats
+	ts, err := resolver.Resolve(int64(1704110400))
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !ts.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamp %v", ts)
+	}
+
+	resolver, _ = NewTimestampResolver(&TimestampConfig{Formats: []string{"2006-01-02", FormatUnix}})
+	ts, err = resolver.Resolve("2024")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if ts.Unix() != 2024 {
+		t.Errorf("Expected epoch seconds with UNIX configured, got %v", ts)
+	}
+}
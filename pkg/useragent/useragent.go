@@ -0,0 +1,311 @@
+// Package useragent parses HTTP User-Agent strings into browser, operating
+// system and device details using regex sets in the uap-core format.
+package useragent
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Other is the family reported when nothing matches
+const Other = "Other"
+
+//go:embed regexes.yaml
+var defaultRegexes []byte
+
+// Client holds the details parsed from a user agent string
+type Client struct {
+	// Family is the browser or client family, e.g. "Chrome" or "curl"
+	Family string
+	Major  string
+	Minor  string
+	Patch  string
+
+	// OS is the operating system family, e.g. "Windows" or "iOS"
+	OS      string
+	OSMajor string
+	OSMinor string
+	OSPatch string
+
+	// Device is the device family, e.g. "iPhone" or "Spider"
+	Device string
+
+	// DeviceType is one of desktop, mobile, tablet, bot or other
+	DeviceType string
+
+	// Bot is set for crawlers, monitoring probes and other automated
+	// clients such as curl or HTTP libraries
+	Bot bool
+}
+
+// Version returns the browser version, e.g. "120.0.1"
+func (c *Client) Version() string {
+	return joinVersion(c.Major, c.Minor, c.Patch)
+}
+
+// OSVersion returns the operating system version, e.g. "17.1"
+func (c *Client) OSVersion() string {
+	return joinVersion(c.OSMajor, c.OSMinor, c.OSPatch)
+}
+
+// joinVersion joins the leading non-empty version parts with dots
+func joinVersion(parts ...string) string {
+	version := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part == "" {
+			break
+		}
+		version = append(version, part)
+	}
+	return strings.Join(version, ".")
+}
+
+// regexFile mirrors the layout of a uap-core regexes.yaml file
+type regexFile struct {
+	UserAgentParsers []struct {
+		Regex             string `yaml:"regex"`
+		RegexFlag         string `yaml:"regex_flag"`
+		FamilyReplacement string `yaml:"family_replacement"`
+		V1Replacement     string `yaml:"v1_replacement"`
+		V2Replacement     string `yaml:"v2_replacement"`
+		V3Replacement     string `yaml:"v3_replacement"`
+	} `yaml:"user_agent_parsers"`
+
+	OSParsers []struct {
+		Regex           string `yaml:"regex"`
+		RegexFlag       string `yaml:"regex_flag"`
+		OSReplacement   string `yaml:"os_replacement"`
+		OSV1Replacement string `yaml:"os_v1_replacement"`
+		OSV2Replacement string `yaml:"os_v2_replacement"`
+		OSV3Replacement string `yaml:"os_v3_replacement"`
+	} `yaml:"os_parsers"`
+
+	DeviceParsers []struct {
+		Regex             string `yaml:"regex"`
+		RegexFlag         string `yaml:"regex_flag"`
+		DeviceReplacement string `yaml:"device_replacement"`
+	} `yaml:"device_parsers"`
+}
+
+// pattern is a compiled regex with replacements for its results. An empty
+// replacement takes the capture group at the same position.
+type pattern struct {
+	re           *regexp.Regexp
+	replacements []string
+}
+
+// apply matches s and returns the resolved results
+func (p *pattern) apply(s string) ([]string, bool) {
+	groups := p.re.FindStringSubmatch(s)
+	if groups == nil {
+		return nil, false
+	}
+
+	results := make([]string, len(p.replacements))
+	for i, replacement := range p.replacements {
+		if replacement != "" {
+			results[i] = strings.TrimSpace(expandGroups(replacement, groups))
+		} else if i+1 < len(groups) {
+			results[i] = groups[i+1]
+		}
+	}
+	return results, true
+}
+
+// groupReference matches $1 to $9 in replacement strings
+var groupReference = regexp.MustCompile(`\$(\d)`)
+
+// expandGroups substitutes $N references with capture groups
+func expandGroups(replacement string, groups []string) string {
+	if !strings.Contains(replacement, "$") {
+		return replacement
+	}
+	return groupReference.ReplaceAllStringFunc(replacement, func(ref string) string {
+		n := int(ref[1] - '0')
+		if n < len(groups) {
+			return groups[n]
+		}
+		return ""
+	})
+}
+
+// Parser parses user agent strings
+type Parser struct {
+	userAgents []*pattern
+	os         []*pattern
+	devices    []*pattern
+}
+
+// New creates a parser from uap-core formatted YAML
+func New(data []byte) (*Parser, error) {
+	var file regexFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse regexes: %w", err)
+	}
+
+	p := &Parser{}
+	for _, r := range file.UserAgentParsers {
+		compiled, err := compile(r.Regex, r.RegexFlag, r.FamilyReplacement, r.V1Replacement, r.V2Replacement, r.V3Replacement)
+		if err != nil {
+			return nil, err
+		}
+		p.userAgents = append(p.userAgents, compiled)
+	}
+	for _, r := range file.OSParsers {
+		compiled, err := compile(r.Regex, r.RegexFlag, r.OSReplacement, r.OSV1Replacement, r.OSV2Replacement, r.OSV3Replacement)
+		if err != nil {
+			return nil, err
+		}
+		p.os = append(p.os, compiled)
+	}
+	for _, r := range file.DeviceParsers {
+		compiled, err := compile(r.Regex, r.RegexFlag, r.DeviceReplacement)
+		if err != nil {
+			return nil, err
+		}
+		p.devices = append(p.devices, compiled)
+	}
+
+	if len(p.userAgents) == 0 {
+		return nil, fmt.Errorf("no user agent parsers defined")
+	}
+	return p, nil
+}
+
+// NewFromFile creates a parser from a uap-core regexes.yaml file
+func NewFromFile(path string) (*Parser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read regexes: %w", err)
+	}
+	return New(data)
+}
+
+// compile compiles a pattern and its replacements
+func compile(expr, flag string, replacements ...string) (*pattern, error) {
+	if flag == "i" {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+	}
+	return &pattern{re: re, replacements: replacements}, nil
+}
+
+var (
+	defaultParser *Parser
+	defaultOnce   sync.Once
+)
+
+// Default returns a parser using the embedded regex set
+func Default() *Parser {
+	defaultOnce.Do(func() {
+		p, err := New(defaultRegexes)
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded user agent regexes: %v", err))
+		}
+		defaultParser = p
+	})
+	return defaultParser
+}
+
+// Parse parses a user agent string
+func (p *Parser) Parse(ua string) *Client {
+	client := &Client{Family: Other, OS: Other, Device: Other}
+
+	for _, pat := range p.userAgents {
+		if results, ok := pat.apply(ua); ok {
+			client.Family = orOther(results[0])
+			client.Major, client.Minor, client.Patch = results[1], results[2], results[3]
+			break
+		}
+	}
+
+	for _, pat := range p.os {
+		if results, ok := pat.apply(ua); ok {
+			client.OS = orOther(results[0])
+			client.OSMajor, client.OSMinor, client.OSPatch = results[1], results[2], results[3]
+			break
+		}
+	}
+
+	for _, pat := range p.devices {
+		if results, ok := pat.apply(ua); ok {
+			client.Device = orOther(results[0])
+			break
+		}
+	}
+
+	client.Bot = client.Device == "Spider" || automatedFamilies[client.Family]
+	client.DeviceType = deviceType(client, ua)
+	return client
+}
+
+// automatedFamilies are command line tools, HTTP libraries and monitoring
+// agents. Their user agents carry no bot marker for the device parsers to
+// find, but they are not people browsing.
+var automatedFamilies = map[string]bool{
+	"curl":              true,
+	"Wget":              true,
+	"HTTPie":            true,
+	"PostmanRuntime":    true,
+	"insomnia":          true,
+	"okhttp":            true,
+	"python-requests":   true,
+	"python-urllib3":    true,
+	"Python-urllib":     true,
+	"aiohttp":           true,
+	"libwww-perl":       true,
+	"Go-http-client":    true,
+	"Java":              true,
+	"Apache-HttpClient": true,
+	"axios":             true,
+	"node-fetch":        true,
+	"Ruby":              true,
+	"Prometheus":        true,
+}
+
+// orOther substitutes Other for an empty family
+func orOther(family string) string {
+	if family == "" {
+		return Other
+	}
+	return family
+}
+
+// deviceType classifies the device from the parsed details
+func deviceType(client *Client, ua string) string {
+	switch {
+	case client.Bot:
+		return DeviceBot
+	case client.Device == "iPad" || strings.Contains(ua, "Tablet"):
+		return DeviceTablet
+	case client.OS == "Android" && !strings.Contains(ua, "Mobile"):
+		return DeviceTablet
+	case client.Device == "iPhone" || client.Device == "iPod" ||
+		client.OS == "Android" || client.OS == "Windows Phone" || client.OS == "iOS" ||
+		strings.Contains(ua, "Mobile"):
+		return DeviceMobile
+	case client.OS == "Windows" || client.OS == "Mac OS X" || client.OS == "Linux" ||
+		client.OS == "Chrome OS" || client.OS == "Ubuntu" || client.OS == "Fedora" ||
+		client.OS == "Debian" || client.OS == "CentOS" || strings.HasSuffix(client.OS, "BSD"):
+		return DeviceDesktop
+	}
+	return DeviceOther
+}
@@ -0,0 +1,159 @@
+package useragent
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua         string
+		family     string
+		version    string
+		os         string
+		osVersion  string
+		deviceType string
+		bot        bool
+	}{
+		{
+			ua:         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			family:     "Chrome",
+			version:    "120.0.6099",
+			os:         "Windows",
+			osVersion:  "10",
+			deviceType: DeviceDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			family:     "Edge",
+			version:    "120.0.2210",
+			os:         "Windows",
+			osVersion:  "10",
+			deviceType: DeviceDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			family:     "Mobile Safari",
+			version:    "17.1",
+			os:         "iOS",
+			osVersion:  "17.1.2",
+			deviceType: DeviceMobile,
+		},
+		{
+			ua:         "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			family:     "Safari",
+			version:    "17.1",
+			os:         "Mac OS X",
+			osVersion:  "10.15.7",
+			deviceType: DeviceDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			family:     "Chrome Mobile",
+			version:    "120.0.6099",
+			os:         "Android",
+			osVersion:  "14",
+			deviceType: DeviceMobile,
+		},
+		{
+			ua:         "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			family:     "Firefox",
+			version:    "121.0",
+			os:         "Ubuntu",
+			deviceType: DeviceDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			family:     "Googlebot",
+			version:    "2.1",
+			os:         Other,
+			deviceType: DeviceBot,
+			bot:        true,
+		},
+		{
+			ua:         "kube-probe/1.28",
+			family:     "kube-probe",
+			version:    "1.28",
+			os:         Other,
+			deviceType: DeviceBot,
+			bot:        true,
+		},
+		{
+			ua:         "curl/8.4.0",
+			family:     "curl",
+			version:    "8.4.0",
+			os:         Other,
+			deviceType: DeviceBot,
+			bot:        true,
+		},
+		{
+			ua:         "python-requests/2.31.0",
+			family:     "python-requests",
+			version:    "2.31.0",
+			os:         Other,
+			deviceType: DeviceBot,
+			bot:        true,
+		},
+		{
+			ua:         "Go-http-client/1.1",
+			family:     "Go-http-client",
+			version:    "1.1",
+			os:         Other,
+			deviceType: DeviceBot,
+			bot:        true,
+		},
+		{
+			ua:         "",
+			family:     Other,
+			os:         Other,
+			deviceType: DeviceOther,
+		},
+	}
+
+	parser := Default()
+	for _, tt := range tests {
+		client := parser.Parse(tt.ua)
+
+		if client.Family != tt.family || client.Version() != tt.version {
+			t.Errorf("%q: expected browser %s %s, got %s %s", tt.ua, tt.family, tt.version, client.Family, client.Version())
+		}
+		if client.OS != tt.os || client.OSVersion() != tt.osVersion {
+			t.Errorf("%q: expected OS %s %s, got %s %s", tt.ua, tt.os, tt.osVersion, client.OS, client.OSVersion())
+		}
+		if client.DeviceType != tt.deviceType {
+			t.Errorf("%q: expected device type %s, got %s", tt.ua, tt.deviceType, client.DeviceType)
+		}
+		if client.Bot != tt.bot {
+			t.Errorf("%q: expected bot %v, got %v", tt.ua, tt.bot, client.Bot)
+		}
+	}
+}
+
+func TestNewReplacements(t *testing.T) {
+	parser, err := New([]byte(`
+user_agent_parsers:
+  - regex: '(MyApp)/(\d+)\.(\d+)'
+    family_replacement: 'My App'
+    v1_replacement: 'v$2'
+os_parsers:
+  - regex: 'linux'
+    regex_flag: 'i'
+    os_replacement: 'Linux'
+`))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	client := parser.Parse("MyApp/3.2 (LINUX)")
+	if client.Family != "My App" || client.Major != "v3" || client.Minor != "2" {
+		t.Errorf("Unexpected browser: %+v", client)
+	}
+	if client.OS != "Linux" {
+		t.Errorf("Expected OS Linux, got %s", client.OS)
+	}
+}
+
+func TestNewInvalidRegex(t *testing.T) {
+	if _, err := New([]byte("user_agent_parsers:\n  - regex: '(unclosed'\n")); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}